@@ -0,0 +1,362 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+
+	"github.com/SarathLUN/go-auth-service/internal/config"
+	"github.com/SarathLUN/go-auth-service/internal/database"
+	"github.com/SarathLUN/go-auth-service/internal/model"
+)
+
+// Backup file layout: magic | salt | nonce | AES-256-GCM(gzip(JSON)).
+// GCM authenticates the whole payload, so any corruption or tampering is
+// detected on restore.
+const (
+	backupMagic   = "GOAUTHBK"
+	backupVersion = 2
+	// minBackupVersion is the oldest format that can still be restored. v1
+	// backups carry the integer user IDs used before UUIDv7 keys.
+	minBackupVersion = 2
+	saltSize         = 16
+)
+
+// backupFile is the JSON document stored inside an encrypted backup.
+type backupFile struct {
+	Version   int          `json:"version"`
+	CreatedAt time.Time    `json:"created_at"`
+	Users     []backupUser `json:"users"`
+}
+
+// backupUser mirrors every column of the users table, including the password
+// hash that model.User deliberately hides from JSON.
+type backupUser struct {
+	ID              string    `json:"id"`
+	Username        string    `json:"username"`
+	Email           string    `json:"email"`
+	EmailNormalized string    `json:"email_normalized"`
+	PasswordHash    string    `json:"password_hash"`
+	ExternalID      *string   `json:"external_id,omitempty"`
+	IsActive        bool      `json:"is_active"`
+	Role            string    `json:"role,omitempty"`
+	Locale          string    `json:"locale,omitempty"`
+	Timezone        string    `json:"timezone,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	// TOTP is the confirmed MFA enrollment, if any. The secret stays sealed
+	// with MFA_ENCRYPTION_KEY, so restoring it needs the same key.
+	TOTP *backupTOTP `json:"totp,omitempty"`
+}
+
+type backupTOTP struct {
+	Secret       []byte    `json:"secret"`
+	ConfirmedAt  time.Time `json:"confirmed_at"`
+	LastUsedStep int64     `json:"last_used_step"`
+	// RecoveryCodes are the hashes of the unused recovery codes.
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+func runBackup(args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	out := fs.String("out", "", "path of the encrypted backup file to write")
+	fs.Parse(args)
+	if *out == "" {
+		return errors.New("-out is required")
+	}
+
+	cfg := config.LoadConfig()
+	if cfg.BackupPassphrase == "" {
+		return errors.New("BACKUP_PASSPHRASE must be set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	db, err := database.Open(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT u.id, u.username, u.email, u.email_normalized, u.password_hash, u.external_id, u.is_active, u.role, u.locale, u.timezone, u.created_at, u.updated_at,
+			t.secret, t.confirmed_at, t.last_used_step
+		FROM users u
+		LEFT JOIN totp_credentials t ON t.user_id = u.id AND t.confirmed_at IS NOT NULL
+		ORDER BY u.id`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	b := backupFile{Version: backupVersion, CreatedAt: time.Now().UTC()}
+	for rows.Next() {
+		var u backupUser
+		var totpSecret []byte
+		var totpConfirmedAt *time.Time
+		var totpLastUsedStep *int64
+		if err := rows.Scan(&u.ID, &u.Username, &u.Email, &u.EmailNormalized, &u.PasswordHash,
+			&u.ExternalID, &u.IsActive, &u.Role, &u.Locale, &u.Timezone, &u.CreatedAt, &u.UpdatedAt,
+			&totpSecret, &totpConfirmedAt, &totpLastUsedStep); err != nil {
+			return err
+		}
+		if totpConfirmedAt != nil {
+			u.TOTP = &backupTOTP{Secret: totpSecret, ConfirmedAt: *totpConfirmedAt, LastUsedStep: *totpLastUsedStep}
+		}
+		b.Users = append(b.Users, u)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if err := backupRecoveryCodes(ctx, db, b.Users); err != nil {
+		return err
+	}
+
+	data, err := encryptBackup(b, cfg.BackupPassphrase)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(*out, data, 0o600); err != nil {
+		return err
+	}
+	fmt.Printf("wrote %d user(s) to %s\n", len(b.Users), *out)
+	return nil
+}
+
+func runRestore(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	in := fs.String("in", "", "path of the encrypted backup file to read")
+	verifyOnly := fs.Bool("verify", false, "only decrypt and verify the backup, don't touch the database")
+	email := fs.String("email", "", "restore only the user with this email address")
+	fs.Parse(args)
+	if *in == "" {
+		return errors.New("-in is required")
+	}
+
+	cfg := config.LoadConfig()
+	if cfg.BackupPassphrase == "" {
+		return errors.New("BACKUP_PASSPHRASE must be set")
+	}
+
+	data, err := os.ReadFile(*in)
+	if err != nil {
+		return err
+	}
+	b, err := decryptBackup(data, cfg.BackupPassphrase)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("backup from %s verified: format v%d, %d user(s)\n", b.CreatedAt.Format(time.RFC3339), b.Version, len(b.Users))
+	if *verifyOnly {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	db, err := database.Open(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	restored := 0
+	for _, u := range b.Users {
+		if *email != "" && u.Email != *email {
+			continue
+		}
+		if u.Role == "" {
+			// Backups taken before roles existed.
+			u.Role = model.RoleUser
+		}
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO users (id, username, email, email_normalized, password_hash, external_id, is_active, role, locale, timezone, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+			ON CONFLICT (id) DO UPDATE SET
+				username = EXCLUDED.username,
+				email = EXCLUDED.email,
+				email_normalized = EXCLUDED.email_normalized,
+				password_hash = EXCLUDED.password_hash,
+				external_id = EXCLUDED.external_id,
+				is_active = EXCLUDED.is_active,
+				role = EXCLUDED.role,
+				locale = EXCLUDED.locale,
+				timezone = EXCLUDED.timezone,
+				created_at = EXCLUDED.created_at,
+				updated_at = EXCLUDED.updated_at`,
+			u.ID, u.Username, u.Email, u.EmailNormalized, u.PasswordHash, u.ExternalID, u.IsActive, u.Role, u.Locale, u.Timezone, u.CreatedAt, u.UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("restore user %s: %w", u.ID, err)
+		}
+		if u.TOTP != nil {
+			_, err := tx.ExecContext(ctx, `
+				INSERT INTO totp_credentials (user_id, secret, confirmed_at, last_used_step)
+				VALUES ($1, $2, $3, $4)
+				ON CONFLICT (user_id) DO UPDATE SET
+					secret = EXCLUDED.secret,
+					confirmed_at = EXCLUDED.confirmed_at,
+					last_used_step = GREATEST(totp_credentials.last_used_step, EXCLUDED.last_used_step)`,
+				u.ID, u.TOTP.Secret, u.TOTP.ConfirmedAt, u.TOTP.LastUsedStep)
+			if err != nil {
+				return fmt.Errorf("restore MFA of user %s: %w", u.ID, err)
+			}
+			if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, u.ID); err != nil {
+				return fmt.Errorf("restore MFA of user %s: %w", u.ID, err)
+			}
+			for _, h := range u.TOTP.RecoveryCodes {
+				if _, err := tx.ExecContext(ctx,
+					`INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, u.ID, h); err != nil {
+					return fmt.Errorf("restore MFA of user %s: %w", u.ID, err)
+				}
+			}
+		}
+		restored++
+	}
+	if restored > 0 {
+		// A restored installation already has its admins; don't offer
+		// first-run setup on it.
+		if _, err := tx.ExecContext(ctx, `INSERT INTO setup_completion (singleton) VALUES (TRUE) ON CONFLICT DO NOTHING`); err != nil {
+			return fmt.Errorf("mark setup complete: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	fmt.Printf("restored %d user(s)\n", restored)
+	return nil
+}
+
+// backupRecoveryCodes attaches the unused recovery codes to the users with
+// MFA enabled.
+func backupRecoveryCodes(ctx context.Context, db *sql.DB, users []backupUser) error {
+	byID := make(map[string]*backupTOTP)
+	for i := range users {
+		if users[i].TOTP != nil {
+			byID[users[i].ID] = users[i].TOTP
+		}
+	}
+
+	rows, err := db.QueryContext(ctx,
+		`SELECT user_id, code_hash FROM mfa_recovery_codes WHERE used_at IS NULL ORDER BY id`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var userID, hash string
+		if err := rows.Scan(&userID, &hash); err != nil {
+			return err
+		}
+		if t := byID[userID]; t != nil {
+			t.RecoveryCodes = append(t.RecoveryCodes, hash)
+		}
+	}
+	return rows.Err()
+}
+
+func backupKey(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encryptBackup(b backupFile, passphrase string) ([]byte, error) {
+	var plain bytes.Buffer
+	zw := gzip.NewWriter(&plain)
+	if err := json.NewEncoder(zw).Encode(b); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	aead, err := backupKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	header := append([]byte(backupMagic), salt...)
+	out := append(append([]byte{}, header...), nonce...)
+	// The header is authenticated as additional data.
+	return aead.Seal(out, nonce, plain.Bytes(), header), nil
+}
+
+func decryptBackup(data []byte, passphrase string) (*backupFile, error) {
+	if len(data) < len(backupMagic)+saltSize || string(data[:len(backupMagic)]) != backupMagic {
+		return nil, errors.New("not a go-auth-service backup file")
+	}
+	header := data[:len(backupMagic)+saltSize]
+	salt := header[len(backupMagic):]
+
+	aead, err := backupKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	rest := data[len(header):]
+	if len(rest) < aead.NonceSize() {
+		return nil, errors.New("backup file is truncated")
+	}
+	plain, err := aead.Open(nil, rest[:aead.NonceSize()], rest[aead.NonceSize():], header)
+	if err != nil {
+		return nil, errors.New("backup integrity check failed: wrong passphrase or corrupted file")
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(plain))
+	if err != nil {
+		return nil, err
+	}
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
+	var format struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(raw, &format); err != nil {
+		return nil, err
+	}
+	if format.Version > backupVersion {
+		return nil, fmt.Errorf("backup format v%d is newer than this authctl supports (v%d)", format.Version, backupVersion)
+	}
+	if format.Version < minBackupVersion {
+		return nil, fmt.Errorf("backup format v%d predates UUID user IDs; restore it with the authctl it was written by, before migrating", format.Version)
+	}
+	var b backupFile
+	if err := json.Unmarshal(raw, &b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/joho/godotenv"
+
+	"github.com/SarathLUN/go-auth-service/internal/config"
+)
+
+const configUsage = `Usage:
+  authctl config schema           Print a JSON Schema of all configuration options
+  authctl config validate FILE    Check an env file against the schema
+`
+
+func runConfig(args []string) error {
+	if len(args) < 1 {
+		fmt.Fprint(os.Stderr, configUsage)
+		os.Exit(2)
+	}
+	switch args[0] {
+	case "schema":
+		return printConfigSchema()
+	case "validate":
+		if len(args) != 2 {
+			fmt.Fprint(os.Stderr, configUsage)
+			os.Exit(2)
+		}
+		return validateEnvFile(args[1])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown config command %q\n\n%s", args[0], configUsage)
+		os.Exit(2)
+	}
+	return nil
+}
+
+// printConfigSchema writes a JSON Schema describing an environment as an
+// object of variable names to values. Every value is a string in the
+// environment, so booleans, integers and durations are expressed as string
+// patterns while the default keeps its natural JSON type.
+func printConfigSchema() error {
+	properties := make(map[string]any)
+	var required []string
+	for _, o := range config.Options() {
+		prop := map[string]any{
+			"type":    "string",
+			"x-type":  o.Type,
+			"x-field": o.Field,
+		}
+		switch o.Type {
+		case "boolean":
+			prop["enum"] = []string{"true", "false", "1", "0"}
+		case "integer":
+			prop["pattern"] = `^-?[0-9]+$`
+		case "duration":
+			prop["pattern"] = `^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+		}
+		if o.Default != "" || o.Type == "string" {
+			prop["default"] = typedDefault(o)
+		}
+		if o.Required {
+			required = append(required, o.Name)
+		}
+		properties[o.Name] = prop
+	}
+
+	schema := map[string]any{
+		"$schema":    "https://json-schema.org/draft/2020-12/schema",
+		"title":      "go-auth-service configuration",
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(schema)
+}
+
+func typedDefault(o config.Option) any {
+	switch o.Type {
+	case "boolean":
+		if b, err := strconv.ParseBool(o.Default); err == nil {
+			return b
+		}
+	case "integer":
+		if n, err := strconv.Atoi(o.Default); err == nil {
+			return n
+		}
+	}
+	return o.Default
+}
+
+// validateEnvFile checks an env file for missing required options and values
+// that would not parse. Unknown keys are only reported, since env files often
+// carry settings for other tools such as goose.
+func validateEnvFile(path string) error {
+	env, err := godotenv.Read(path)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", path, err)
+	}
+
+	known := make(map[string]bool)
+	var problems []string
+	for _, o := range config.Options() {
+		known[o.Name] = true
+		value, ok := env[o.Name]
+		if !ok || value == "" {
+			if o.Required {
+				problems = append(problems, o.Name+": required but not set")
+			}
+			continue
+		}
+		if err := o.Check(value); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	var unknown []string
+	for k := range env {
+		if !known[k] {
+			unknown = append(unknown, k)
+		}
+	}
+	sort.Strings(unknown)
+	for _, k := range unknown {
+		fmt.Printf("[WARN] %s: not a go-auth-service option\n", k)
+	}
+
+	for _, p := range problems {
+		fmt.Printf("[FAIL] %s\n", p)
+	}
+	if len(problems) > 0 {
+		return errors.New("configuration is invalid")
+	}
+	fmt.Printf("[ OK ] %s\n", path)
+	return nil
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pressly/goose/v3"
+
+	"github.com/SarathLUN/go-auth-service/internal/advisory"
+	"github.com/SarathLUN/go-auth-service/internal/config"
+	"github.com/SarathLUN/go-auth-service/internal/database"
+	"github.com/SarathLUN/go-auth-service/internal/jwtkeys"
+	"github.com/SarathLUN/go-auth-service/internal/middleware"
+	"github.com/SarathLUN/go-auth-service/internal/outbound"
+	"github.com/SarathLUN/go-auth-service/internal/password"
+	"github.com/SarathLUN/go-auth-service/internal/pwned"
+	"github.com/SarathLUN/go-auth-service/internal/reputation"
+	"github.com/SarathLUN/go-auth-service/internal/secretbox"
+	"github.com/SarathLUN/go-auth-service/internal/service/auth"
+	"github.com/SarathLUN/go-auth-service/internal/service/email"
+	"github.com/SarathLUN/go-auth-service/internal/util"
+)
+
+// doctor collects check results and prints them as they are produced.
+type doctor struct {
+	failures int
+	warnings int
+}
+
+func (d *doctor) ok(msg string) {
+	fmt.Printf("[ OK ] %s\n", msg)
+}
+
+func (d *doctor) warn(msg, hint string) {
+	d.warnings++
+	fmt.Printf("[WARN] %s\n       -> %s\n", msg, hint)
+}
+
+func (d *doctor) fail(msg, hint string) {
+	d.failures++
+	fmt.Printf("[FAIL] %s\n       -> %s\n", msg, hint)
+}
+
+func runDoctor(args []string) error {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	timeout := fs.Duration("timeout", 5*time.Second, "timeout for each connectivity check")
+	skipSMTP := fs.Bool("skip-smtp", false, "skip the SMTP connectivity check")
+	fs.Parse(args)
+
+	cfg := config.LoadConfig()
+	d := &doctor{}
+
+	d.checkConfig(cfg)
+	d.checkKeyMaterial(cfg)
+	d.checkAdvisories(cfg)
+	d.checkDatabase(cfg, *timeout)
+	if !*skipSMTP && cfg.EmailDriver == "smtp" {
+		d.checkSMTP(cfg, *timeout)
+	}
+
+	fmt.Printf("\n%d failure(s), %d warning(s)\n", d.failures, d.warnings)
+	if d.failures > 0 {
+		return errors.New("doctor found problems")
+	}
+	return nil
+}
+
+func (d *doctor) checkConfig(cfg *config.Config) {
+	if u, err := url.Parse(cfg.PublicURL); err != nil || u.Scheme == "" || u.Host == "" {
+		d.fail(fmt.Sprintf("PUBLIC_URL %q is not an absolute URL", cfg.PublicURL),
+			"set PUBLIC_URL to the externally visible URL of the service, including any ingress path prefix")
+	} else {
+		d.ok("PUBLIC_URL is " + cfg.PublicURL)
+	}
+
+	if u, err := url.Parse(cfg.ActivateBaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+		d.fail(fmt.Sprintf("ACTIVATE_BASE_URL %q is not an absolute URL", cfg.ActivateBaseURL),
+			"set ACTIVATE_BASE_URL to the public URL users should open, e.g. https://auth.example.com/activate")
+	} else {
+		d.ok("ACTIVATE_BASE_URL is a valid absolute URL")
+	}
+
+	if u, err := url.Parse(cfg.DeviceVerificationURL); err != nil || u.Scheme == "" || u.Host == "" {
+		d.fail(fmt.Sprintf("DEVICE_VERIFICATION_URL %q is not an absolute URL", cfg.DeviceVerificationURL),
+			"set DEVICE_VERIFICATION_URL to the page where users enter device codes, e.g. https://auth.example.com/device")
+	} else if cfg.DeviceVerificationURL == cfg.AbsoluteURL("/oauth/device") {
+		d.warn("DEVICE_VERIFICATION_URL is the /oauth/device API",
+			"users cannot sign in there from a browser; point it at a page of your frontend that calls GET and POST /oauth/device")
+	}
+
+	if u, err := url.Parse(cfg.PasswordResetBaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+		d.fail(fmt.Sprintf("PASSWORD_RESET_BASE_URL %q is not an absolute URL", cfg.PasswordResetBaseURL),
+			"set PASSWORD_RESET_BASE_URL to the page where users choose a new password, e.g. https://auth.example.com/password/reset")
+	}
+	if cfg.EmailLinkProxy {
+		d.ok("email links go through " + cfg.PublicURL + "/links and can be followed once")
+	}
+
+	if _, err := password.ParseClasses(cfg.PasswordRequiredClasses); err != nil {
+		d.fail("PASSWORD_REQUIRED_CLASSES is invalid: "+err.Error(), "list any of lower, upper, digit and symbol, separated by commas")
+	}
+	if cfg.PasswordMinLength < 8 {
+		d.warn(fmt.Sprintf("PASSWORD_MIN_LENGTH is %d", cfg.PasswordMinLength), "require at least 8 characters, as NIST SP 800-63B does")
+	}
+	if cfg.PasswordMinScore < password.ScoreTooGuessable || cfg.PasswordMinScore > password.ScoreVeryUnguessable {
+		d.fail(fmt.Sprintf("PASSWORD_MIN_SCORE is %d", cfg.PasswordMinScore), "set PASSWORD_MIN_SCORE to a strength score from 0 to 4")
+	}
+
+	if hasher, err := auth.PasswordHasherFromConfig(cfg); err != nil {
+		d.fail("password hashing is misconfigured: "+err.Error(), "set PASSWORD_HASH_ALGORITHM to argon2id or bcrypt and ARGON2_ITERATIONS and ARGON2_PARALLELISM to at least 1")
+	} else if hasher.Algorithm == util.PasswordHashBcrypt {
+		d.warn("passwords are hashed with bcrypt", "set PASSWORD_HASH_ALGORITHM=argon2id; existing hashes are upgraded as users log in")
+	}
+
+	if cfg.PwnedPasswordsCheck {
+		if u, err := url.Parse(cfg.PwnedPasswordsEndpoint); err != nil || u.Scheme == "" || u.Host == "" {
+			d.fail(fmt.Sprintf("PWNED_PASSWORDS_ENDPOINT %q is not an absolute URL", cfg.PwnedPasswordsEndpoint),
+				"unset it to use "+pwned.DefaultEndpoint)
+		}
+	}
+
+	if _, err := auth.ParseClaimMapping(cfg.ClaimNamespace, cfg.ClaimMapping); err != nil {
+		d.fail("CLAIM_MAPPING is invalid: "+err.Error(), "map locale, zoneinfo, email, role or roles to unique names other than the standard JWT claims")
+	}
+
+	if cfg.LockoutThreshold <= 0 {
+		d.warn("account lockout is disabled", "set LOCKOUT_THRESHOLD to lock accounts after repeated failed logins")
+	} else if cfg.LockoutWindow <= 0 || cfg.LockoutDuration <= 0 {
+		d.fail("LOCKOUT_WINDOW and LOCKOUT_DURATION must be positive", "set them to durations such as 15m, or LOCKOUT_THRESHOLD=0 to disable lockout")
+	}
+
+	if _, err := email.NewService(cfg); err != nil {
+		d.fail("email configuration is invalid: "+err.Error(), "set EMAIL_DRIVER to smtp or log and check SMTP_PORT")
+	} else if cfg.EmailDriver == "log" {
+		d.warn("EMAIL_DRIVER is log", "activation emails are only written to the server log; use smtp outside development")
+	}
+
+	if cfg.SMTPFromEmail == "" {
+		d.fail("SMTP_FROM_EMAIL is empty", "set SMTP_FROM_EMAIL to the sender address for outgoing mail")
+	} else {
+		d.ok("SMTP sender is configured")
+	}
+
+	if _, err := reputation.NewFromConfig(cfg); err != nil {
+		d.fail("IP reputation configuration is invalid: "+err.Error(),
+			"check IP_REPUTATION_PROVIDER, IP_REPUTATION_BLOCKLIST and ABUSEIPDB_API_KEY")
+	} else {
+		d.ok("IP reputation configuration is valid")
+	}
+
+	if _, err := middleware.ParseSigningKeys(cfg.InternalSigningKeys); err != nil {
+		d.fail("INTERNAL_SIGNING_KEYS is invalid: "+err.Error(), "use comma-separated keyID:secret pairs")
+	}
+
+	if _, err := middleware.ParseTrustedProxies(cfg.TrustedProxies); err != nil {
+		d.fail("TRUSTED_PROXIES is invalid: "+err.Error(), "use comma-separated CIDRs or addresses of your reverse proxies")
+	} else if cfg.TrustedProxies == "" {
+		d.warn("TRUSTED_PROXIES is not set",
+			"behind a reverse proxy all clients share the proxy's rate limits; list the proxy's addresses")
+	}
+
+	if cfg.MFAEncryptionKey == "" {
+		d.warn("MFA_ENCRYPTION_KEY is not set", "TOTP enrollment is disabled; generate a key with `openssl rand -base64 32`")
+	} else if _, err := secretbox.New(cfg.MFAEncryptionKey); err != nil {
+		d.fail("MFA_ENCRYPTION_KEY is invalid: "+err.Error(), "generate one with `openssl rand -base64 32`")
+	} else {
+		d.ok("MFA encryption key is configured")
+	}
+
+	if _, err := outbound.TLSConfig(cfg); err != nil {
+		d.fail("outbound TLS configuration is invalid: "+err.Error(), "point OUTBOUND_CA_FILE at a readable PEM bundle")
+	} else if cfg.OutboundCAFile != "" {
+		d.ok("extra outbound CAs loaded from " + cfg.OutboundCAFile)
+	}
+}
+
+// checkAdvisories reports the same findings the server logs at startup.
+// Informational advisories are left out.
+func (d *doctor) checkAdvisories(cfg *config.Config) {
+	for _, a := range advisory.Check(cfg, os.LookupEnv) {
+		switch a.Severity {
+		case advisory.Critical:
+			d.fail(a.Setting+": "+a.Message, a.Remediation)
+		case advisory.Warning:
+			d.warn(a.Setting+": "+a.Message, a.Remediation)
+		}
+	}
+}
+
+func (d *doctor) checkKeyMaterial(cfg *config.Config) {
+	keys, err := jwtkeys.FromConfig(cfg)
+	if err != nil {
+		d.fail("JWT signing key: "+err.Error(), "see JWT_SIGNING_ALG, JWT_PRIVATE_KEY_FILE and JWT_PRIVATE_KEY in .env.example")
+		return
+	}
+	switch {
+	case cfg.JWTKeyStore == jwtkeys.StoreDatabase:
+		if _, err := jwtkeys.NewSealer(cfg.JWTKeyEncryptionKey); err != nil {
+			d.fail(err.Error(), "generate one with `openssl rand -base64 32` and keep it with your other secrets")
+			return
+		}
+		d.ok(fmt.Sprintf("%s signing keys are managed in the database", keys.Algorithm()))
+	case keys.Algorithm() == jwtkeys.HS256:
+		d.ok("JWT signing key loaded (HS256)")
+	default:
+		d.ok(fmt.Sprintf("JWT signing key loaded (%s, kid %s)", keys.Algorithm(), keys.CurrentKeyID()))
+	}
+}
+
+func (d *doctor) checkDatabase(cfg *config.Config, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	db, err := database.Open(ctx, cfg)
+	if err != nil {
+		d.fail("cannot connect to the database: "+err.Error(),
+			fmt.Sprintf("check that Postgres is running on %s and DB_USER/DB_PASSWORD/DB_NAME are correct", strings.Join(cfg.DBHosts(), ", ")))
+		return
+	}
+	defer db.Close()
+	var host string
+	if err := db.QueryRowContext(ctx, `SELECT COALESCE(inet_server_addr()::text, 'local socket')`).Scan(&host); err != nil {
+		host = strings.Join(cfg.DBHosts(), ", ")
+	}
+	d.ok(fmt.Sprintf("connected to database %s (server %s)", cfg.DBName, host))
+
+	d.checkMigrations(ctx, cfg, db)
+}
+
+func (d *doctor) checkMigrations(ctx context.Context, cfg *config.Config, db *sql.DB) {
+	provider, err := goose.NewProvider(goose.DialectPostgres, db, os.DirFS(cfg.MigrationDir))
+	if err != nil {
+		d.fail("cannot read migrations from "+cfg.MigrationDir+": "+err.Error(),
+			"run authctl from the repository root or set GOOSE_MIGRATION_DIR")
+		return
+	}
+	statuses, err := provider.Status(ctx)
+	if err != nil {
+		d.fail("cannot read migration status: "+err.Error(), "check that the database user can read goose_db_version")
+		return
+	}
+
+	pending := 0
+	for _, s := range statuses {
+		if s.State == goose.StatePending {
+			pending++
+		}
+	}
+	if pending > 0 {
+		d.fail(fmt.Sprintf("%d migration(s) pending", pending), "run `goose up` before starting the server")
+		return
+	}
+	d.ok(fmt.Sprintf("all %d migration(s) applied", len(statuses)))
+}
+
+func (d *doctor) checkSMTP(cfg *config.Config, timeout time.Duration) {
+	addr := net.JoinHostPort(cfg.SMTPHost, cfg.SMTPPort)
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		d.fail("cannot reach SMTP server at "+addr+": "+err.Error(),
+			"check SMTP_HOST/SMTP_PORT and that outbound SMTP is allowed by your network")
+		return
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	c, err := smtp.NewClient(conn, cfg.SMTPHost)
+	if err != nil {
+		conn.Close()
+		d.fail("SMTP handshake with "+addr+" failed: "+err.Error(), "make sure SMTP_PORT points at a plain or STARTTLS SMTP port")
+		return
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); !ok {
+		d.warn("SMTP server at "+addr+" does not offer STARTTLS", "credentials and activation links will be sent in clear text")
+		return
+	}
+	d.ok("SMTP server at " + addr + " is reachable and offers STARTTLS")
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+const usage = `Usage: authctl <command> [flags]
+
+Commands:
+  doctor              Check configuration and connectivity to dependencies
+  config              Print the configuration schema (schema) or check an env file (validate FILE)
+  normalize-emails    Recompute canonical emails with the configured rules (-apply to write)
+  backup              Write an encrypted backup of identity data (-out FILE)
+  restore             Verify (-verify) or restore an encrypted backup (-in FILE)
+  rotate-keys         Generate a new JWT signing key (JWT_KEY_STORE=database)
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "doctor":
+		err = runDoctor(os.Args[2:])
+	case "config":
+		err = runConfig(os.Args[2:])
+	case "normalize-emails":
+		err = runNormalizeEmails(os.Args[2:])
+	case "backup":
+		err = runBackup(os.Args[2:])
+	case "restore":
+		err = runRestore(os.Args[2:])
+	case "rotate-keys":
+		err = runRotateKeys(os.Args[2:])
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
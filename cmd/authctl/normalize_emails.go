@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/config"
+	"github.com/SarathLUN/go-auth-service/internal/database"
+	"github.com/SarathLUN/go-auth-service/internal/util"
+)
+
+type emailRow struct {
+	id         string
+	email      string
+	normalized string
+}
+
+// runNormalizeEmails recomputes users.email_normalized with the configured
+// rules. When several accounts collapse to the same canonical address the
+// oldest one keeps it; the others are reported and left untouched so an
+// operator can merge or rename them.
+func runNormalizeEmails(args []string) error {
+	fs := flag.NewFlagSet("normalize-emails", flag.ExitOnError)
+	apply := fs.Bool("apply", false, "write changes (default is a dry run)")
+	fs.Parse(args)
+
+	cfg := config.LoadConfig()
+	opts := util.EmailNormalization{Gmail: cfg.EmailNormalizeGmail, StripSubaddress: cfg.EmailStripSubaddress}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	db, err := database.Open(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT id, email, email_normalized FROM users ORDER BY created_at, id FOR UPDATE`)
+	if err != nil {
+		return err
+	}
+	var users []emailRow
+	for rows.Next() {
+		var u emailRow
+		if err := rows.Scan(&u.id, &u.email, &u.normalized); err != nil {
+			rows.Close()
+			return err
+		}
+		users = append(users, u)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	owner := make(map[string]emailRow) // canonical address -> oldest account
+	var changed []emailRow
+	conflicts := 0
+	for _, u := range users {
+		canonical := util.NormalizeEmail(u.email, opts)
+		if first, taken := owner[canonical]; taken {
+			conflicts++
+			fmt.Printf("conflict: user %s (%s) normalizes to %s, already owned by user %s (%s)\n",
+				u.id, u.email, canonical, first.id, first.email)
+			continue
+		}
+		owner[canonical] = u
+		if canonical != u.normalized {
+			changed = append(changed, emailRow{id: u.id, email: u.email, normalized: canonical})
+		}
+	}
+
+	fmt.Printf("%d user(s) scanned, %d to update, %d conflict(s)\n", len(users), len(changed), conflicts)
+	if !*apply || len(changed) == 0 {
+		return nil
+	}
+
+	// Move changed rows to placeholder values first so that swapping canonical
+	// addresses between rows can't trip the unique index mid-way.
+	for _, u := range changed {
+		if _, err := tx.ExecContext(ctx, `UPDATE users SET email_normalized = '#' || id::text WHERE id = $1`, u.id); err != nil {
+			return err
+		}
+	}
+	for _, u := range changed {
+		_, err := tx.ExecContext(ctx,
+			`UPDATE users SET email_normalized = $1, updated_at = NOW() WHERE id = $2`, u.normalized, u.id)
+		if err != nil {
+			return fmt.Errorf("update user %s: %w", u.id, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	fmt.Printf("updated %d user(s)\n", len(changed))
+	return nil
+}
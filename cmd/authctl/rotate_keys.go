@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/clock"
+	"github.com/SarathLUN/go-auth-service/internal/config"
+	"github.com/SarathLUN/go-auth-service/internal/database"
+	"github.com/SarathLUN/go-auth-service/internal/jwtkeys"
+	"github.com/SarathLUN/go-auth-service/internal/repository"
+	"github.com/SarathLUN/go-auth-service/internal/service/signingkey"
+)
+
+// runRotateKeys generates a new JWT signing key in the database key store.
+// Running servers publish it on their next reload and switch to it one
+// JWT_KEY_REFRESH_INTERVAL later.
+func runRotateKeys(args []string) error {
+	fs := flag.NewFlagSet("rotate-keys", flag.ExitOnError)
+	fs.Parse(args)
+
+	cfg := config.LoadConfig()
+	if cfg.JWTKeyStore != jwtkeys.StoreDatabase {
+		return errors.New("key rotation requires JWT_KEY_STORE=database")
+	}
+	keys, err := jwtkeys.FromConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	db, err := database.Open(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	svc, err := signingkey.NewService(cfg, signingkey.Deps{
+		SigningKeys: repository.NewSigningKeyRepository(db),
+		KeySet:      keys,
+		Clock:       clock.Real{},
+	})
+	if err != nil {
+		return err
+	}
+	kid, activeFrom, err := svc.Rotate(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("new signing key %s is published now and signs from %s\n", kid, activeFrom.Format(time.RFC3339))
+	fmt.Printf("previous keys keep verifying until %s\n", activeFrom.Add(cfg.JWTKeyRotationGrace).Format(time.RFC3339))
+	return nil
+}
@@ -1,29 +1,226 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"time"
 
+	"github.com/SarathLUN/go-auth-service/internal/advisory"
+	"github.com/SarathLUN/go-auth-service/internal/clock"
 	"github.com/SarathLUN/go-auth-service/internal/config"
+	"github.com/SarathLUN/go-auth-service/internal/controller"
+	"github.com/SarathLUN/go-auth-service/internal/database"
+	"github.com/SarathLUN/go-auth-service/internal/jwtkeys"
+	"github.com/SarathLUN/go-auth-service/internal/loginevents"
+	"github.com/SarathLUN/go-auth-service/internal/middleware"
+	"github.com/SarathLUN/go-auth-service/internal/pwned"
+	"github.com/SarathLUN/go-auth-service/internal/repository"
+	"github.com/SarathLUN/go-auth-service/internal/reputation"
+	"github.com/SarathLUN/go-auth-service/internal/secretbox"
+	"github.com/SarathLUN/go-auth-service/internal/service/actiontoken"
+	"github.com/SarathLUN/go-auth-service/internal/service/auth"
+	"github.com/SarathLUN/go-auth-service/internal/service/email"
+	"github.com/SarathLUN/go-auth-service/internal/service/oauth"
+	"github.com/SarathLUN/go-auth-service/internal/service/setup"
+	"github.com/SarathLUN/go-auth-service/internal/service/share"
+	"github.com/SarathLUN/go-auth-service/internal/service/signingkey"
+	"github.com/SarathLUN/go-auth-service/internal/service/user"
+	transporthttp "github.com/SarathLUN/go-auth-service/internal/transport/http"
+	"github.com/SarathLUN/go-auth-service/internal/version"
 )
 
 func main() {
+	showVersion := flag.Bool("version", false, "print build information and exit")
+	flag.Parse()
+
+	if *showVersion {
+		fmt.Println(version.Get())
+		return
+	}
+
+	log.Printf("go-auth-service %s", version.Get())
 	cfg := config.LoadConfig()
 
-	// Access configuration values:
-	fmt.Printf("Database Host: %s\n", cfg.DBHost)
-	fmt.Printf("JWT Secret: %s\n", cfg.JWTSecret) // Be careful about logging secrets!
-	fmt.Printf("Database Connection String: %s\n", cfg.GetDBConnectionString())
-	fmt.Printf("Application Port: %s\n", cfg.AppPort)
+	advisories := advisory.Check(cfg, os.LookupEnv)
+	for _, a := range advisories {
+		log.Printf("advisory id=%s severity=%s setting=%s message=%q remediation=%q",
+			a.ID, a.Severity, a.Setting, a.Message, a.Remediation)
+	}
+
+	var db *sql.DB
+	var err error
+	if cfg.WaitForDeps {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.WaitForDepsTimeout)
+		db, err = database.OpenWithRetry(ctx, cfg)
+		cancel()
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		db, err = database.Open(ctx, cfg)
+		cancel()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	mailer, err := email.NewService(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	keys, err := jwtkeys.FromConfig(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var signingKeyController *controller.SigningKeyController
+	if cfg.JWTKeyStore == jwtkeys.StoreDatabase {
+		keyService, err := signingkey.NewService(cfg, signingkey.Deps{
+			SigningKeys: repository.NewSigningKeyRepository(db),
+			KeySet:      keys,
+			Clock:       clock.Real{},
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		err = keyService.Load(ctx)
+		cancel()
+		if err != nil {
+			log.Fatal(err)
+		}
+		go keyService.Run(context.Background())
+		signingKeyController = controller.NewSigningKeyController(keyService)
+	}
+	log.Printf("Signing access tokens with %s (kid %q)", keys.Algorithm(), keys.CurrentKeyID())
+
+	internalSigningKeys, err := middleware.ParseSigningKeys(cfg.InternalSigningKeys)
+	if err != nil {
+		log.Fatal(err)
+	}
+	trustedProxies, err := middleware.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		log.Fatalf("TRUSTED_PROXIES: %v", err)
+	}
 
-	// Example HTTP server (replace with your actual application logic)
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		fmt.Fprintf(w, "Hello, world!  The database host is: %s", cfg.DBHost)
+	var mfaBox *secretbox.Box
+	if cfg.MFAEncryptionKey != "" {
+		mfaBox, err = secretbox.New(cfg.MFAEncryptionKey)
+		if err != nil {
+			log.Fatalf("MFA_ENCRYPTION_KEY: %v", err)
+		}
+	} else {
+		log.Println("MFA_ENCRYPTION_KEY is not set; TOTP enrollment is disabled")
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db)
+	revokedTokenRepo := repository.NewRevokedTokenRepository(db)
+	actionTokens := actiontoken.NewService(actiontoken.Deps{
+		Tokens: repository.NewActionTokenRepository(db),
+		Clock:  clock.Real{},
+	})
+	loginObserver, err := loginevents.NewFromConfig(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ipReputation, err := reputation.NewFromConfig(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pwnedPasswords, err := pwned.NewFromConfig(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	authService := auth.NewService(cfg, auth.Deps{
+		Users:           userRepo,
+		RefreshTokens:   refreshTokenRepo,
+		ActionTokens:    actionTokens,
+		RevokedTokens:   revokedTokenRepo,
+		TOTPCredentials: repository.NewTOTPCredentialRepository(db),
+		MFAChallenges:   repository.NewMFAChallengeRepository(db),
+		RecoveryCodes:   repository.NewRecoveryCodeRepository(db),
+		QRLogins:        repository.NewQRLoginRepository(db),
+		FailedLogins:    repository.NewFailedLoginRepository(db),
+		Tx:              repository.NewUnitOfWork(db),
+		Mailer:          mailer,
+		Clock:           clock.Real{},
+		Keys:            keys,
+		MFABox:          mfaBox,
+		LoginObserver:   loginObserver,
+		Reputation:      ipReputation,
+		PwnedPasswords:  pwnedPasswords,
+	})
+	go authService.RunCleanup(context.Background())
+	setupService := setup.NewService(cfg, setup.Deps{
+		Setup:    repository.NewSetupRepository(db),
+		Accounts: authService,
 	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	setupPending, setupToken, err := setupService.Start(ctx)
+	cancel()
+	if err != nil {
+		log.Fatal(err)
+	}
+	switch {
+	case setupToken != "":
+		log.Printf("First-run setup is pending: create the first admin with POST %s and setup token %s", cfg.AbsoluteURL("/setup"), setupToken)
+	case setupPending:
+		log.Printf("First-run setup is pending: create the first admin with POST %s and SETUP_TOKEN", cfg.AbsoluteURL("/setup"))
+	}
+
+	authController := controller.NewAuthController(authService)
+	userService := user.NewService(user.Deps{
+		Users:    userRepo,
+		Accounts: authService,
+	})
+	userController := controller.NewUserController(userService)
+	actionTokenController := controller.NewActionTokenController(actionTokens)
+	shareController := controller.NewShareController(share.NewService(share.Deps{
+		Clock: clock.Real{},
+		Keys:  keys,
+	}))
+	oauthController := controller.NewOAuthController(oauth.NewService(cfg, oauth.Deps{
+		Clients:              repository.NewOAuthClientRepository(db),
+		RefreshTokens:        refreshTokenRepo,
+		RevokedTokens:        revokedTokenRepo,
+		DeviceAuthorizations: repository.NewDeviceAuthorizationRepository(db),
+		Sessions:             authService,
+		Clock:                clock.Real{},
+		Keys:                 keys,
+	}))
+
+	handler := transporthttp.NewHandler(cfg, transporthttp.Deps{
+		Keys:                  keys,
+		Auth:                  authService,
+		Users:                 userService,
+		AuthController:        authController,
+		UserController:        userController,
+		SigningKeyController:  signingKeyController,
+		OAuthController:       oauthController,
+		ActionTokenController: actionTokenController,
+		ShareController:       shareController,
+		SetupController:       controller.NewSetupController(setupService),
+		Advisories:            advisories,
+		InternalSigningKeys:   internalSigningKeys,
+		Reputation:            ipReputation,
+		TrustedProxies:        trustedProxies,
+		Clock:                 clock.Real{},
+	})
+
+	server := &http.Server{
+		Addr:              ":" + cfg.AppPort,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
 
-	log.Printf("Server starting on port %s...\n", cfg.AppPort)
-	if err := http.ListenAndServe(":"+cfg.AppPort, nil); err != nil {
+	log.Printf("Server starting on port %s (public URL %s)...\n", cfg.AppPort, cfg.PublicURL)
+	if err := server.ListenAndServe(); err != nil {
 		log.Fatal(err)
 	}
 }
@@ -0,0 +1,155 @@
+// Package advisory inspects the configuration for deprecated environment
+// variables and insecure settings. The server logs the findings at startup
+// and serves them at /admin/advisories; authctl doctor reports them too.
+package advisory
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/SarathLUN/go-auth-service/internal/config"
+	"github.com/SarathLUN/go-auth-service/internal/jwtkeys"
+)
+
+// Severity ranks how urgently an advisory should be addressed.
+type Severity string
+
+const (
+	Info     Severity = "info"
+	Warning  Severity = "warning"
+	Critical Severity = "critical"
+)
+
+// Advisory is a single finding with the steps needed to resolve it.
+type Advisory struct {
+	ID          string   `json:"id"`
+	Severity    Severity `json:"severity"`
+	Setting     string   `json:"setting"`
+	Message     string   `json:"message"`
+	Remediation string   `json:"remediation"`
+}
+
+// deprecation describes an environment variable that is no longer read.
+type deprecation struct {
+	Name        string
+	Replacement string
+	Note        string
+}
+
+// deprecatedEnv lists renamed or removed environment variables. Add an entry
+// whenever a setting is renamed so existing deployments are told about it
+// rather than silently falling back to defaults.
+var deprecatedEnv []deprecation
+
+// placeholderSecrets are JWT secrets shipped in defaults and examples.
+var placeholderSecrets = map[string]bool{
+	"secret":               true,
+	"your-very-secret-key": true,
+}
+
+// minHMACSecretBytes is the recommended minimum length of JWT_SECRET.
+const minHMACSecretBytes = 32
+
+// Check returns the advisories for cfg. lookupEnv is used to detect
+// deprecated variables, normally os.LookupEnv.
+func Check(cfg *config.Config, lookupEnv func(string) (string, bool)) []Advisory {
+	var out []Advisory
+
+	for _, d := range deprecatedEnv {
+		if _, set := lookupEnv(d.Name); !set {
+			continue
+		}
+		a := Advisory{
+			ID:       "deprecated-env-" + strings.ToLower(strings.ReplaceAll(d.Name, "_", "-")),
+			Severity: Warning,
+			Setting:  d.Name,
+			Message:  d.Name + " is deprecated and ignored. " + d.Note,
+		}
+		if d.Replacement != "" {
+			a.Remediation = "rename it to " + d.Replacement
+		} else {
+			a.Remediation = "remove it from the environment"
+		}
+		out = append(out, a)
+	}
+
+	if cfg.JWTSigningAlg == jwtkeys.HS256 || cfg.JWTSigningAlg == "" {
+		switch {
+		case placeholderSecrets[cfg.JWTSecret]:
+			out = append(out, Advisory{
+				ID:          "jwt-placeholder-secret",
+				Severity:    Critical,
+				Setting:     "JWT_SECRET",
+				Message:     "access tokens are signed with a publicly known placeholder secret, so anyone can forge them",
+				Remediation: "set JWT_SECRET to at least 32 random bytes, e.g. `openssl rand -base64 48`, or switch JWT_SIGNING_ALG to RS256/ES256",
+			})
+		case len(cfg.JWTSecret) < minHMACSecretBytes:
+			out = append(out, Advisory{
+				ID:          "jwt-short-secret",
+				Severity:    Warning,
+				Setting:     "JWT_SECRET",
+				Message:     fmt.Sprintf("JWT_SECRET is only %d bytes long", len(cfg.JWTSecret)),
+				Remediation: "use at least 32 random bytes for HMAC signing keys",
+			})
+		}
+		out = append(out, Advisory{
+			ID:          "jwt-shared-secret",
+			Severity:    Info,
+			Setting:     "JWT_SIGNING_ALG",
+			Message:     "HS256 tokens can only be verified by services that also hold JWT_SECRET",
+			Remediation: "set JWT_SIGNING_ALG to RS256 or ES256 with JWT_PRIVATE_KEY_FILE and verify tokens against /.well-known/jwks.json",
+		})
+	}
+
+	if cfg.JWTKeyStore == jwtkeys.StoreDatabase && cfg.JWTKeyRotationGrace < cfg.AccessTokenTTL {
+		out = append(out, Advisory{
+			ID:          "jwt-rotation-grace-short",
+			Severity:    Warning,
+			Setting:     "JWT_KEY_ROTATION_GRACE",
+			Message:     "tokens signed shortly before a key rotation stop verifying before they expire",
+			Remediation: "set JWT_KEY_ROTATION_GRACE to at least ACCESS_TOKEN_TTL (" + cfg.AccessTokenTTL.String() + ")",
+		})
+	}
+
+	if cfg.DBSSLMode == "disable" && !allLocal(cfg.DBHosts()) {
+		out = append(out, Advisory{
+			ID:          "db-sslmode-disable",
+			Severity:    Critical,
+			Setting:     "DB_SSL_MODE",
+			Message:     "database traffic, including password hashes, is sent unencrypted to a remote host",
+			Remediation: "set DB_SSL_MODE to verify-full (or at least require)",
+		})
+	}
+
+	if u, err := url.Parse(cfg.PublicURL); err == nil && u.Scheme == "http" && !isLocal(u.Hostname()) {
+		out = append(out, Advisory{
+			ID:          "public-url-http",
+			Severity:    Warning,
+			Setting:     "PUBLIC_URL",
+			Message:     "activation links and tokens are served over plain HTTP",
+			Remediation: "serve the service over HTTPS and set PUBLIC_URL to the https:// address",
+		})
+	}
+
+	return out
+}
+
+func allLocal(hosts []string) bool {
+	for _, h := range hosts {
+		if !isLocal(h) {
+			return false
+		}
+	}
+	return true
+}
+
+// isLocal reports whether host is a loopback address or a Unix socket directory.
+func isLocal(host string) bool {
+	if host == "localhost" || strings.HasPrefix(host, "/") {
+		return true
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	return ip != nil && ip.IsLoopback()
+}
@@ -1,30 +1,159 @@
 package config
 
 import (
-	"fmt"
 	"log"
 	"os"
+	"reflect"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 // Config holds all the configuration settings for the application.
 type Config struct {
-	DBHost          string `envconfig:"DB_HOST" default:"localhost"`
-	DBPort          string `envconfig:"DB_PORT" default:"5432"`
-	DBUser          string `envconfig:"DB_USER" default:"postgres"`
-	DBPassword      string `envconfig:"DB_PASSWORD" default:"postgres"`
-	DBName          string `envconfig:"DB_NAME" default:"postgres"`
-	DBSSLMode       string `envconfig:"DB_SSL_MODE" default:"disable"`
-	JWTSecret       string `envconfig:"JWT_SECRET" default:"secret" required:"true"`
-	SMTPHost        string `envconfig:"SMTP_HOST" default:"smtp.gmail.com"`
-	SMTPPort        string `envconfig:"SMTP_PORT" default:"587"`
-	SMTPUsername    string `enconfig:"SMTP_USERNAME"`
-	SMTPPassword    string `enconfig:"SMTP_PASSWORD"`
-	SMTPFromEmail   string `enconfig:"SMTP_FROM_EMAIL"`
-	AppPort         string `envconfig:"APP_PORT" default:"8080"`
-	ActivateBaseURL string `envconfig:"ACTIVATE_BASE_URL" default:"http://localhost:8080/activate"`
+	// Comma-separated for failover, in preference order.
+	DBHost       string `envconfig:"DB_HOST" default:"localhost"`
+	DBPort       string `envconfig:"DB_PORT" default:"5432"`
+	DBUser       string `envconfig:"DB_USER" default:"postgres"`
+	DBPassword   string `envconfig:"DB_PASSWORD" default:"postgres"`
+	DBName       string `envconfig:"DB_NAME" default:"postgres"`
+	DBSSLMode    string `envconfig:"DB_SSL_MODE" default:"disable"`
+	MigrationDir string `envconfig:"GOOSE_MIGRATION_DIR" default:"./migrations"`
+	JWTSecret    string `envconfig:"JWT_SECRET" default:"secret" required:"true"`
+	SMTPHost     string `envconfig:"SMTP_HOST" default:"smtp.example.com"`
+	// Common SMTP ports: 587 (TLS), 465 (SSL).
+	SMTPPort      string `envconfig:"SMTP_PORT" default:"587"`
+	SMTPUsername  string `envconfig:"SMTP_USERNAME"`
+	SMTPPassword  string `envconfig:"SMTP_PASSWORD"`
+	SMTPFromEmail string `envconfig:"SMTP_FROM_EMAIL" default:"noreply@example.com"`
+	AppPort       string `envconfig:"APP_PORT" default:"8080"`
+	// Prefix all routes are served under, e.g. /auth.
+	BasePath string `envconfig:"BASE_PATH"`
+	// Externally reachable URL of the service, used to build links.
+	PublicURL       string `envconfig:"PUBLIC_URL" default:"http://localhost:${APP_PORT}${BASE_PATH}"`
+	ActivateBaseURL string `envconfig:"ACTIVATE_BASE_URL" default:"${PUBLIC_URL}/activate"`
+
+	// Per host, before trying the next one.
+	DBConnectTimeout time.Duration `envconfig:"DB_CONNECT_TIMEOUT" default:"5s"`
+	// Retry dependencies at startup instead of exiting.
+	WaitForDeps        bool          `envconfig:"WAIT_FOR_DEPS" default:"false"`
+	WaitForDepsTimeout time.Duration `envconfig:"WAIT_FOR_DEPS_TIMEOUT" default:"1m"`
+	// Cancels a request's database queries and outbound calls; 0 disables.
+	RequestTimeout time.Duration `envconfig:"REQUEST_TIMEOUT" default:"30s"`
+
+	AccessTokenTTL     time.Duration `envconfig:"ACCESS_TOKEN_TTL" default:"15m"`
+	RefreshTokenTTL    time.Duration `envconfig:"REFRESH_TOKEN_TTL" default:"720h"`
+	ActivationTokenTTL time.Duration `envconfig:"ACTIVATION_TOKEN_TTL" default:"24h"`
+
+	PasswordResetBaseURL  string        `envconfig:"PASSWORD_RESET_BASE_URL" default:"${PUBLIC_URL}/password/reset"`
+	PasswordResetTokenTTL time.Duration `envconfig:"PASSWORD_RESET_TOKEN_TTL" default:"1h"`
+
+	PasswordMinLength       int    `envconfig:"PASSWORD_MIN_LENGTH" default:"8"`
+	PasswordRequiredClasses string `envconfig:"PASSWORD_REQUIRED_CLASSES"`
+	PasswordDenyCommon      bool   `envconfig:"PASSWORD_DENY_COMMON" default:"true"`
+	PasswordDenyEmail       bool   `envconfig:"PASSWORD_DENY_EMAIL" default:"true"`
+	PasswordMinScore        int    `envconfig:"PASSWORD_MIN_SCORE" default:"2"`
+
+	// argon2id or bcrypt; hashes of the other are upgraded at login.
+	PasswordHashAlgorithm string `envconfig:"PASSWORD_HASH_ALGORITHM" default:"argon2id"`
+	Argon2MemoryKiB       int    `envconfig:"ARGON2_MEMORY_KIB" default:"19456"`
+	Argon2Iterations      int    `envconfig:"ARGON2_ITERATIONS" default:"2"`
+	Argon2Parallelism     int    `envconfig:"ARGON2_PARALLELISM" default:"1"`
+
+	// Reject passwords found in breaches, via the HIBP range API.
+	PwnedPasswordsCheck    bool          `envconfig:"PWNED_PASSWORDS_CHECK" default:"false"`
+	PwnedPasswordsEndpoint string        `envconfig:"PWNED_PASSWORDS_ENDPOINT" default:"https://api.pwnedpasswords.com/range/"`
+	PwnedPasswordsCacheTTL time.Duration `envconfig:"PWNED_PASSWORDS_CACHE_TTL" default:"24h"`
+
+	// Failed logins within LOCKOUT_WINDOW that lock an account; 0 disables lockout.
+	LockoutThreshold int           `envconfig:"LOCKOUT_THRESHOLD" default:"5"`
+	LockoutWindow    time.Duration `envconfig:"LOCKOUT_WINDOW" default:"15m"`
+	LockoutDuration  time.Duration `envconfig:"LOCKOUT_DURATION" default:"15m"`
+
+	RateLimitIPPerMinute      int `envconfig:"RATE_LIMIT_IP_PER_MINUTE" default:"30"`
+	RateLimitIPBurst          int `envconfig:"RATE_LIMIT_IP_BURST" default:"10"`
+	RateLimitAccountPerMinute int `envconfig:"RATE_LIMIT_ACCOUNT_PER_MINUTE" default:"10"`
+	RateLimitAccountBurst     int `envconfig:"RATE_LIMIT_ACCOUNT_BURST" default:"5"`
+	// For IPs scoring IP_REPUTATION_THRESHOLD or more; 0 blocks them.
+	RateLimitBadIPPerMinute int `envconfig:"RATE_LIMIT_BAD_IP_PER_MINUTE" default:"5"`
+	RateLimitBadIPBurst     int `envconfig:"RATE_LIMIT_BAD_IP_BURST" default:"2"`
+
+	// Comma-separated CIDRs of reverse proxies whose X-Forwarded-For is used.
+	TrustedProxies string `envconfig:"TRUSTED_PROXIES"`
+
+	LoginEventsLog        bool   `envconfig:"LOGIN_EVENTS_LOG" default:"true"`
+	LoginEventsExportFile string `envconfig:"LOGIN_EVENTS_EXPORT_FILE"`
+
+	// HS256 (JWT_SECRET), RS256 or ES256 (private key).
+	JWTSigningAlg string `envconfig:"JWT_SIGNING_ALG" default:"HS256"`
+	// PEM private key for RS256/ES256.
+	JWTPrivateKey     string `envconfig:"JWT_PRIVATE_KEY"`
+	JWTPrivateKeyFile string `envconfig:"JWT_PRIVATE_KEY_FILE"`
+
+	ClaimNamespace string `envconfig:"CLAIM_NAMESPACE"`
+	ClaimMapping   string `envconfig:"CLAIM_MAPPING"`
+
+	// config, or database for rotatable keys.
+	JWTKeyStore string `envconfig:"JWT_KEY_STORE" default:"config"`
+	// base64 32 bytes; encrypts keys in the database.
+	JWTKeyEncryptionKey string `envconfig:"JWT_KEY_ENCRYPTION_KEY"`
+	// Retired keys keep verifying this long.
+	JWTKeyRotationGrace   time.Duration `envconfig:"JWT_KEY_ROTATION_GRACE" default:"1h"`
+	JWTKeyRefreshInterval time.Duration `envconfig:"JWT_KEY_REFRESH_INTERVAL" default:"1m"`
+
+	// smtp, or log to print emails instead of sending them.
+	EmailDriver string `envconfig:"EMAIL_DRIVER" default:"smtp"`
+
+	// Send email links through GET /links, which records clicks.
+	EmailLinkProxy bool `envconfig:"EMAIL_LINK_PROXY" default:"false"`
+
+	// Treat Gmail dot/plus variants as one address.
+	EmailNormalizeGmail bool `envconfig:"EMAIL_NORMALIZE_GMAIL" default:"false"`
+	// Ignore "+tag" suffixes for all domains.
+	EmailStripSubaddress bool `envconfig:"EMAIL_STRIP_SUBADDRESS" default:"false"`
+
+	// none, static, abuseipdb or a comma-separated combination.
+	IPReputationProvider string `envconfig:"IP_REPUTATION_PROVIDER" default:"none"`
+	// Comma-separated CIDRs for the static provider.
+	IPReputationBlocklist string        `envconfig:"IP_REPUTATION_BLOCKLIST"`
+	IPReputationCacheTTL  time.Duration `envconfig:"IP_REPUTATION_CACHE_TTL" default:"1h"`
+	// Scores at or above this are treated as bad.
+	IPReputationThreshold int    `envconfig:"IP_REPUTATION_THRESHOLD" default:"75"`
+	AbuseIPDBAPIKey       string `envconfig:"ABUSEIPDB_API_KEY"`
+
+	CompressionEnabled bool `envconfig:"COMPRESSION_ENABLED" default:"true"`
+	// Extra prefixes; routes returning secrets are never compressed.
+	CompressionExcludePaths string `envconfig:"COMPRESSION_EXCLUDE_PATHS"`
+
+	// keyID:secret pairs for signed internal calls.
+	InternalSigningKeys      string        `envconfig:"INTERNAL_SIGNING_KEYS"`
+	InternalSigningTolerance time.Duration `envconfig:"INTERNAL_SIGNING_TOLERANCE" default:"5m"`
+
+	// Encrypts authctl backups.
+	BackupPassphrase string `envconfig:"BACKUP_PASSPHRASE"`
+
+	// Extra trusted CAs (PEM) for outbound TLS.
+	OutboundCAFile  string        `envconfig:"OUTBOUND_CA_FILE"`
+	OutboundTimeout time.Duration `envconfig:"OUTBOUND_TIMEOUT" default:"10s"`
+
+	// Shown next to the account in authenticator apps.
+	MFAIssuer string `envconfig:"MFA_ISSUER" default:"go-auth-service"`
+	// base64 32 bytes; encrypts TOTP secrets, required for MFA.
+	MFAEncryptionKey string        `envconfig:"MFA_ENCRYPTION_KEY"`
+	MFAChallengeTTL  time.Duration `envconfig:"MFA_CHALLENGE_TTL" default:"5m"`
+
+	// How long a QR login code can be approved.
+	QRLoginTTL time.Duration `envconfig:"QR_LOGIN_TTL" default:"2m"`
+
+	DeviceCodeTTL time.Duration `envconfig:"DEVICE_CODE_TTL" default:"10m"`
+	// Page where users enter device user codes.
+	DeviceVerificationURL string `envconfig:"DEVICE_VERIFICATION_URL" default:"${PUBLIC_URL}/oauth/device"`
+
+	// Guards POST /setup; generated and logged when empty.
+	SetupToken string `envconfig:"SETUP_TOKEN"`
 }
 
 var (
@@ -32,7 +161,8 @@ var (
 	config *Config
 )
 
-// LoadConfig loads configuration from environment variables.
+// LoadConfig loads configuration from environment variables. Unset
+// variables take the default from the field's tag.
 func LoadConfig() *Config {
 	once.Do(func() {
 		// load environment variables from .env file (if it exists).
@@ -41,55 +171,138 @@ func LoadConfig() *Config {
 			log.Println("Warning: .env file not found. Using default values.")
 		}
 	})
-	// Retrieve environment variables, providing defaults if not set.
-	dbHost := getEnv("DB_HOST", "localhost")
-	dbPortStr := getEnv("DB_PORT", "5432")
-	dbUser := getEnv("DB_USER", "postgres")
-	dbPassword := getEnv("DB_PASSWORD", "postgres")
-	dbName := getEnv("DB_NAME", "postgres")
-	dbSslMode := getEnv("DB_SSL_MODE", "disable")
-	jwtSecret := getEnv("JWT_SECRET", "secret")
-	smtpHost := getEnv("SMTP_HOST", "smtp.example.com")               // Example - use your SMTP server
-	smtpPortStr := getEnv("SMTP_PORT", "587")                         // Common SMTP ports: 587 (TLS), 465 (SSL)
-	smtpUsername := getEnv("SMTP_USERNAME", "")                       // Your SMTP username (if required)
-	smtpPassword := getEnv("SMTP_PASSWORD", "")                       // Your SMTP password
-	smtpFromEmail := getEnv("SMTP_FROM_EMAIL", "noreply@example.com") // Sender email
-	appPort := getEnv("APP_PORT", "8080")                             // Default to port 8080
-	activateBaseURL := getEnv("ACTIVATE_BASE_URL", "http://localhost:3000")
-
-	// Create the Config instance.
-	config = &Config{
-		DBHost:          dbHost,
-		DBPort:          dbPortStr,
-		DBUser:          dbUser,
-		DBPassword:      dbPassword,
-		DBName:          dbName,
-		DBSSLMode:       dbSslMode,
-		JWTSecret:       jwtSecret,
-		SMTPHost:        smtpHost,
-		SMTPPort:        smtpPortStr,
-		SMTPUsername:    smtpUsername,
-		SMTPPassword:    smtpPassword,
-		SMTPFromEmail:   smtpFromEmail,
-		AppPort:         appPort,
-		ActivateBaseURL: activateBaseURL,
-	}
+	config = load(os.Getenv)
 	return config
 }
 
-// getEnv retrieves an environment variable with a default value.
-func getEnv(key, defaultValue string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
+// Defaults returns the configuration with every option at its default, as
+// LoadConfig would return it from an empty environment.
+func Defaults() *Config {
+	return load(func(string) string { return "" })
+}
+
+// normalizers clean up option values before they are stored and before other
+// defaults refer to them.
+var normalizers = map[string]func(string) string{
+	"BASE_PATH":  normalizeBasePath,
+	"PUBLIC_URL": func(u string) string { return strings.TrimRight(u, "/") },
+}
+
+// load fills a Config from getenv, falling back to the default tags. A
+// default may refer to options declared before it as ${NAME}, and sees
+// their loaded values. Values that do not parse are logged and replaced by
+// the default.
+func load(getenv func(string) string) *Config {
+	cfg := &Config{}
+	values := make(map[string]string)
+	v := reflect.ValueOf(cfg).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := f.Tag.Get("envconfig")
+		if name == "" {
+			continue
+		}
+		def := os.Expand(f.Tag.Get("default"), func(ref string) string { return values[ref] })
+		value := getenv(name)
+		if value == "" {
+			value = def
+		}
+		if normalize, ok := normalizers[name]; ok {
+			value = normalize(value)
+		}
+		if err := setField(v.Field(i), value); err != nil {
+			log.Printf("Warning: invalid value %q for %s, using default %s", value, name, def)
+			value = def
+			setField(v.Field(i), value)
+		}
+		values[name] = value
+	}
+	return cfg
+}
+
+// setField parses s into a Config field of one of the option types.
+func setField(v reflect.Value, s string) error {
+	if s == "" {
+		v.SetZero()
+		return nil
+	}
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(s)
+		v.SetInt(int64(d))
+		return err
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		v.SetBool(b)
+		return err
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.Atoi(s)
+		v.SetInt(int64(n))
+		return err
+	default:
+		v.SetString(s)
+		return nil
 	}
-	return value
 }
 
-// GetDBConnectionString builds the database connection string.
+// normalizeBasePath turns "auth/" or "/auth/" into "/auth", and "/" into "".
+func normalizeBasePath(p string) string {
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return ""
+	}
+	return "/" + p
+}
+
+// AbsoluteURL builds an externally reachable URL for a route path such as
+// "/activate", using PUBLIC_URL rather than the listen address so links work
+// behind reverse proxies and ingress path prefixes.
+func (c *Config) AbsoluteURL(path string) string {
+	return c.PublicURL + "/" + strings.TrimLeft(path, "/")
+}
+
+// DBHosts returns the database hosts from DB_HOST in preference order.
+// DB_HOST may list several comma-separated hosts.
+func (c *Config) DBHosts() []string {
+	var hosts []string
+	for _, h := range strings.Split(c.DBHost, ",") {
+		if h = strings.TrimSpace(h); h != "" {
+			hosts = append(hosts, h)
+		}
+	}
+	return hosts
+}
+
+// GetDBConnectionString builds the database connection string. With several
+// hosts the driver tries them in order and only settles on a writable
+// primary, so a standby promoted after a failover is picked up on reconnect.
+// DB_PORT may likewise list one port per host.
 func (c *Config) GetDBConnectionString() string {
-	return fmt.Sprintf(
-		"postgres://%s:%s@%s:%s/%s?sslmode=%s",
-		c.DBUser, c.DBPassword, c.DBHost, c.DBPort, c.DBName, c.DBSSLMode,
-	)
+	hosts := c.DBHosts()
+	params := [][2]string{
+		{"host", strings.Join(hosts, ",")},
+		{"port", c.DBPort},
+		{"user", c.DBUser},
+		{"password", c.DBPassword},
+		{"dbname", c.DBName},
+		{"sslmode", c.DBSSLMode},
+		{"connect_timeout", strconv.Itoa(int(c.DBConnectTimeout.Seconds()))},
+	}
+	if len(hosts) > 1 {
+		params = append(params, [2]string{"target_session_attrs", "read-write"})
+	}
+
+	parts := make([]string, len(params))
+	for i, p := range params {
+		parts[i] = p[0] + "=" + quoteDSNValue(p[1])
+	}
+	return strings.Join(parts, " ")
+}
+
+// quoteDSNValue quotes a keyword/value connection string value.
+func quoteDSNValue(v string) string {
+	v = strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v)
+	return "'" + v + "'"
 }
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDefaultsParse(t *testing.T) {
+	for _, o := range Options() {
+		if o.Default == "" {
+			continue
+		}
+		if err := o.Check(o.Default); err != nil {
+			t.Errorf("default: %v", err)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name  string
+		env   map[string]string
+		check func(t *testing.T, cfg *Config)
+	}{
+		{"defaults", nil, func(t *testing.T, cfg *Config) {
+			if cfg.AccessTokenTTL != 15*time.Minute || cfg.Argon2MemoryKiB != 19*1024 || !cfg.PasswordDenyCommon {
+				t.Errorf("got AccessTokenTTL %s, Argon2MemoryKiB %d, PasswordDenyCommon %t", cfg.AccessTokenTTL, cfg.Argon2MemoryKiB, cfg.PasswordDenyCommon)
+			}
+			if cfg.PublicURL != "http://localhost:8080" || cfg.ActivateBaseURL != "http://localhost:8080/activate" {
+				t.Errorf("got PublicURL %q, ActivateBaseURL %q", cfg.PublicURL, cfg.ActivateBaseURL)
+			}
+		}},
+		{"derived URLs", map[string]string{"APP_PORT": "9000", "BASE_PATH": "auth/"}, func(t *testing.T, cfg *Config) {
+			if cfg.BasePath != "/auth" || cfg.PublicURL != "http://localhost:9000/auth" {
+				t.Errorf("got BasePath %q, PublicURL %q", cfg.BasePath, cfg.PublicURL)
+			}
+			if cfg.DeviceVerificationURL != "http://localhost:9000/auth/oauth/device" {
+				t.Errorf("got DeviceVerificationURL %q", cfg.DeviceVerificationURL)
+			}
+		}},
+		{"public URL", map[string]string{"PUBLIC_URL": "https://id.example.com/", "ACTIVATE_BASE_URL": "https://app.example.com/activate"}, func(t *testing.T, cfg *Config) {
+			if cfg.PasswordResetBaseURL != "https://id.example.com/password/reset" {
+				t.Errorf("got PasswordResetBaseURL %q", cfg.PasswordResetBaseURL)
+			}
+			if cfg.ActivateBaseURL != "https://app.example.com/activate" {
+				t.Errorf("got ActivateBaseURL %q", cfg.ActivateBaseURL)
+			}
+		}},
+		{"invalid values", map[string]string{"LOCKOUT_THRESHOLD": "five", "LOCKOUT_WINDOW": "soon", "PASSWORD_DENY_EMAIL": "maybe"}, func(t *testing.T, cfg *Config) {
+			if cfg.LockoutThreshold != 5 || cfg.LockoutWindow != 15*time.Minute || !cfg.PasswordDenyEmail {
+				t.Errorf("got LockoutThreshold %d, LockoutWindow %s, PasswordDenyEmail %t", cfg.LockoutThreshold, cfg.LockoutWindow, cfg.PasswordDenyEmail)
+			}
+		}},
+		{"set values", map[string]string{"LOCKOUT_THRESHOLD": "0", "REQUEST_TIMEOUT": "0s", "COMPRESSION_ENABLED": "false"}, func(t *testing.T, cfg *Config) {
+			if cfg.LockoutThreshold != 0 || cfg.RequestTimeout != 0 || cfg.CompressionEnabled {
+				t.Errorf("got LockoutThreshold %d, RequestTimeout %s, CompressionEnabled %t", cfg.LockoutThreshold, cfg.RequestTimeout, cfg.CompressionEnabled)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.check(t, load(func(name string) string { return tt.env[name] }))
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// Option describes one environment variable understood by LoadConfig, as
+// declared by the envconfig/default/required tags on Config. The tags are
+// the only place defaults are written down.
+type Option struct {
+	Name     string // environment variable, e.g. DB_HOST
+	Field    string // Config field it populates
+	Type     string // string, boolean, integer or duration
+	Default  string // with references to other options expanded to their defaults
+	Required bool
+}
+
+// Options lists every configuration option in Config field order.
+func Options() []Option {
+	t := reflect.TypeOf(Config{})
+	opts := make([]Option, 0, t.NumField())
+	defaults := make(map[string]string)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := f.Tag.Get("envconfig")
+		if name == "" {
+			continue
+		}
+		def := os.Expand(f.Tag.Get("default"), func(ref string) string { return defaults[ref] })
+		defaults[name] = def
+		opts = append(opts, Option{
+			Name:     name,
+			Field:    f.Name,
+			Type:     optionType(f.Type),
+			Default:  def,
+			Required: f.Tag.Get("required") == "true",
+		})
+	}
+	return opts
+}
+
+func optionType(t reflect.Type) string {
+	if t == reflect.TypeOf(time.Duration(0)) {
+		return "duration"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int64:
+		return "integer"
+	default:
+		return "string"
+	}
+}
+
+// Check reports whether value parses as the option's type.
+func (o Option) Check(value string) error {
+	var err error
+	switch o.Type {
+	case "boolean":
+		_, err = strconv.ParseBool(value)
+	case "integer":
+		_, err = strconv.Atoi(value)
+	case "duration":
+		_, err = time.ParseDuration(value)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %q is not a valid %s", o.Name, value, o.Type)
+	}
+	return nil
+}
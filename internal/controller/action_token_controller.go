@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/service/actiontoken"
+)
+
+// ActionTokenController lets trusted services issue and consume single-use
+// tokens for their own flows, such as unsubscribe links.
+type ActionTokenController struct {
+	tokens *actiontoken.Service
+}
+
+// NewActionTokenController creates an ActionTokenController.
+func NewActionTokenController(tokens *actiontoken.Service) *ActionTokenController {
+	return &ActionTokenController{tokens: tokens}
+}
+
+type issueActionTokenRequest struct {
+	Purpose    string          `json:"purpose"`
+	Subject    string          `json:"subject"`
+	Payload    json.RawMessage `json:"payload"`
+	TTLSeconds int64           `json:"ttl_seconds"`
+}
+
+type issueActionTokenResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Issue handles POST /admin/action-tokens.
+func (c *ActionTokenController) Issue(w http.ResponseWriter, r *http.Request) {
+	var req issueActionTokenRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	token, stored, err := c.tokens.IssueExternal(r.Context(), actiontoken.IssueInput{
+		Purpose: req.Purpose,
+		Subject: req.Subject,
+		Payload: req.Payload,
+		TTL:     time.Duration(req.TTLSeconds) * time.Second,
+	})
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusCreated, issueActionTokenResponse{Token: token, ExpiresAt: stored.ExpiresAt})
+}
+
+type consumeActionTokenRequest struct {
+	Purpose string `json:"purpose"`
+	Token   string `json:"token"`
+}
+
+// actionTokenResponse matches the ActionToken schema in api/openapi.yaml.
+type actionTokenResponse struct {
+	Purpose   string          `json:"purpose"`
+	Subject   string          `json:"subject"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Consume handles POST /admin/action-tokens/consume. A token can be
+// consumed once, and only for the purpose it was issued for.
+func (c *ActionTokenController) Consume(w http.ResponseWriter, r *http.Request) {
+	var req consumeActionTokenRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Purpose == "" || req.Token == "" {
+		writeError(w, http.StatusBadRequest, "purpose and token are required")
+		return
+	}
+
+	stored, err := c.tokens.ConsumeExternal(r.Context(), req.Purpose, req.Token)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, actionTokenResponse{
+		Purpose:   stored.Purpose,
+		Subject:   stored.Subject,
+		Payload:   stored.Payload,
+		CreatedAt: stored.CreatedAt,
+	})
+}
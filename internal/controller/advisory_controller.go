@@ -0,0 +1,22 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/SarathLUN/go-auth-service/internal/advisory"
+)
+
+type advisoriesResponse struct {
+	Advisories []advisory.Advisory `json:"advisories"`
+}
+
+// Advisories returns a handler for GET /admin/advisories, which lists the
+// configuration advisories found at startup.
+func Advisories(list []advisory.Advisory) http.HandlerFunc {
+	if list == nil {
+		list = []advisory.Advisory{}
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, advisoriesResponse{Advisories: list})
+	}
+}
@@ -0,0 +1,597 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/middleware"
+	"github.com/SarathLUN/go-auth-service/internal/model"
+	"github.com/SarathLUN/go-auth-service/internal/password"
+	"github.com/SarathLUN/go-auth-service/internal/repository"
+	"github.com/SarathLUN/go-auth-service/internal/service/actiontoken"
+	"github.com/SarathLUN/go-auth-service/internal/service/auth"
+	"github.com/SarathLUN/go-auth-service/internal/service/oauth"
+	"github.com/SarathLUN/go-auth-service/internal/service/setup"
+	"github.com/SarathLUN/go-auth-service/internal/service/share"
+	"github.com/SarathLUN/go-auth-service/internal/service/user"
+)
+
+// AuthController exposes the authentication endpoints.
+type AuthController struct {
+	auth *auth.Service
+}
+
+// NewAuthController creates an AuthController.
+func NewAuthController(authService *auth.Service) *AuthController {
+	return &AuthController{auth: authService}
+}
+
+type registerRequest struct {
+	Email    string `json:"email"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Locale   string `json:"locale"`
+	Timezone string `json:"timezone"`
+}
+
+// Register handles POST /register.
+func (c *AuthController) Register(w http.ResponseWriter, r *http.Request) {
+	var req registerRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	_, err := c.auth.Register(r.Context(), auth.RegisterInput{
+		Email:    req.Email,
+		Username: req.Username,
+		Password: req.Password,
+		Locale:   req.Locale,
+		Timezone: req.Timezone,
+	})
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, messageResponse{
+		Message: "User registered successfully. Please check your email to activate your account.",
+	})
+}
+
+// Activate handles GET /activate?token=...
+func (c *AuthController) Activate(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		writeError(w, http.StatusBadRequest, "token is required")
+		return
+	}
+	if err := c.auth.Activate(r.Context(), token); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, messageResponse{Message: "Account activated successfully."})
+}
+
+// FollowEmailLink handles GET /links?token=..., where activation and password
+// reset emails link to when EMAIL_LINK_PROXY is set. It records the click and
+// redirects to the page the link is for with a new short-lived token. No
+// Referer is sent along, so the link doesn't leak to that page or anything
+// it loads.
+func (c *AuthController) FollowEmailLink(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		writeError(w, http.StatusBadRequest, "token is required")
+		return
+	}
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("Cache-Control", "no-store")
+	target, err := c.auth.FollowEmailLink(r.Context(), token)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// ResetPassword handles POST /password/reset, which sets a new password with
+// the token from a password reset email.
+func (c *AuthController) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req resetPasswordRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Token == "" {
+		writeError(w, http.StatusBadRequest, "token is required")
+		return
+	}
+
+	if err := c.auth.ResetPassword(r.Context(), req.Token, req.Password); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, messageResponse{Message: "Password changed. You can now log in with the new password."})
+}
+
+type loginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// tokenResponse matches the LoginResponse schema in api/openapi.yaml.
+type tokenResponse struct {
+	Message      string `json:"message"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// Login handles POST /login.
+func (c *AuthController) Login(w http.ResponseWriter, r *http.Request) {
+	var req loginRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Email == "" || req.Password == "" {
+		writeError(w, http.StatusBadRequest, "email and password are required")
+		return
+	}
+
+	result, err := c.auth.Login(r.Context(), req.Email, req.Password)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	if result.MFAChallenge != "" {
+		w.Header().Set("Cache-Control", "no-store")
+		writeJSON(w, http.StatusOK, mfaChallengeResponse{
+			Message:      "MFA code required",
+			MFARequired:  true,
+			MFAChallenge: result.MFAChallenge,
+		})
+		return
+	}
+
+	writeTokens(w, "Login successful", result.Tokens)
+}
+
+// mfaChallengeResponse matches the MFAChallengeResponse schema in api/openapi.yaml.
+type mfaChallengeResponse struct {
+	Message      string `json:"message"`
+	MFARequired  bool   `json:"mfa_required"`
+	MFAChallenge string `json:"mfa_challenge"`
+}
+
+type mfaLoginRequest struct {
+	MFAChallenge string `json:"mfa_challenge"`
+	Code         string `json:"code"`
+}
+
+// LoginMFA handles POST /login/mfa, the second step of a login for accounts
+// with MFA enabled.
+func (c *AuthController) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	var req mfaLoginRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.MFAChallenge == "" || req.Code == "" {
+		writeError(w, http.StatusBadRequest, "mfa_challenge and code are required")
+		return
+	}
+
+	tokens, err := c.auth.CompleteMFALogin(r.Context(), req.MFAChallenge, req.Code)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeTokens(w, "Login successful", tokens)
+}
+
+// totpEnrollmentResponse matches the TOTPEnrollment schema in api/openapi.yaml.
+type totpEnrollmentResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// EnrollTOTP handles POST /mfa/totp/enroll. It must be behind middleware.RequireAuth.
+func (c *AuthController) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	enrollment, err := c.auth.EnrollTOTP(r.Context(), middleware.ClaimsFromContext(r.Context()).UserID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, totpEnrollmentResponse{Secret: enrollment.Secret, OTPAuthURI: enrollment.URI})
+}
+
+type totpCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// VerifyTOTP handles POST /mfa/totp/verify, which confirms an enrollment
+// with a first code. It must be behind middleware.RequireAuth.
+func (c *AuthController) VerifyTOTP(w http.ResponseWriter, r *http.Request) {
+	var req totpCodeRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Code == "" {
+		writeError(w, http.StatusBadRequest, "code is required")
+		return
+	}
+
+	codes, err := c.auth.ConfirmTOTP(r.Context(), middleware.ClaimsFromContext(r.Context()).UserID, req.Code)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, recoveryCodesResponse{Message: "MFA enabled", RecoveryCodes: codes})
+}
+
+// sessionListResponse matches the SessionList schema in api/openapi.yaml.
+type sessionListResponse struct {
+	Sessions []*model.Session `json:"sessions"`
+}
+
+// ListSessions handles GET /sessions. It must be behind middleware.RequireAuth.
+func (c *AuthController) ListSessions(w http.ResponseWriter, r *http.Request) {
+	sessions, err := c.auth.ListSessions(r.Context(), middleware.ClaimsFromContext(r.Context()).UserID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, sessionListResponse{Sessions: sessions})
+}
+
+// RevokeSession handles DELETE /sessions/{id}, which signs the user out of
+// one of their sessions. It must be behind middleware.RequireAuth.
+func (c *AuthController) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	err := c.auth.RevokeSession(r.Context(), middleware.ClaimsFromContext(r.Context()).UserID, r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, messageResponse{Message: "Session revoked"})
+}
+
+// preferences matches the Preferences schema in api/openapi.yaml.
+type preferences struct {
+	Locale   string `json:"locale"`
+	Timezone string `json:"timezone"`
+}
+
+// UpdatePreferences handles PUT /preferences, which sets the locale and time
+// zone sent in the user's next access tokens. It must be behind
+// middleware.RequireAuth.
+func (c *AuthController) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	var req preferences
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	u, err := c.auth.SetPreferences(r.Context(), middleware.ClaimsFromContext(r.Context()).UserID, auth.Preferences{
+		Locale:   req.Locale,
+		Timezone: req.Timezone,
+	})
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, preferences{Locale: u.Locale, Timezone: u.Timezone})
+}
+
+// recoveryCodesResponse matches the RecoveryCodes schema in api/openapi.yaml.
+type recoveryCodesResponse struct {
+	Message       string   `json:"message"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// RegenerateRecoveryCodes handles POST /mfa/recovery-codes. It must be
+// behind middleware.RequireAuth.
+func (c *AuthController) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	var req totpCodeRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Code == "" {
+		writeError(w, http.StatusBadRequest, "code is required")
+		return
+	}
+
+	codes, err := c.auth.RegenerateRecoveryCodes(r.Context(), middleware.ClaimsFromContext(r.Context()).UserID, req.Code)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, recoveryCodesResponse{Message: "Recovery codes regenerated", RecoveryCodes: codes})
+}
+
+// CountRecoveryCodes handles GET /mfa/recovery-codes. It must be behind
+// middleware.RequireAuth.
+func (c *AuthController) CountRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	n, err := c.auth.RemainingRecoveryCodes(r.Context(), middleware.ClaimsFromContext(r.Context()).UserID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, recoveryCodeCountResponse{Remaining: n})
+}
+
+type recoveryCodeCountResponse struct {
+	Remaining int `json:"remaining"`
+}
+
+// qrLoginResponse matches the QRLoginStart schema in api/openapi.yaml.
+type qrLoginResponse struct {
+	Code      string `json:"code"`
+	PollToken string `json:"poll_token"`
+	ExpiresIn int64  `json:"expires_in"`
+	Interval  int64  `json:"interval"`
+}
+
+// StartQRLogin handles POST /login/qr, called by a device that wants to be
+// signed in from another device. It shows code as a QR and polls with
+// poll_token.
+func (c *AuthController) StartQRLogin(w http.ResponseWriter, r *http.Request) {
+	start, err := c.auth.StartQRLogin(r.Context())
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, qrLoginResponse{
+		Code:      start.Code,
+		PollToken: start.PollToken,
+		ExpiresIn: int64(start.ExpiresIn.Seconds()),
+		Interval:  int64(auth.QRLoginPollInterval.Seconds()),
+	})
+}
+
+// qrLoginRequestResponse matches the QRLoginRequest schema in api/openapi.yaml.
+type qrLoginRequestResponse struct {
+	UserAgent string    `json:"user_agent"`
+	ClientIP  string    `json:"client_ip"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// LookupQRLogin handles GET /login/qr/approve?code=..., which shows the
+// signed-in user the device asking to be signed in before they approve it.
+// It must be behind middleware.RequireAuth.
+func (c *AuthController) LookupQRLogin(w http.ResponseWriter, r *http.Request) {
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		writeError(w, http.StatusBadRequest, "code is required")
+		return
+	}
+
+	req, err := c.auth.LookupQRLogin(r.Context(), code)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, qrLoginRequestResponse{
+		UserAgent: req.UserAgent,
+		ClientIP:  req.ClientIP,
+		CreatedAt: req.CreatedAt,
+		ExpiresAt: req.ExpiresAt,
+	})
+}
+
+type qrLoginApproveRequest struct {
+	Code string `json:"code"`
+}
+
+// ApproveQRLogin handles POST /login/qr/approve, called with the scanned code
+// by a device where the user is signed in, after LookupQRLogin showed them
+// the requesting device. It must be behind middleware.RequireAuth.
+func (c *AuthController) ApproveQRLogin(w http.ResponseWriter, r *http.Request) {
+	var req qrLoginApproveRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Code == "" {
+		writeError(w, http.StatusBadRequest, "code is required")
+		return
+	}
+
+	if err := c.auth.ApproveQRLogin(r.Context(), middleware.ClaimsFromContext(r.Context()).UserID, req.Code); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, messageResponse{Message: "Login approved"})
+}
+
+type qrLoginPollRequest struct {
+	PollToken string `json:"poll_token"`
+}
+
+// PollQRLogin handles POST /login/qr/poll. It answers 202 until the login is
+// approved and then returns tokens, once.
+func (c *AuthController) PollQRLogin(w http.ResponseWriter, r *http.Request) {
+	var req qrLoginPollRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.PollToken == "" {
+		writeError(w, http.StatusBadRequest, "poll_token is required")
+		return
+	}
+
+	tokens, err := c.auth.PollQRLogin(r.Context(), req.PollToken)
+	if errors.Is(err, auth.ErrQRLoginPending) {
+		w.Header().Set("Cache-Control", "no-store")
+		writeJSON(w, http.StatusAccepted, messageResponse{Message: "Waiting for approval"})
+		return
+	}
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeTokens(w, "Login successful", tokens)
+}
+
+// Refresh handles POST /token/refresh.
+func (c *AuthController) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req refreshRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.RefreshToken == "" {
+		writeError(w, http.StatusBadRequest, "refresh_token is required")
+		return
+	}
+
+	tokens, err := c.auth.Refresh(r.Context(), req.RefreshToken)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeTokens(w, "Token refreshed", tokens)
+}
+
+// Logout handles POST /logout. It must be behind middleware.RequireAuth.
+// The access token used to call it is revoked; an optional refresh_token in
+// the body revokes that session's refresh tokens too.
+func (c *AuthController) Logout(w http.ResponseWriter, r *http.Request) {
+	var req refreshRequest
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)).Decode(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := c.auth.Logout(r.Context(), middleware.ClaimsFromContext(r.Context()), req.RefreshToken); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, messageResponse{Message: "Logged out"})
+}
+
+func writeTokens(w http.ResponseWriter, msg string, tokens *auth.TokenPair) {
+	w.Header().Set("Authorization", "Bearer "+tokens.AccessToken)
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, tokenResponse{
+		Message:      msg,
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(tokens.ExpiresIn.Seconds()),
+	})
+}
+
+// validationErrorResponse is the ErrorResponse schema in api/openapi.yaml
+// with the details of an *auth.ValidationError.
+type validationErrorResponse struct {
+	Error      string               `json:"error"`
+	Field      string               `json:"field"`
+	Violations []password.Violation `json:"violations,omitempty"`
+}
+
+// writeServiceError maps errors returned by the service layer to responses.
+func writeServiceError(w http.ResponseWriter, err error) {
+	var verr *auth.ValidationError
+	switch {
+	case errors.As(err, &verr):
+		writeJSON(w, http.StatusBadRequest, validationErrorResponse{Error: verr.Error(), Field: verr.Field, Violations: verr.Violations})
+	case errors.Is(err, auth.ErrInvalidCredentials):
+		writeError(w, http.StatusUnauthorized, "Invalid email or password")
+	case errors.Is(err, auth.ErrAccountLocked):
+		writeError(w, http.StatusLocked, "Account is temporarily locked after too many failed logins; try again later")
+	case errors.Is(err, auth.ErrInvalidActivationToken):
+		writeError(w, http.StatusBadRequest, "Invalid or expired activation token")
+	case errors.Is(err, auth.ErrInvalidPasswordResetToken):
+		writeError(w, http.StatusBadRequest, "Invalid or expired password reset token")
+	case errors.Is(err, auth.ErrInvalidEmailLink):
+		writeError(w, http.StatusGone, "This link is invalid, has expired or has already been used")
+	case errors.Is(err, auth.ErrInvalidRefreshToken):
+		writeError(w, http.StatusUnauthorized, "Invalid or expired refresh token")
+	case errors.Is(err, auth.ErrRefreshTokenReused):
+		writeError(w, http.StatusUnauthorized, "Refresh token has already been used; please log in again")
+	case errors.Is(err, auth.ErrInvalidAccessToken):
+		writeError(w, http.StatusUnauthorized, "Invalid or expired access token")
+	case errors.Is(err, auth.ErrInvalidMFACode):
+		writeError(w, http.StatusUnauthorized, "Invalid MFA code")
+	case errors.Is(err, auth.ErrInvalidMFAChallenge):
+		writeError(w, http.StatusUnauthorized, "Invalid or expired MFA challenge; please log in again")
+	case errors.Is(err, auth.ErrMFAAlreadyEnabled):
+		writeError(w, http.StatusConflict, "MFA is already enabled")
+	case errors.Is(err, auth.ErrMFANotEnabled):
+		writeError(w, http.StatusBadRequest, "MFA is not enabled")
+	case errors.Is(err, auth.ErrMFANotEnrolled):
+		writeError(w, http.StatusBadRequest, "Start enrollment with /mfa/totp/enroll first")
+	case errors.Is(err, auth.ErrMFAUnavailable):
+		writeError(w, http.StatusServiceUnavailable, "MFA is not available")
+	case errors.Is(err, auth.ErrInvalidQRLogin):
+		writeError(w, http.StatusBadRequest, "Invalid or expired QR login")
+	case errors.Is(err, auth.ErrSessionNotFound):
+		writeError(w, http.StatusNotFound, "Session not found")
+	case errors.Is(err, auth.ErrUserNotActive):
+		writeError(w, http.StatusUnauthorized, "Account has not been activated")
+	case errors.Is(err, repository.ErrDuplicateEmail):
+		writeError(w, http.StatusConflict, "A user with this email already exists")
+	case errors.Is(err, repository.ErrDuplicateUsername):
+		writeError(w, http.StatusConflict, "This username is already taken")
+	case errors.Is(err, user.ErrUserNotFound):
+		writeError(w, http.StatusNotFound, "User not found")
+	case errors.Is(err, user.ErrInvalidExternalID):
+		writeError(w, http.StatusBadRequest, "external_id must be 1-255 characters")
+	case errors.Is(err, user.ErrInvalidRole), errors.Is(err, user.ErrInvalidPage), errors.Is(err, user.ErrInvalidCursor),
+		errors.Is(err, user.ErrInvalidSort), errors.Is(err, user.ErrInvalidStatus):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, repository.ErrDuplicateExternalID):
+		writeError(w, http.StatusConflict, "This external_id is already linked to another user")
+	case errors.Is(err, actiontoken.ErrInvalidActionToken):
+		writeError(w, http.StatusBadRequest, "Invalid or expired token")
+	case errors.Is(err, actiontoken.ErrInvalidPurpose), errors.Is(err, actiontoken.ErrInvalidTTL), errors.Is(err, actiontoken.ErrInvalidPayload):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, oauth.ErrClientNotFound):
+		writeError(w, http.StatusNotFound, "Client not found")
+	case errors.Is(err, oauth.ErrInvalidClientName), errors.Is(err, oauth.ErrInvalidScopeValue),
+		errors.Is(err, oauth.ErrPublicClientScope):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, oauth.ErrInvalidUserCode):
+		writeError(w, http.StatusBadRequest, "Invalid or expired code")
+	case errors.Is(err, share.ErrInvalidAudience), errors.Is(err, share.ErrInvalidResource),
+		errors.Is(err, share.ErrInvalidSubject), errors.Is(err, share.ErrInvalidTTL):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, setup.ErrSetupComplete):
+		writeError(w, http.StatusConflict, "Setup has already been completed")
+	case errors.Is(err, setup.ErrInvalidSetupToken):
+		writeError(w, http.StatusUnauthorized, "Invalid setup token")
+	case errors.Is(err, context.DeadlineExceeded):
+		log.Printf("Request timed out: %v", err)
+		writeError(w, http.StatusServiceUnavailable, "Request timed out; please try again")
+	case errors.Is(err, context.Canceled):
+		// The client went away; there is no one left to answer.
+	default:
+		log.Printf("Internal error: %v", err)
+		writeError(w, http.StatusInternalServerError, "Internal server error")
+	}
+}
@@ -0,0 +1,16 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/SarathLUN/go-auth-service/internal/jwtkeys"
+)
+
+// JWKS returns a handler for GET /.well-known/jwks.json, which publishes the
+// public keys downstream services use to verify access tokens.
+func JWKS(keys *jwtkeys.KeySet) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		writeJSON(w, http.StatusOK, keys.JWKS())
+	}
+}
@@ -0,0 +1,401 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/middleware"
+	"github.com/SarathLUN/go-auth-service/internal/service/auth"
+	"github.com/SarathLUN/go-auth-service/internal/service/oauth"
+)
+
+// OAuthController exposes the OAuth token, device authorization and
+// introspection endpoints and client registration.
+type OAuthController struct {
+	oauth *oauth.Service
+}
+
+// NewOAuthController creates an OAuthController.
+func NewOAuthController(oauthService *oauth.Service) *OAuthController {
+	return &OAuthController{oauth: oauthService}
+}
+
+// oauthErrorResponse is the error body of RFC 6749 section 5.2.
+type oauthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// oauthTokenResponse is the token body of RFC 6749 section 5.1.
+type oauthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// deviceCodeGrantType is the grant_type of RFC 8628 section 3.4.
+const deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+// Token handles POST /oauth/token for the client_credentials and device_code
+// grants. Clients authenticate with HTTP Basic (client_secret_basic) or with
+// client_id and client_secret form fields (client_secret_post).
+func (c *OAuthController) Token(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "body must be application/x-www-form-urlencoded")
+		return
+	}
+
+	grantType := r.PostForm.Get("grant_type")
+	switch grantType {
+	case "client_credentials", deviceCodeGrantType:
+	case "":
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "grant_type is required")
+		return
+	default:
+		writeOAuthError(w, http.StatusBadRequest, "unsupported_grant_type", "")
+		return
+	}
+
+	clientID, clientSecret, basic, ok := clientCredentials(w, r)
+	if !ok {
+		return
+	}
+	if grantType == deviceCodeGrantType {
+		c.deviceToken(w, r, clientID, clientSecret, basic)
+		return
+	}
+
+	token, err := c.oauth.ClientCredentials(r.Context(), clientID, clientSecret, r.PostForm.Get("scope"))
+	switch {
+	case errors.Is(err, oauth.ErrInvalidClient):
+		writeInvalidClient(w, basic)
+		return
+	case errors.Is(err, oauth.ErrInvalidScope):
+		writeOAuthError(w, http.StatusBadRequest, "invalid_scope", err.Error())
+		return
+	case err != nil:
+		log.Printf("Internal error: %v", err)
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, oauthTokenResponse{
+		AccessToken: token.AccessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(token.ExpiresIn.Seconds()),
+		Scope:       token.Scope,
+	})
+}
+
+func (c *OAuthController) deviceToken(w http.ResponseWriter, r *http.Request, clientID, clientSecret string, basic bool) {
+	deviceCode := r.PostForm.Get("device_code")
+	if deviceCode == "" {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "device_code is required")
+		return
+	}
+
+	tokens, err := c.oauth.DeviceToken(r.Context(), clientID, clientSecret, deviceCode)
+	switch {
+	case errors.Is(err, oauth.ErrInvalidClient):
+		writeInvalidClient(w, basic)
+		return
+	case errors.Is(err, oauth.ErrAuthorizationPending):
+		writeOAuthError(w, http.StatusBadRequest, "authorization_pending", "")
+		return
+	case errors.Is(err, oauth.ErrSlowDown):
+		writeOAuthError(w, http.StatusBadRequest, "slow_down", "")
+		return
+	case errors.Is(err, oauth.ErrAccessDenied):
+		writeOAuthError(w, http.StatusBadRequest, "access_denied", "")
+		return
+	case errors.Is(err, oauth.ErrExpiredToken):
+		writeOAuthError(w, http.StatusBadRequest, "expired_token", "")
+		return
+	case errors.Is(err, oauth.ErrInvalidGrant), errors.Is(err, auth.ErrUserNotActive):
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant", err.Error())
+		return
+	case err != nil:
+		log.Printf("Internal error: %v", err)
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, oauthTokenResponse{
+		AccessToken:  tokens.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(tokens.ExpiresIn.Seconds()),
+		RefreshToken: tokens.RefreshToken,
+	})
+}
+
+// deviceAuthorizationResponse is the body of RFC 8628 section 3.2.
+type deviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int64  `json:"expires_in"`
+	Interval                int64  `json:"interval"`
+}
+
+// DeviceAuthorization handles POST /oauth/device/code, the device
+// authorization endpoint of RFC 8628. Clients authenticate like at the token
+// endpoint; public clients send only client_id.
+func (c *OAuthController) DeviceAuthorization(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "body must be application/x-www-form-urlencoded")
+		return
+	}
+	clientID, clientSecret, basic, ok := clientCredentials(w, r)
+	if !ok {
+		return
+	}
+
+	da, err := c.oauth.StartDeviceAuthorization(r.Context(), clientID, clientSecret, r.PostForm.Get("scope"))
+	switch {
+	case errors.Is(err, oauth.ErrInvalidClient):
+		writeInvalidClient(w, basic)
+		return
+	case errors.Is(err, oauth.ErrInvalidScope):
+		writeOAuthError(w, http.StatusBadRequest, "invalid_scope", "device authorizations issue user sessions, which have no scope")
+		return
+	case err != nil:
+		log.Printf("Internal error: %v", err)
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, deviceAuthorizationResponse{
+		DeviceCode:              da.DeviceCode,
+		UserCode:                da.UserCode,
+		VerificationURI:         da.VerificationURI,
+		VerificationURIComplete: da.VerificationURIComplete,
+		ExpiresIn:               int64(da.ExpiresIn.Seconds()),
+		Interval:                int64(da.Interval.Seconds()),
+	})
+}
+
+// deviceRequestResponse matches the DeviceRequest schema in api/openapi.yaml.
+type deviceRequestResponse struct {
+	ClientID   string    `json:"client_id"`
+	ClientName string    `json:"client_name"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// LookupDevice handles GET /oauth/device?user_code=..., which the
+// verification page uses to show the user which client is asking. It must
+// be behind middleware.RequireAuth.
+func (c *OAuthController) LookupDevice(w http.ResponseWriter, r *http.Request) {
+	userCode := r.URL.Query().Get("user_code")
+	if userCode == "" {
+		writeError(w, http.StatusBadRequest, "user_code is required")
+		return
+	}
+
+	req, err := c.oauth.LookupDeviceAuthorization(r.Context(), userCode)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, deviceRequestResponse{
+		ClientID:   req.ClientID,
+		ClientName: req.ClientName,
+		ExpiresAt:  req.ExpiresAt,
+	})
+}
+
+type deviceDecisionRequest struct {
+	UserCode string `json:"user_code"`
+	Approve  *bool  `json:"approve"`
+}
+
+// DecideDevice handles POST /oauth/device, where the signed-in user approves
+// or denies a device. It must be behind middleware.RequireAuth.
+func (c *OAuthController) DecideDevice(w http.ResponseWriter, r *http.Request) {
+	var req deviceDecisionRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.UserCode == "" || req.Approve == nil {
+		writeError(w, http.StatusBadRequest, "user_code and approve are required")
+		return
+	}
+
+	userID := middleware.ClaimsFromContext(r.Context()).UserID
+	if err := c.oauth.DecideDeviceAuthorization(r.Context(), userID, req.UserCode, *req.Approve); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	if *req.Approve {
+		writeJSON(w, http.StatusOK, messageResponse{Message: "Device approved"})
+		return
+	}
+	writeJSON(w, http.StatusOK, messageResponse{Message: "Device denied"})
+}
+
+// introspectionResponse is the body of RFC 7662 section 2.2.
+type introspectionResponse struct {
+	Active    bool   `json:"active"`
+	TokenType string `json:"token_type,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+}
+
+// Introspect handles POST /oauth/introspect (RFC 7662). Callers
+// authenticate like at the token endpoint.
+func (c *OAuthController) Introspect(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "body must be application/x-www-form-urlencoded")
+		return
+	}
+	token := r.PostForm.Get("token")
+	if token == "" {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "token is required")
+		return
+	}
+	clientID, clientSecret, basic, ok := clientCredentials(w, r)
+	if !ok {
+		return
+	}
+
+	info, err := c.oauth.Introspect(r.Context(), clientID, clientSecret, token)
+	switch {
+	case errors.Is(err, oauth.ErrInvalidClient):
+		writeInvalidClient(w, basic)
+		return
+	case err != nil:
+		log.Printf("Internal error: %v", err)
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	if !info.Active {
+		writeJSON(w, http.StatusOK, introspectionResponse{})
+		return
+	}
+	writeJSON(w, http.StatusOK, introspectionResponse{
+		Active:    true,
+		TokenType: info.TokenType,
+		Scope:     info.Scope,
+		ClientID:  info.ClientID,
+		Subject:   info.Subject,
+		IssuedAt:  info.IssuedAt.Unix(),
+		ExpiresAt: info.ExpiresAt.Unix(),
+	})
+}
+
+// clientCredentials reads HTTP Basic (client_secret_basic) or client_id and
+// client_secret form fields (client_secret_post) from a parsed form request.
+// It writes an error and returns ok false if both are used.
+func clientCredentials(w http.ResponseWriter, r *http.Request) (clientID, clientSecret string, basic, ok bool) {
+	clientID, clientSecret, basic = r.BasicAuth()
+	if !basic {
+		return r.PostForm.Get("client_id"), r.PostForm.Get("client_secret"), false, true
+	}
+	if r.PostForm.Has("client_secret") {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "use only one client authentication method")
+		return "", "", true, false
+	}
+	// RFC 6749 section 2.3.1 form-encodes the credentials before Basic encoding.
+	clientID, _ = url.QueryUnescape(clientID)
+	clientSecret, _ = url.QueryUnescape(clientSecret)
+	return clientID, clientSecret, true, true
+}
+
+func writeInvalidClient(w http.ResponseWriter, basic bool) {
+	if basic {
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+	writeOAuthError(w, http.StatusUnauthorized, "invalid_client", "")
+}
+
+func writeOAuthError(w http.ResponseWriter, status int, code, description string) {
+	writeJSON(w, status, oauthErrorResponse{Error: code, ErrorDescription: description})
+}
+
+// Values of token_endpoint_auth_method (RFC 7591 section 2).
+const (
+	authMethodSecretBasic = "client_secret_basic"
+	authMethodNone        = "none"
+)
+
+type registerClientRequest struct {
+	Name                    string   `json:"name"`
+	Scopes                  []string `json:"scopes"`
+	TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method"`
+}
+
+// registeredClientResponse matches the RegisteredClient schema in api/openapi.yaml.
+type registeredClientResponse struct {
+	ClientID                string `json:"client_id"`
+	ClientSecret            string `json:"client_secret,omitempty"`
+	Name                    string `json:"name"`
+	Scope                   string `json:"scope"`
+	TokenEndpointAuthMethod string `json:"token_endpoint_auth_method"`
+}
+
+// RegisterClient handles POST /admin/oauth/clients.
+func (c *OAuthController) RegisterClient(w http.ResponseWriter, r *http.Request) {
+	var req registerClientRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	var public bool
+	switch req.TokenEndpointAuthMethod {
+	case "", authMethodSecretBasic:
+	case authMethodNone:
+		public = true
+	default:
+		writeError(w, http.StatusBadRequest, "token_endpoint_auth_method must be client_secret_basic or none")
+		return
+	}
+
+	client, secret, err := c.oauth.RegisterClient(r.Context(), req.Name, req.Scopes, public)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	authMethod := authMethodSecretBasic
+	if client.Public {
+		authMethod = authMethodNone
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusCreated, registeredClientResponse{
+		ClientID:                client.ID,
+		ClientSecret:            secret,
+		Name:                    client.Name,
+		Scope:                   client.Scope,
+		TokenEndpointAuthMethod: authMethod,
+	})
+}
+
+// DeleteClient handles DELETE /admin/oauth/clients/{id}.
+func (c *OAuthController) DeleteClient(w http.ResponseWriter, r *http.Request) {
+	if err := c.oauth.DeleteClient(r.Context(), r.PathValue("id")); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, messageResponse{Message: "Client deleted"})
+}
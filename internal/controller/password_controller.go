@@ -0,0 +1,38 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/SarathLUN/go-auth-service/internal/password"
+)
+
+// maxRequestBodyBytes bounds JSON request bodies read by controllers.
+const maxRequestBodyBytes = 64 << 10
+
+type passwordStrengthRequest struct {
+	Password   string   `json:"password"`
+	UserInputs []string `json:"user_inputs"` // e.g. email and username, penalised if reused
+}
+
+// PasswordStrength handles POST /v1/password/strength so signup forms can
+// show the score that PASSWORD_MIN_SCORE is checked against. Passwords longer than password.MaxEstimateLength
+// are rejected rather than scored on a prefix.
+func PasswordStrength(w http.ResponseWriter, r *http.Request) {
+	var req passwordStrengthRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Password == "" {
+		writeError(w, http.StatusBadRequest, "password is required")
+		return
+	}
+	if utf8.RuneCountInString(req.Password) > password.MaxEstimateLength {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("password must be at most %d characters", password.MaxEstimateLength))
+		return
+	}
+	writeJSON(w, http.StatusOK, password.Estimate(req.Password, req.UserInputs...))
+}
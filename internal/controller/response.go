@@ -0,0 +1,31 @@
+package controller
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// errorResponse matches the ErrorResponse schema in api/openapi.yaml.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// messageResponse matches the SuccessMessage schema in api/openapi.yaml.
+type messageResponse struct {
+	Message string `json:"message"`
+}
+
+// writeJSON encodes v as the JSON response body with the given status.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
+
+// writeError writes an ErrorResponse with the given status.
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, errorResponse{Error: msg})
+}
@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/SarathLUN/go-auth-service/internal/model"
+	"github.com/SarathLUN/go-auth-service/internal/service/auth"
+	"github.com/SarathLUN/go-auth-service/internal/service/setup"
+)
+
+// SetupController exposes the first-run setup endpoints.
+type SetupController struct {
+	setup *setup.Service
+}
+
+// NewSetupController creates a SetupController.
+func NewSetupController(setupService *setup.Service) *SetupController {
+	return &SetupController{setup: setupService}
+}
+
+type setupStatusResponse struct {
+	SetupRequired bool `json:"setup_required"`
+}
+
+// Status handles GET /setup.
+func (c *SetupController) Status(w http.ResponseWriter, r *http.Request) {
+	required, err := c.setup.Required(r.Context())
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, setupStatusResponse{SetupRequired: required})
+}
+
+type setupRequest struct {
+	SetupToken string `json:"setup_token"`
+	Email      string `json:"email"`
+	Username   string `json:"username"`
+	Password   string `json:"password"`
+}
+
+type setupResponse struct {
+	Message string      `json:"message"`
+	User    *model.User `json:"user"`
+}
+
+// Complete handles POST /setup, which creates the first admin.
+func (c *SetupController) Complete(w http.ResponseWriter, r *http.Request) {
+	var req setupRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.SetupToken == "" {
+		writeError(w, http.StatusBadRequest, "setup_token is required")
+		return
+	}
+
+	admin, err := c.setup.Complete(r.Context(), req.SetupToken, auth.RegisterInput{
+		Email:    req.Email,
+		Username: req.Username,
+		Password: req.Password,
+	})
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, setupResponse{Message: "Setup complete. Log in as the new admin.", User: admin})
+}
@@ -0,0 +1,54 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/service/share"
+)
+
+// ShareController lets trusted services mint share tokens for read-only
+// links to their resources.
+type ShareController struct {
+	shares *share.Service
+}
+
+// NewShareController creates a ShareController.
+func NewShareController(shares *share.Service) *ShareController {
+	return &ShareController{shares: shares}
+}
+
+type issueShareTokenRequest struct {
+	Audience   string `json:"audience"`
+	Resource   string `json:"resource"`
+	Subject    string `json:"subject"`
+	TTLSeconds int64  `json:"ttl_seconds"`
+}
+
+type issueShareTokenResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Issue handles POST /admin/share-tokens.
+func (c *ShareController) Issue(w http.ResponseWriter, r *http.Request) {
+	var req issueShareTokenRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	token, expiresAt, err := c.shares.Issue(r.Context(), share.IssueInput{
+		Audience: req.Audience,
+		Resource: req.Resource,
+		Subject:  req.Subject,
+		TTL:      time.Duration(req.TTLSeconds) * time.Second,
+	})
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusCreated, issueShareTokenResponse{Token: token, ExpiresAt: expiresAt})
+}
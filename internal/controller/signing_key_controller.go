@@ -0,0 +1,35 @@
+package controller
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/service/signingkey"
+)
+
+// SigningKeyController exposes JWT signing key rotation.
+type SigningKeyController struct {
+	keys *signingkey.Service
+}
+
+// NewSigningKeyController creates a SigningKeyController.
+func NewSigningKeyController(keyService *signingkey.Service) *SigningKeyController {
+	return &SigningKeyController{keys: keyService}
+}
+
+type rotateResponse struct {
+	KeyID      string    `json:"kid"`
+	ActiveFrom time.Time `json:"active_from"`
+}
+
+// Rotate handles POST /admin/keys/rotate.
+func (c *SigningKeyController) Rotate(w http.ResponseWriter, r *http.Request) {
+	kid, activeFrom, err := c.keys.Rotate(r.Context())
+	if err != nil {
+		log.Printf("Error rotating signing keys: %v", err)
+		writeError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	writeJSON(w, http.StatusOK, rotateResponse{KeyID: kid, ActiveFrom: activeFrom})
+}
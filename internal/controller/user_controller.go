@@ -0,0 +1,186 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/model"
+	"github.com/SarathLUN/go-auth-service/internal/service/user"
+)
+
+// UserController exposes user lookups and account management for operators
+// and internal callers.
+type UserController struct {
+	users *user.Service
+}
+
+// NewUserController creates a UserController.
+func NewUserController(userService *user.Service) *UserController {
+	return &UserController{users: userService}
+}
+
+// Get handles GET /admin/users/{id}.
+func (c *UserController) Get(w http.ResponseWriter, r *http.Request) {
+	u, err := c.users.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, u)
+}
+
+// GetByExternalID handles GET /admin/users/by-external-id/{externalID}.
+func (c *UserController) GetByExternalID(w http.ResponseWriter, r *http.Request) {
+	u, err := c.users.GetByExternalID(r.Context(), r.PathValue("externalID"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, u)
+}
+
+type externalIDRequest struct {
+	ExternalID *string `json:"external_id"`
+}
+
+// SetExternalID handles PUT /admin/users/{id}/external-id. A null
+// external_id unlinks the user.
+func (c *UserController) SetExternalID(w http.ResponseWriter, r *http.Request) {
+	var req externalIDRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	u, err := c.users.SetExternalID(r.Context(), r.PathValue("id"), req.ExternalID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, u)
+}
+
+// userListResponse matches the UserList schema in api/openapi.yaml.
+type userListResponse struct {
+	Users      []*model.User `json:"users"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+// List handles GET /admin/users. The query parameters limit, cursor,
+// status, email, created_after, created_before and sort select the page.
+func (c *UserController) List(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	in := user.ListInput{
+		Cursor: q.Get("cursor"),
+		Status: q.Get("status"),
+		Email:  q.Get("email"),
+		Sort:   q.Get("sort"),
+	}
+	var err error
+	if v := q.Get("limit"); v != "" {
+		if in.Limit, err = strconv.Atoi(v); err != nil {
+			writeError(w, http.StatusBadRequest, "limit must be an integer")
+			return
+		}
+	}
+	for _, p := range []struct {
+		name string
+		t    *time.Time
+	}{{"created_after", &in.CreatedFrom}, {"created_before", &in.CreatedBefore}} {
+		if v := q.Get(p.name); v != "" {
+			if *p.t, err = time.Parse(time.RFC3339, v); err != nil {
+				writeError(w, http.StatusBadRequest, p.name+" must be an RFC 3339 timestamp")
+				return
+			}
+		}
+	}
+
+	page, err := c.users.List(r.Context(), in)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, userListResponse{Users: page.Users, NextCursor: page.NextCursor})
+}
+
+type createUserRequest struct {
+	Email    string `json:"email"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+	Locale   string `json:"locale"`
+	Timezone string `json:"timezone"`
+}
+
+// Create handles POST /admin/users.
+func (c *UserController) Create(w http.ResponseWriter, r *http.Request) {
+	var req createUserRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	u, err := c.users.Create(r.Context(), user.CreateInput{
+		Email:    req.Email,
+		Username: req.Username,
+		Password: req.Password,
+		Role:     req.Role,
+		Locale:   req.Locale,
+		Timezone: req.Timezone,
+	})
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, u)
+}
+
+// Disable handles POST /admin/users/{id}/disable.
+func (c *UserController) Disable(w http.ResponseWriter, r *http.Request) {
+	u, err := c.users.Disable(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, u)
+}
+
+// Enable handles POST /admin/users/{id}/enable.
+func (c *UserController) Enable(w http.ResponseWriter, r *http.Request) {
+	u, err := c.users.Enable(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, u)
+}
+
+// ForcePasswordReset handles POST /admin/users/{id}/password-reset.
+func (c *UserController) ForcePasswordReset(w http.ResponseWriter, r *http.Request) {
+	if err := c.users.ForcePasswordReset(r.Context(), r.PathValue("id")); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, messageResponse{Message: "Password reset; the user has been emailed a link to choose a new one"})
+}
+
+// Unlock handles POST /admin/users/{id}/unlock.
+func (c *UserController) Unlock(w http.ResponseWriter, r *http.Request) {
+	u, err := c.users.Unlock(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, u)
+}
+
+// Delete handles DELETE /admin/users/{id}.
+func (c *UserController) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := c.users.Delete(r.Context(), r.PathValue("id")); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, messageResponse{Message: "User deleted"})
+}
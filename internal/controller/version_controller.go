@@ -0,0 +1,12 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/SarathLUN/go-auth-service/internal/version"
+)
+
+// Version handles GET /version and reports the running build.
+func Version(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, version.Get())
+}
@@ -0,0 +1,54 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib" // registers the "pgx" database/sql driver
+
+	"github.com/SarathLUN/go-auth-service/internal/config"
+)
+
+// Open opens a connection pool to the configured Postgres database and
+// verifies it is reachable.
+func Open(ctx context.Context, cfg *config.Config) (*sql.DB, error) {
+	db, err := sql.Open("pgx", cfg.GetDBConnectionString())
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("ping database: %w", err)
+	}
+	return db, nil
+}
+
+// Retry backoff bounds used by OpenWithRetry.
+const (
+	initialBackoff = 500 * time.Millisecond
+	maxBackoff     = 5 * time.Second
+)
+
+// OpenWithRetry keeps trying Open with exponential backoff until it succeeds
+// or ctx is done, so the server can start before the database is ready
+// without an external wait-for-it wrapper.
+func OpenWithRetry(ctx context.Context, cfg *config.Config) (*sql.DB, error) {
+	backoff := initialBackoff
+	for attempt := 1; ; attempt++ {
+		db, err := Open(ctx, cfg)
+		if err == nil {
+			return db, nil
+		}
+		log.Printf("Database not ready (attempt %d): %v; retrying in %s", attempt, err, backoff)
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("gave up waiting for database: %w", err)
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
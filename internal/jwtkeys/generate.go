@@ -0,0 +1,85 @@
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/SarathLUN/go-auth-service/internal/secretbox"
+)
+
+// rsaGenerateBits is the modulus size of generated RS256 keys.
+const rsaGenerateBits = 3072
+
+// GenerateKey creates a new private key for alg.
+func GenerateKey(alg string) (crypto.Signer, error) {
+	switch alg {
+	case RS256:
+		return rsa.GenerateKey(rand.Reader, rsaGenerateBits)
+	case ES256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	default:
+		return nil, fmt.Errorf("cannot generate keys for %q", alg)
+	}
+}
+
+// KeyID returns the kid a KeySet assigns to signer.
+func KeyID(signer crypto.Signer) (string, error) {
+	jwk, err := publicJWK(signer.Public())
+	if err != nil {
+		return "", err
+	}
+	return thumbprint(jwk)
+}
+
+// MarshalPrivateKey encodes signer as a PKCS#8 PEM block.
+func MarshalPrivateKey(signer crypto.Signer) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(signer)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// Sealer encrypts private keys at rest under the key from
+// JWT_KEY_ENCRYPTION_KEY. The kid is bound as additional data, so a sealed
+// key cannot be swapped onto another row.
+type Sealer struct {
+	box *secretbox.Box
+}
+
+// NewSealer parses JWT_KEY_ENCRYPTION_KEY.
+func NewSealer(encodedKey string) (*Sealer, error) {
+	if encodedKey == "" {
+		return nil, errors.New("JWT_KEY_ENCRYPTION_KEY is required for the database key store")
+	}
+	box, err := secretbox.New(encodedKey)
+	if err != nil {
+		return nil, fmt.Errorf("JWT_KEY_ENCRYPTION_KEY: %w", err)
+	}
+	return &Sealer{box: box}, nil
+}
+
+// Seal encrypts the PEM of signer.
+func (s *Sealer) Seal(kid string, signer crypto.Signer) ([]byte, error) {
+	plain, err := MarshalPrivateKey(signer)
+	if err != nil {
+		return nil, err
+	}
+	return s.box.Seal(plain, []byte(kid))
+}
+
+// Open decrypts a key produced by Seal.
+func (s *Sealer) Open(kid string, sealed []byte) (crypto.Signer, error) {
+	plain, err := s.box.Open(sealed, []byte(kid))
+	if err != nil {
+		return nil, fmt.Errorf("decrypt signing key %s: wrong JWT_KEY_ENCRYPTION_KEY or corrupted row", kid)
+	}
+	return ParsePrivateKey(plain)
+}
@@ -0,0 +1,308 @@
+// Package jwtkeys holds the key material used to sign and verify access
+// tokens. HS256 uses the shared JWT_SECRET; RS256 and ES256 use private keys
+// whose public halves are published as a JWK set so other services can verify
+// tokens without knowing any secret.
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"github.com/SarathLUN/go-auth-service/internal/config"
+)
+
+// Supported signing algorithms.
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	ES256 = "ES256"
+)
+
+// Key stores for JWT_KEY_STORE.
+const (
+	// StoreConfig reads a single key from JWT_SECRET or JWT_PRIVATE_KEY(_FILE).
+	StoreConfig = "config"
+	// StoreDatabase keeps rotatable keys in the signing_keys table.
+	StoreDatabase = "database"
+)
+
+// minRSABits is the smallest RSA modulus accepted for RS256.
+const minRSABits = 2048
+
+// ErrNoSigningKey is returned by Sign before any key has been loaded.
+var ErrNoSigningKey = errors.New("no signing key loaded")
+
+// key is a single signing key. For HS256 kid is empty and sign and verify
+// are the same secret.
+type key struct {
+	kid    string
+	sign   interface{}
+	verify interface{}
+	public *JWK // nil for HS256
+}
+
+// KeySet signs tokens with its current key and verifies them with any of
+// its keys, which lets recently rotated keys keep verifying tokens they
+// signed. It is safe for concurrent use; SetKeys swaps the keys atomically.
+type KeySet struct {
+	method jwt.SigningMethod
+
+	mu      sync.RWMutex
+	current *key
+	keys    map[string]*key // by kid
+	order   []*key          // current first, for JWKS
+}
+
+// FromConfig builds the KeySet selected by JWT_SIGNING_ALG and JWT_KEY_STORE.
+// With the config store, RS256 and ES256 read the PEM private key from
+// JWT_PRIVATE_KEY_FILE or JWT_PRIVATE_KEY. With the database store the set
+// starts empty and is filled by the signing key service.
+func FromConfig(cfg *config.Config) (*KeySet, error) {
+	switch cfg.JWTKeyStore {
+	case StoreConfig, "":
+	case StoreDatabase:
+		if cfg.JWTSigningAlg != RS256 && cfg.JWTSigningAlg != ES256 {
+			return nil, fmt.Errorf("JWT_KEY_STORE=database requires JWT_SIGNING_ALG RS256 or ES256, not %q", cfg.JWTSigningAlg)
+		}
+		return New(cfg.JWTSigningAlg)
+	default:
+		return nil, fmt.Errorf("unsupported JWT_KEY_STORE %q (want config or database)", cfg.JWTKeyStore)
+	}
+
+	switch cfg.JWTSigningAlg {
+	case HS256, "":
+		if cfg.JWTSecret == "" {
+			return nil, errors.New("JWT_SECRET is required for HS256")
+		}
+		return NewHMAC([]byte(cfg.JWTSecret)), nil
+	case RS256, ES256:
+		pemBytes, err := privateKeyPEM(cfg)
+		if err != nil {
+			return nil, err
+		}
+		key, err := ParsePrivateKey(pemBytes)
+		if err != nil {
+			return nil, err
+		}
+		return NewAsymmetric(cfg.JWTSigningAlg, key)
+	default:
+		return nil, fmt.Errorf("unsupported JWT_SIGNING_ALG %q (want HS256, RS256 or ES256)", cfg.JWTSigningAlg)
+	}
+}
+
+func privateKeyPEM(cfg *config.Config) ([]byte, error) {
+	switch {
+	case cfg.JWTPrivateKeyFile != "" && cfg.JWTPrivateKey != "":
+		return nil, errors.New("set only one of JWT_PRIVATE_KEY_FILE and JWT_PRIVATE_KEY")
+	case cfg.JWTPrivateKeyFile != "":
+		b, err := os.ReadFile(cfg.JWTPrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read JWT_PRIVATE_KEY_FILE: %w", err)
+		}
+		return b, nil
+	case cfg.JWTPrivateKey != "":
+		return []byte(cfg.JWTPrivateKey), nil
+	default:
+		return nil, fmt.Errorf("%s requires JWT_PRIVATE_KEY_FILE or JWT_PRIVATE_KEY", cfg.JWTSigningAlg)
+	}
+}
+
+// New creates an empty RS256 or ES256 KeySet to be filled with SetKeys.
+func New(alg string) (*KeySet, error) {
+	var method jwt.SigningMethod
+	switch alg {
+	case RS256:
+		method = jwt.SigningMethodRS256
+	case ES256:
+		method = jwt.SigningMethodES256
+	default:
+		return nil, fmt.Errorf("unsupported asymmetric algorithm %q", alg)
+	}
+	return &KeySet{method: method, keys: map[string]*key{}}, nil
+}
+
+// NewHMAC creates an HS256 KeySet. Tokens carry no kid and the secret is never
+// published.
+func NewHMAC(secret []byte) *KeySet {
+	k := &key{sign: secret, verify: secret}
+	return &KeySet{method: jwt.SigningMethodHS256, current: k, keys: map[string]*key{"": k}, order: []*key{k}}
+}
+
+// NewAsymmetric creates an RS256 or ES256 KeySet holding a single key.
+func NewAsymmetric(alg string, signer crypto.Signer) (*KeySet, error) {
+	ks, err := New(alg)
+	if err != nil {
+		return nil, err
+	}
+	if err := ks.SetKeys(signer, nil); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// SetKeys replaces the keys of an RS256 or ES256 set: current signs new
+// tokens, and current plus previous verify them.
+func (k *KeySet) SetKeys(current crypto.Signer, previous []crypto.Signer) error {
+	if k.method == jwt.SigningMethodHS256 {
+		return errors.New("HS256 key sets hold a single secret")
+	}
+	cur, err := k.newKey(current)
+	if err != nil {
+		return err
+	}
+	keys := map[string]*key{cur.kid: cur}
+	order := []*key{cur}
+	for _, s := range previous {
+		p, err := k.newKey(s)
+		if err != nil {
+			return err
+		}
+		if _, dup := keys[p.kid]; !dup {
+			keys[p.kid] = p
+			order = append(order, p)
+		}
+	}
+
+	k.mu.Lock()
+	k.current, k.keys, k.order = cur, keys, order
+	k.mu.Unlock()
+	return nil
+}
+
+// newKey validates signer against the set's algorithm. The kid is the
+// RFC 7638 thumbprint of the public key, so it is stable across restarts.
+func (k *KeySet) newKey(signer crypto.Signer) (*key, error) {
+	if err := checkKey(k.method.Alg(), signer); err != nil {
+		return nil, err
+	}
+	jwk, err := publicJWK(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	if jwk.KeyID, err = thumbprint(jwk); err != nil {
+		return nil, err
+	}
+	jwk.Use = "sig"
+	jwk.Algorithm = k.method.Alg()
+	return &key{kid: jwk.KeyID, sign: signer, verify: signer.Public(), public: &jwk}, nil
+}
+
+func checkKey(alg string, signer crypto.Signer) error {
+	switch s := signer.(type) {
+	case *rsa.PrivateKey:
+		if alg != RS256 {
+			return fmt.Errorf("an RSA key cannot be used for %s", alg)
+		}
+		if s.N.BitLen() < minRSABits {
+			return fmt.Errorf("RSA key is %d bits, need at least %d", s.N.BitLen(), minRSABits)
+		}
+	case *ecdsa.PrivateKey:
+		if alg != ES256 {
+			return fmt.Errorf("an EC key cannot be used for %s", alg)
+		}
+		if s.Curve != elliptic.P256() {
+			return errors.New("ES256 requires a P-256 key")
+		}
+	default:
+		return fmt.Errorf("unsupported private key type %T", signer)
+	}
+	return nil
+}
+
+// ParsePrivateKey decodes a PEM encoded PKCS#8, PKCS#1 (RSA) or SEC 1 (EC)
+// private key.
+func ParsePrivateKey(pemBytes []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errors.New("no PEM block found in private key")
+	}
+
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse private key: %w", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return signer, nil
+}
+
+// Algorithm returns the JWS algorithm tokens are signed with.
+func (k *KeySet) Algorithm() string {
+	return k.method.Alg()
+}
+
+// CurrentKeyID returns the kid new tokens are signed with; it is empty for
+// HS256 or before any key has been loaded.
+func (k *KeySet) CurrentKeyID() string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if k.current == nil {
+		return ""
+	}
+	return k.current.kid
+}
+
+// Sign serialises claims into a JWT of type typ signed with the current key.
+func (k *KeySet) Sign(typ string, claims jwt.Claims) (string, error) {
+	k.mu.RLock()
+	cur := k.current
+	k.mu.RUnlock()
+	if cur == nil {
+		return "", ErrNoSigningKey
+	}
+
+	token := jwt.NewWithClaims(k.method, claims)
+	token.Header["typ"] = typ
+	if cur.kid != "" {
+		token.Header["kid"] = cur.kid
+	}
+	return token.SignedString(cur.sign)
+}
+
+// Parse verifies the signature of token against the key named by its kid and
+// decodes its claims. Only the configured algorithm is accepted, which rules
+// out algorithm confusion between HMAC secrets and public keys, and only
+// tokens whose typ header is typ, so that kinds of tokens signed with the
+// same keys cannot stand in for each other. Time based claims are left to
+// the caller so it can use its own clock.
+func (k *KeySet) Parse(token, typ string, claims jwt.Claims) error {
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{k.method.Alg()}), jwt.WithoutClaimsValidation())
+	_, err := parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		if got, _ := t.Header["typ"].(string); !strings.EqualFold(got, typ) {
+			return nil, fmt.Errorf("unexpected token type %q", got)
+		}
+		kid, _ := t.Header["kid"].(string)
+		k.mu.RLock()
+		v, ok := k.keys[kid]
+		k.mu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		return v.verify, nil
+	})
+	return err
+}
@@ -0,0 +1,83 @@
+package loginevents
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/service/auth"
+)
+
+// exportBuffer is how many events may wait to be written before new ones
+// are dropped.
+const exportBuffer = 1024
+
+// exportRecord is the JSON form of an exported event.
+type exportRecord struct {
+	Time      time.Time `json:"time"`
+	Method    string    `json:"method"`
+	Outcome   string    `json:"outcome"`
+	UserID    string    `json:"user_id,omitempty"`
+	UserAgent string    `json:"user_agent"`
+	ClientIP  string    `json:"client_ip,omitempty"`
+	RiskScore int       `json:"risk_score"`
+	LatencyMS float64   `json:"latency_ms"`
+}
+
+// Export streams login events as JSON lines. Events are written in the
+// background so that a slow destination never holds up logins; when it
+// falls too far behind, events are dropped and counted in the
+// login_events_dropped metric.
+type Export struct {
+	events chan exportRecord
+}
+
+// OpenExport appends events to the file at path, creating it if needed.
+func OpenExport(path string) (*Export, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open LOGIN_EVENTS_EXPORT_FILE: %w", err)
+	}
+	return NewExport(f), nil
+}
+
+// NewExport streams events to w.
+func NewExport(w io.Writer) *Export {
+	e := &Export{events: make(chan exportRecord, exportBuffer)}
+	go e.run(w)
+	return e
+}
+
+// ObserveLogin implements auth.LoginObserver.
+func (e *Export) ObserveLogin(_ context.Context, ev auth.LoginEvent) {
+	r := exportRecord{
+		Time:      ev.Time.UTC(),
+		Method:    ev.Method,
+		Outcome:   ev.Outcome,
+		UserID:    ev.UserID,
+		UserAgent: ev.UserAgent,
+		RiskScore: ev.RiskScore,
+		LatencyMS: float64(ev.Latency) / float64(time.Millisecond),
+	}
+	if ev.ClientIP.IsValid() {
+		r.ClientIP = ev.ClientIP.String()
+	}
+	select {
+	case e.events <- r:
+	default:
+		dropped.Add(1)
+	}
+}
+
+func (e *Export) run(w io.Writer) {
+	enc := json.NewEncoder(w)
+	for r := range e.events {
+		if err := enc.Encode(r); err != nil {
+			log.Printf("Failed to export login event: %v", err)
+		}
+	}
+}
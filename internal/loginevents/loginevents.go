@@ -0,0 +1,57 @@
+// Package loginevents consumes the login events of the auth service. It
+// logs them for auditing, counts them for metrics and can export them for
+// offline analysis such as fraud models.
+package loginevents
+
+import (
+	"context"
+	"expvar"
+	"log"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/config"
+	"github.com/SarathLUN/go-auth-service/internal/service/auth"
+)
+
+// NewFromConfig builds the observers selected by the LOGIN_EVENTS_*
+// settings. Metrics are always collected.
+func NewFromConfig(cfg *config.Config) (auth.LoginObserver, error) {
+	observers := auth.LoginObservers{Metrics{}}
+	if cfg.LoginEventsLog {
+		observers = append(observers, Log{})
+	}
+	if cfg.LoginEventsExportFile != "" {
+		e, err := OpenExport(cfg.LoginEventsExportFile)
+		if err != nil {
+			return nil, err
+		}
+		observers = append(observers, e)
+	}
+	return observers, nil
+}
+
+// Log writes each login event to the server log as an audit record.
+type Log struct{}
+
+// ObserveLogin implements auth.LoginObserver.
+func (Log) ObserveLogin(_ context.Context, e auth.LoginEvent) {
+	log.Printf("login method=%s outcome=%s user_id=%s client_ip=%s user_agent=%q risk_score=%d latency=%s",
+		e.Method, e.Outcome, e.UserID, e.ClientIP, e.UserAgent, e.RiskScore, e.Latency.Round(time.Millisecond))
+}
+
+var (
+	attempts = expvar.NewMap("login_attempts")        // by "method.outcome"
+	latency  = expvar.NewMap("login_latency_seconds") // total by method
+	dropped  = expvar.NewInt("login_events_dropped")  // by Export
+)
+
+// Metrics counts login attempts by method and outcome and sums their
+// latency by method. The counters are published with package expvar and
+// served at GET /admin/metrics.
+type Metrics struct{}
+
+// ObserveLogin implements auth.LoginObserver.
+func (Metrics) ObserveLogin(_ context.Context, e auth.LoginEvent) {
+	attempts.Add(e.Method+"."+e.Outcome, 1)
+	latency.AddFloat(e.Method, e.Latency.Seconds())
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/SarathLUN/go-auth-service/internal/model"
+	"github.com/SarathLUN/go-auth-service/internal/service/user"
+)
+
+// UserGetter looks users up by ID.
+type UserGetter interface {
+	Get(ctx context.Context, id string) (*model.User, error)
+}
+
+// RequireAdmin returns middleware that only lets through requests from active
+// users with the admin role. It must be behind RequireAuth. The role is read
+// from the database on every request, so demoting or disabling an admin takes
+// effect immediately rather than when their access token expires.
+func RequireAdmin(users UserGetter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			u, err := users.Get(r.Context(), ClaimsFromContext(r.Context()).UserID)
+			if err != nil && !errors.Is(err, user.ErrUserNotFound) {
+				log.Printf("Error loading user for admin check: %v", err)
+				writeError(w, http.StatusInternalServerError, "Internal server error")
+				return
+			}
+			if u == nil || !u.IsActive || u.Role != model.RoleAdmin {
+				writeError(w, http.StatusForbidden, "admin role required")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// SignatureOr returns middleware that checks requests carrying a
+// SignatureKeyIDHeader with signed, and all other requests with fallback.
+// It lets internal callers and signed-in operators share a route.
+func SignatureOr(signed, fallback func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		signedNext, fallbackNext := signed(next), fallback(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get(SignatureKeyIDHeader) != "" {
+				signedNext.ServeHTTP(w, r)
+				return
+			}
+			fallbackNext.ServeHTTP(w, r)
+		})
+	}
+}
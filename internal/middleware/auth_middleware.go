@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/SarathLUN/go-auth-service/internal/service/auth"
+)
+
+type claimsKey struct{}
+
+// Authenticator validates bearer access tokens.
+type Authenticator interface {
+	Authenticate(ctx context.Context, accessToken string) (*auth.AccessClaims, error)
+}
+
+// RequireAuth returns middleware that rejects requests without a valid,
+// unrevoked "Authorization: Bearer" access token. The token's claims are
+// available to handlers through ClaimsFromContext.
+func RequireAuth(a Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := BearerToken(r)
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `Bearer`)
+				unauthorized(w, "missing bearer token")
+				return
+			}
+
+			claims, err := a.Authenticate(r.Context(), token)
+			if errors.Is(err, auth.ErrInvalidAccessToken) {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				unauthorized(w, "invalid or expired access token")
+				return
+			}
+			if err != nil {
+				log.Printf("Error authenticating request: %v", err)
+				writeError(w, http.StatusInternalServerError, "Internal server error")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimsKey{}, claims)))
+		})
+	}
+}
+
+// RecordClient passes the request's User-Agent and remote address to the
+// auth service, which labels the sessions it starts or refreshes with the
+// former and reports both in login events.
+func RecordClient(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := auth.WithUserAgent(r.Context(), r.UserAgent())
+		if ip, ok := remoteIP(r); ok {
+			ctx = auth.WithClientIP(ctx, ip)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// remoteIP returns the address of the peer that sent the request.
+func remoteIP(r *http.Request) (netip.Addr, bool) {
+	addr, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Addr().Unmap(), true
+}
+
+// BearerToken extracts the token from an "Authorization: Bearer" header.
+func BearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// ClaimsFromContext returns the claims stored by RequireAuth, or nil if the
+// request was not authenticated.
+func ClaimsFromContext(ctx context.Context) *auth.AccessClaims {
+	claims, _ := ctx.Value(claimsKey{}).(*auth.AccessClaims)
+	return claims
+}
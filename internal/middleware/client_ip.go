@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ParseTrustedProxies parses the comma-separated CIDRs or bare addresses of
+// TRUSTED_PROXIES.
+func ParseTrustedProxies(s string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, e := range strings.Split(s, ",") {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		if !strings.Contains(e, "/") {
+			addr, err := netip.ParseAddr(e)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", e, err)
+			}
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		p, err := netip.ParsePrefix(e)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", e, err)
+		}
+		prefixes = append(prefixes, p.Masked())
+	}
+	return prefixes, nil
+}
+
+// ForwardedFor returns middleware that replaces the request's RemoteAddr
+// with the client address from X-Forwarded-For when the request comes from
+// one of the trusted proxies. The header is read from the right, skipping
+// trusted hops, so a client cannot pick its address by sending the header
+// itself. Requests from other peers keep their RemoteAddr, and so does
+// every request when trusted is empty.
+func ForwardedFor(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if len(trusted) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ip, ok := forwardedClient(r, trusted); ok {
+				r = r.Clone(r.Context())
+				r.RemoteAddr = netip.AddrPortFrom(ip, 0).String()
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// forwardedClient returns the first address in X-Forwarded-For, from the
+// right, that is not a trusted proxy. If all of them are, it returns the
+// leftmost one.
+func forwardedClient(r *http.Request, trusted []netip.Prefix) (netip.Addr, bool) {
+	peer, ok := remoteIP(r)
+	if !ok || !isTrusted(peer, trusted) {
+		return netip.Addr{}, false
+	}
+	var hops []string
+	for _, h := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(h, ",")...)
+	}
+
+	client, found := peer, false
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		client, found = ip.Unmap(), true
+		if !isTrusted(client, trusted) {
+			break
+		}
+	}
+	return client, found
+}
+
+func isTrusted(ip netip.Addr, trusted []netip.Prefix) bool {
+	for _, p := range trusted {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestForwardedFor(t *testing.T) {
+	trusted, err := ParseTrustedProxies("10.0.0.0/8, 192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xff        []string
+		want       string
+	}{
+		{"direct client", "203.0.113.5:4000", nil, "203.0.113.5:4000"},
+		{"untrusted peer sends header", "203.0.113.5:4000", []string{"198.51.100.1"}, "203.0.113.5:4000"},
+		{"trusted proxy", "10.1.2.3:4000", []string{"198.51.100.1"}, "198.51.100.1:0"},
+		{"spoofed leftmost entry", "10.1.2.3:4000", []string{"1.2.3.4, 198.51.100.1"}, "198.51.100.1:0"},
+		{"chain of trusted proxies", "10.1.2.3:4000", []string{"198.51.100.1, 192.0.2.1", "10.9.9.9"}, "198.51.100.1:0"},
+		{"only trusted hops", "10.1.2.3:4000", []string{"10.4.4.4, 10.5.5.5"}, "10.4.4.4:0"},
+		{"garbage stops the walk", "10.1.2.3:4000", []string{"198.51.100.1, junk, 10.5.5.5"}, "10.5.5.5:0"},
+		{"trusted proxy without header", "10.1.2.3:4000", nil, "10.1.2.3:4000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			h := ForwardedFor(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.RemoteAddr
+			}))
+			r := httptest.NewRequest(http.MethodPost, "/login", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, v := range tt.xff {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), r)
+			if got != tt.want {
+				t.Errorf("RemoteAddr = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	if _, err := ParseTrustedProxies("10.0.0.0/33"); err == nil {
+		t.Error("ParseTrustedProxies(10.0.0.0/33) succeeded")
+	}
+	if _, err := ParseTrustedProxies("proxy.local"); err == nil {
+		t.Error("ParseTrustedProxies(proxy.local) succeeded")
+	}
+	got, err := ParseTrustedProxies(" 10.0.0.1/8 ,::1,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].String() != "10.0.0.0/8" || got[1].String() != "::1/128" {
+		t.Errorf("ParseTrustedProxies() = %v", got)
+	}
+}
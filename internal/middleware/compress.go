@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// SecretPaths are the path prefixes of routes whose responses carry tokens,
+// secrets or recovery codes. Compress never compresses them, because
+// compressing secrets alongside attacker-influenced input enables
+// compression oracle attacks (BREACH).
+var SecretPaths = []string{
+	"/setup",
+	"/login",
+	"/token",
+	"/oauth",
+	"/mfa",
+	"/admin/oauth/clients",
+	"/admin/action-tokens",
+	"/admin/share-tokens",
+}
+
+// Compress returns middleware that brotli- or gzip-encodes JSON responses for
+// clients that accept it. Requests whose path starts with one of SecretPaths
+// or of the excluded prefixes are never compressed.
+func Compress(excludePrefixes []string) func(http.Handler) http.Handler {
+	excludePrefixes = append(slices.Clone(SecretPaths), excludePrefixes...)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, p := range excludePrefixes {
+				if p != "" && strings.HasPrefix(r.URL.Path, p) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+			if encoding == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{ResponseWriter: w, encoding: encoding}
+			defer cw.Close()
+			w.Header().Add("Vary", "Accept-Encoding")
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// negotiateEncoding picks "br" or "gzip" from an Accept-Encoding header,
+// preferring brotli. Encodings explicitly disabled with q=0 are ignored.
+func negotiateEncoding(header string) string {
+	accepted := map[string]bool{}
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if strings.ReplaceAll(strings.TrimSpace(params), " ", "") == "q=0" {
+			continue
+		}
+		accepted[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+	switch {
+	case accepted["br"]:
+		return "br"
+	case accepted["gzip"]:
+		return "gzip"
+	default:
+		return ""
+	}
+}
+
+// compressWriter decides on the first write whether the response is eligible
+// for compression, based on the headers set by the handler.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding    string
+	encoder     io.WriteCloser
+	wroteHeader bool
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+
+	h := cw.Header()
+	if isJSON(h.Get("Content-Type")) && h.Get("Content-Encoding") == "" &&
+		status != http.StatusNoContent && status != http.StatusNotModified {
+		h.Set("Content-Encoding", cw.encoding)
+		h.Del("Content-Length")
+		if cw.encoding == "br" {
+			cw.encoder = brotli.NewWriter(cw.ResponseWriter)
+		} else {
+			cw.encoder = gzip.NewWriter(cw.ResponseWriter)
+		}
+	}
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.encoder != nil {
+		return cw.encoder.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// Close flushes any buffered compressed data.
+func (cw *compressWriter) Close() error {
+	if cw.encoder != nil {
+		return cw.encoder.Close()
+	}
+	return nil
+}
+
+func isJSON(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.TrimSpace(strings.ToLower(mediaType))
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/ratelimit"
+	"github.com/SarathLUN/go-auth-service/internal/reputation"
+	"github.com/SarathLUN/go-auth-service/internal/util"
+)
+
+// maxPeekBytes is how much of a request body RateLimit reads to find the
+// account. The controllers reject larger bodies anyway.
+const maxPeekBytes = 64 << 10
+
+// BadIPLimit makes RateLimit stricter on addresses whose reputation score
+// is at or above Threshold.
+type BadIPLimit struct {
+	// Reputation looks up client addresses; nil disables the check.
+	Reputation reputation.Provider
+	Threshold  int
+	// Limiter applies on top of the per-IP limit. A nil Limiter rejects
+	// known-bad addresses outright.
+	Limiter *ratelimit.Limiter
+}
+
+// RateLimit returns middleware that limits requests per client IP and
+// route, and per account for requests whose JSON body names an "email".
+// Either limiter may be nil to skip that check; bad adds a stricter limit
+// for known-bad addresses. Rejected requests get a 429 with a Retry-After
+// header.
+func RateLimit(byIP, byAccount *ratelimit.Limiter, bad BadIPLimit, norm util.EmailNormalization) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ip, ok := remoteIP(r); ok {
+				key := r.URL.Path + " " + ip.String()
+				if ok, wait := byIP.Allow(key); !ok {
+					tooManyRequests(w, wait)
+					return
+				}
+				if bad.isBad(r, ip) {
+					if bad.Limiter == nil {
+						writeError(w, http.StatusForbidden, "Requests from this network are not allowed")
+						return
+					}
+					if ok, wait := bad.Limiter.Allow(key); !ok {
+						tooManyRequests(w, wait)
+						return
+					}
+				}
+			}
+			if byAccount != nil {
+				if email := peekEmail(r); email != "" {
+					if ok, wait := byAccount.Allow(util.NormalizeEmail(email, norm)); !ok {
+						tooManyRequests(w, wait)
+						return
+					}
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// isBad reports whether ip's reputation meets the threshold. Failed lookups
+// count as clean, so an unreachable provider doesn't lock everyone out.
+func (b BadIPLimit) isBad(r *http.Request, ip netip.Addr) bool {
+	if b.Reputation == nil {
+		return false
+	}
+	v, err := b.Reputation.Lookup(r.Context(), ip)
+	if err != nil {
+		log.Printf("Warning: IP reputation lookup for %s failed: %v", ip, err)
+		return false
+	}
+	return v.IsBad(b.Threshold)
+}
+
+// peekEmail returns the "email" field of a JSON request body, leaving the
+// body intact for the handler.
+func peekEmail(r *http.Request) string {
+	if r.Body == nil {
+		return ""
+	}
+	buf, err := io.ReadAll(io.LimitReader(r.Body, maxPeekBytes))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(buf), r.Body), r.Body}
+	if err != nil {
+		return ""
+	}
+
+	var body struct {
+		Email string `json:"email"`
+	}
+	if json.Unmarshal(buf, &body) != nil {
+		return ""
+	}
+	return body.Email
+}
+
+func tooManyRequests(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	writeError(w, http.StatusTooManyRequests, "Too many requests; try again later")
+}
@@ -0,0 +1,164 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/clock"
+)
+
+// Headers used by internal callers to sign requests.
+const (
+	SignatureKeyIDHeader     = "X-Signature-Key-Id"
+	SignatureTimestampHeader = "X-Signature-Timestamp"
+	SignatureHeader          = "X-Signature"
+)
+
+// maxSignedBodyBytes bounds how much of a signed request body is read into memory.
+const maxSignedBodyBytes = 1 << 20
+
+// ParseSigningKeys parses "keyID:secret" pairs separated by commas, as used
+// by INTERNAL_SIGNING_KEYS.
+func ParseSigningKeys(s string) (map[string][]byte, error) {
+	keys := make(map[string][]byte)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		id, secret, ok := strings.Cut(pair, ":")
+		if !ok || id == "" || secret == "" {
+			return nil, fmt.Errorf("invalid signing key entry %q, expected keyID:secret", pair)
+		}
+		keys[id] = []byte(secret)
+	}
+	return keys, nil
+}
+
+// StringToSign builds the canonical string an internal caller signs:
+// the unix timestamp, method, request URI and hex SHA-256 of the body,
+// separated by newlines. The request URI is the path and query as sent,
+// including BASE_PATH.
+func StringToSign(timestamp, method, requestURI string, body []byte) string {
+	sum := sha256.Sum256(body)
+	return strings.Join([]string{timestamp, method, requestURI, hex.EncodeToString(sum[:])}, "\n")
+}
+
+// RequireSignature returns middleware that rejects requests not signed with
+// one of keys. The signature is a hex HMAC-SHA256 of StringToSign. Requests
+// whose timestamp is further than tolerance from now, or whose signature has
+// already been seen within that window, are rejected to prevent replays.
+func RequireSignature(keys map[string][]byte, tolerance time.Duration, clk clock.Clock) func(http.Handler) http.Handler {
+	if clk == nil {
+		clk = clock.Real{}
+	}
+	seen := &replayCache{entries: make(map[string]time.Time)}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, ok := keys[r.Header.Get(SignatureKeyIDHeader)]
+			if !ok {
+				unauthorized(w, "unknown signing key")
+				return
+			}
+
+			ts := r.Header.Get(SignatureTimestampHeader)
+			unix, err := strconv.ParseInt(ts, 10, 64)
+			if err != nil {
+				unauthorized(w, "invalid signature timestamp")
+				return
+			}
+			now := clk.Now()
+			signedAt := time.Unix(unix, 0)
+			if signedAt.Before(now.Add(-tolerance)) || signedAt.After(now.Add(tolerance)) {
+				unauthorized(w, "signature timestamp outside tolerance")
+				return
+			}
+
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxSignedBodyBytes+1))
+			if err != nil || len(body) > maxSignedBodyBytes {
+				unauthorized(w, "unreadable request body")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			provided, err := hex.DecodeString(r.Header.Get(SignatureHeader))
+			if err != nil {
+				unauthorized(w, "invalid signature")
+				return
+			}
+			mac := hmac.New(sha256.New, key)
+			mac.Write([]byte(StringToSign(ts, r.Method, signedURI(r), body)))
+			if !hmac.Equal(provided, mac.Sum(nil)) {
+				unauthorized(w, "invalid signature")
+				return
+			}
+
+			if !seen.add(string(provided), signedAt.Add(tolerance), now) {
+				unauthorized(w, "replayed request")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// signedURI returns the request target the client sent. Unlike r.URL, it
+// is not rewritten by http.StripPrefix, so the BASE_PATH a caller signed is
+// kept.
+func signedURI(r *http.Request) string {
+	u, err := url.ParseRequestURI(r.RequestURI)
+	if err != nil {
+		return r.URL.RequestURI()
+	}
+	return u.RequestURI()
+}
+
+// replayCache remembers signatures until they fall out of the tolerance window.
+type replayCache struct {
+	mu      sync.Mutex
+	entries map[string]time.Time
+}
+
+// add records sig and reports whether it was not already present.
+func (c *replayCache) add(sig string, expires, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, exp := range c.entries {
+		if now.After(exp) {
+			delete(c.entries, k)
+		}
+	}
+	if _, dup := c.entries[sig]; dup {
+		return false
+	}
+	c.entries[sig] = expires
+	return true
+}
+
+// unauthorized writes a 401 ErrorResponse.
+func unauthorized(w http.ResponseWriter, msg string) {
+	writeError(w, http.StatusUnauthorized, msg)
+}
+
+// writeError writes the same {"error": msg} body as the controllers.
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(map[string]string{"error": msg}); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
@@ -0,0 +1,21 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ActionToken is a single-use token that authorizes one action, such as
+// activating an account or following an unsubscribe link. Only the SHA-256
+// hash of the token is persisted.
+type ActionToken struct {
+	ID        string          `db:"id"`
+	Purpose   string          `db:"purpose"`
+	Subject   string          `db:"subject"` // e.g. the user the action applies to
+	Payload   json.RawMessage `db:"payload"` // optional data returned when the token is consumed
+	TokenHash string          `db:"token_hash"`
+	ExpiresAt time.Time       `db:"expires_at"`
+	UsedAt    *time.Time      `db:"used_at"`
+	ClickedAt *time.Time      `db:"clicked_at"` // set when the emailed link is followed
+	CreatedAt time.Time       `db:"created_at"`
+}
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// DeviceAuthorization is a pending OAuth device authorization grant
+// (RFC 8628). Only hashes of the device code and the user code are
+// persisted.
+type DeviceAuthorization struct {
+	ID             string     `db:"id"`
+	ClientID       string     `db:"client_id"`
+	DeviceCodeHash string     `db:"device_code_hash"`
+	UserCodeHash   string     `db:"user_code_hash"`
+	UserID         *string    `db:"user_id"` // set on approval
+	ExpiresAt      time.Time  `db:"expires_at"`
+	ApprovedAt     *time.Time `db:"approved_at"`
+	DeniedAt       *time.Time `db:"denied_at"`
+	LastPolledAt   *time.Time `db:"last_polled_at"`
+	CompletedAt    *time.Time `db:"completed_at"`
+	CreatedAt      time.Time  `db:"created_at"`
+}
@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// TOTPCredential is a user's authenticator app enrollment. The secret is
+// stored encrypted; the credential only enforces MFA once ConfirmedAt is set.
+type TOTPCredential struct {
+	UserID       string     `db:"user_id"`
+	Secret       []byte     `db:"secret"`
+	ConfirmedAt  *time.Time `db:"confirmed_at"`
+	LastUsedStep int64      `db:"last_used_step"`
+	CreatedAt    time.Time  `db:"created_at"`
+}
+
+// MFAChallenge is issued by a password login for an account with MFA and is
+// exchanged, together with a one-time code, for tokens. Only the SHA-256 hash
+// of the challenge token is persisted.
+type MFAChallenge struct {
+	ID        string     `db:"id"`
+	UserID    string     `db:"user_id"`
+	TokenHash string     `db:"token_hash"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	Attempts  int        `db:"attempts"`
+	UsedAt    *time.Time `db:"used_at"`
+	CreatedAt time.Time  `db:"created_at"`
+}
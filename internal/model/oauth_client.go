@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// OAuthClient is a service registered to obtain tokens with the
+// client_credentials grant. Only the SHA-256 hash of its secret is persisted.
+// Public clients have no secret and may only use the device authorization
+// grant.
+type OAuthClient struct {
+	ID         string    `json:"client_id" db:"id"` // UUIDv7
+	Name       string    `json:"name" db:"name"`
+	SecretHash string    `json:"-" db:"secret_hash"` // empty for public clients
+	Scope      string    `json:"scope" db:"scope"`   // space-separated scopes the client may request
+	Public     bool      `json:"public" db:"public"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// QRLogin is a cross-device login: the device being signed in shows a QR
+// code, a device where the user is already signed in approves it, and the
+// first device polls for tokens. Only hashes of the code and the poll token
+// are persisted.
+type QRLogin struct {
+	ID            string     `db:"id"`
+	CodeHash      string     `db:"code_hash"`
+	PollTokenHash string     `db:"poll_token_hash"`
+	UserID        *string    `db:"user_id"` // set on approval
+	ExpiresAt     time.Time  `db:"expires_at"`
+	ApprovedAt    *time.Time `db:"approved_at"`
+	CompletedAt   *time.Time `db:"completed_at"`
+	// UserAgent and ClientIP describe the device that started the login;
+	// either is empty if unknown.
+	UserAgent string    `db:"user_agent"`
+	ClientIP  string    `db:"client_ip"`
+	CreatedAt time.Time `db:"created_at"`
+}
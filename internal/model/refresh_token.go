@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// RefreshToken is a stored refresh token. Only the SHA-256 hash of the
+// opaque token value is persisted. Tokens issued by rotating one another
+// share a FamilyID, which starts at login.
+type RefreshToken struct {
+	ID        string     `db:"id"`
+	UserID    string     `db:"user_id"`
+	FamilyID  string     `db:"family_id"`
+	TokenHash string     `db:"token_hash"`
+	UserAgent string     `db:"user_agent"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	RotatedAt *time.Time `db:"rotated_at"`
+	RevokedAt *time.Time `db:"revoked_at"`
+	CreatedAt time.Time  `db:"created_at"`
+}
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// Session is a login on one device: a refresh token family seen through its
+// newest token. Its ID is the family ID.
+type Session struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`   // when the user logged in
+	LastUsedAt time.Time `json:"last_used_at"` // when the tokens were last issued or refreshed
+	ExpiresAt  time.Time `json:"expires_at"`   // when the session ends unless refreshed
+}
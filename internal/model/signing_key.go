@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// SigningKey is a JWT signing key managed by the database key store. The
+// private key is stored encrypted.
+type SigningKey struct {
+	KeyID      string     `db:"kid"`
+	Algorithm  string     `db:"algorithm"`
+	PrivateKey []byte     `db:"private_key"`
+	CreatedAt  time.Time  `db:"created_at"`
+	RetiredAt  *time.Time `db:"retired_at"`
+}
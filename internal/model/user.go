@@ -2,13 +2,31 @@ package model
 
 import "time"
 
+// User roles.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 // User represents a user in the system.
 type User struct {
-	ID           int64     `json:"id" db:"id"`
-	Username     string    `json:"username" db:"username"`
-	Email        string    `json:"email" db:"email"`
-	PasswordHash string    `json:"-" db:"password_hash"` // exclude from JSON responses
-	IsActive     bool      `json:"is_active" db:"is_active"`
-	CreatedAt    time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+	ID              string    `json:"id" db:"id"` // UUIDv7
+	Username        string    `json:"username" db:"username"`
+	Email           string    `json:"email" db:"email"`
+	EmailNormalized string    `json:"-" db:"email_normalized"`                // canonical form used for uniqueness and login
+	PasswordHash    string    `json:"-" db:"password_hash"`                   // exclude from JSON responses
+	ExternalID      *string   `json:"external_id,omitempty" db:"external_id"` // ID in a legacy system, if any
+	IsActive        bool      `json:"is_active" db:"is_active"`
+	Role            string    `json:"role" db:"role"`
+	Locale          string    `json:"locale,omitempty" db:"locale"`     // BCP 47 language tag
+	Timezone        string    `json:"timezone,omitempty" db:"timezone"` // IANA time zone name
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+
+	// LockedUntil is set when too many failed logins locked the account.
+	// The lock has lifted once it is in the past.
+	LockedUntil *time.Time `json:"locked_until,omitempty" db:"locked_until"`
+	// FailedLoginsResetAt is when failed logins last stopped counting towards
+	// a lockout: the last successful login, lockout or unlock.
+	FailedLoginsResetAt *time.Time `json:"-" db:"failed_logins_reset_at"`
 }
@@ -0,0 +1,48 @@
+package outbound
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/SarathLUN/go-auth-service/internal/config"
+)
+
+// TLSConfig returns a TLS configuration trusting the system roots plus any
+// certificates in OUTBOUND_CA_FILE, for calls to webhooks, identity
+// providers and SMTP servers behind corporate TLS interception.
+func TLSConfig(cfg *config.Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.OutboundCAFile == "" {
+		return tlsConfig, nil
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	pem, err := os.ReadFile(cfg.OutboundCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("read OUTBOUND_CA_FILE: %w", err)
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("OUTBOUND_CA_FILE %s contains no PEM certificates", cfg.OutboundCAFile)
+	}
+	tlsConfig.RootCAs = pool
+	return tlsConfig, nil
+}
+
+// NewHTTPClient returns an HTTP client for outbound calls. It honours
+// HTTPS_PROXY, HTTP_PROXY and NO_PROXY and trusts the extra CAs from TLSConfig.
+func NewHTTPClient(cfg *config.Config) (*http.Client, error) {
+	tlsConfig, err := TLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport, Timeout: cfg.OutboundTimeout}, nil
+}
@@ -0,0 +1,123 @@
+package password
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Character classes a Policy can require.
+const (
+	ClassLower  = "lower"
+	ClassUpper  = "upper"
+	ClassDigit  = "digit"
+	ClassSymbol = "symbol"
+)
+
+// Rules reported by Violation.
+const (
+	RuleMinLength      = "min_length"
+	RuleCharacterClass = "character_class"
+	RuleCommonPassword = "common_password"
+	RuleContainsEmail  = "contains_email"
+	RuleMinScore       = "min_score"
+)
+
+// classes maps each character class to its test and the message for
+// passwords lacking it.
+var classes = map[string]struct {
+	is      func(rune) bool
+	message string
+}{
+	ClassLower:  {unicode.IsLower, "must contain a lowercase letter"},
+	ClassUpper:  {unicode.IsUpper, "must contain an uppercase letter"},
+	ClassDigit:  {unicode.IsDigit, "must contain a digit"},
+	ClassSymbol: {isSymbol, "must contain a symbol"},
+}
+
+// Policy is a set of requirements passwords must meet.
+type Policy struct {
+	// MinLength is counted in characters, not bytes.
+	MinLength int
+	// RequiredClasses are the character classes, such as ClassUpper, that
+	// must each appear at least once.
+	RequiredClasses []string
+	// DenyCommon rejects passwords from the common password list, including
+	// their l33t spellings.
+	DenyCommon bool
+	// DenyEmailLocalPart rejects passwords containing the part of the
+	// user's email address before the @.
+	DenyEmailLocalPart bool
+	// MinScore rejects passwords Estimate scores lower, such as
+	// ScoreSomewhatGuessable. 0 accepts any score.
+	MinScore int
+}
+
+// Violation is a requirement of a Policy that a password does not meet. Its
+// message is safe to show to users.
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Check returns every requirement password does not meet, in a stable
+// order, or nil if it meets them all. email and username identify the
+// account the password is for.
+func (p Policy) Check(password, email, username string) []Violation {
+	var violations []Violation
+	if len([]rune(password)) < p.MinLength {
+		violations = append(violations, Violation{RuleMinLength, fmt.Sprintf("must be at least %d characters", p.MinLength)})
+	}
+	for _, name := range p.RequiredClasses {
+		class, ok := classes[name]
+		if ok && !strings.ContainsFunc(password, class.is) {
+			violations = append(violations, Violation{RuleCharacterClass, class.message})
+		}
+	}
+
+	lower := strings.ToLower(password)
+	if p.DenyCommon {
+		if _, ok := commonPasswords[lower]; ok {
+			violations = append(violations, Violation{RuleCommonPassword, "is too common"})
+		} else if _, ok := commonPasswords[l33t.Replace(lower)]; ok {
+			violations = append(violations, Violation{RuleCommonPassword, "is too common"})
+		}
+	}
+	if p.DenyEmailLocalPart {
+		local, _, _ := strings.Cut(strings.ToLower(email), "@")
+		local, _, _ = strings.Cut(local, "+")
+		// Very short local parts would reject too many unrelated passwords.
+		if len(local) >= 3 && strings.Contains(lower, local) {
+			violations = append(violations, Violation{RuleContainsEmail, "must not contain your email address"})
+		}
+	}
+	if p.MinScore > 0 && Estimate(password, email, username).Score < p.MinScore {
+		violations = append(violations, Violation{RuleMinScore, "is too easy to guess"})
+	}
+	return violations
+}
+
+// ParseClasses parses a comma-separated list of character classes. Unknown
+// classes are left out of the result and reported in the error.
+func ParseClasses(list string) ([]string, error) {
+	var names, unknown []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch _, ok := classes[name]; {
+		case name == "":
+		case ok:
+			names = append(names, name)
+		default:
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		return names, fmt.Errorf("unknown character classes %q, expected %s, %s, %s or %s",
+			unknown, ClassLower, ClassUpper, ClassDigit, ClassSymbol)
+	}
+	return names, nil
+}
+
+func isSymbol(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsSpace(r)
+}
@@ -0,0 +1,317 @@
+package password
+
+import (
+	_ "embed"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Scores returned by Estimate, following zxcvbn's 0 (too guessable) to 4
+// (very unguessable) scale.
+const (
+	ScoreTooGuessable = iota
+	ScoreVeryGuessable
+	ScoreSomewhatGuessable
+	ScoreSafelyUnguessable
+	ScoreVeryUnguessable
+)
+
+var (
+	//go:embed common.txt
+	commonPasswordsFile string
+	//go:embed words.txt
+	commonWordsFile string
+
+	// rankedDictionaries map lower-cased entries to their frequency rank (1 = most common).
+	commonPasswords = rankedDictionary(commonPasswordsFile)
+	commonWords     = rankedDictionary(commonWordsFile)
+)
+
+// MaxEstimateLength is the number of characters Estimate looks at, as in
+// zxcvbn. Finding matches costs time cubic in the length, so longer
+// passwords are scored on this prefix only.
+const MaxEstimateLength = 100
+
+// keyboardRows are adjacent-key runs people type as "patterns".
+var keyboardRows = []string{"qwertyuiop", "asdfghjkl", "zxcvbnm", "1234567890", "qazwsxedc"}
+
+// l33t maps common character substitutions back to letters.
+var l33t = strings.NewReplacer("4", "a", "@", "a", "8", "b", "3", "e", "6", "g", "1", "l", "!", "i", "0", "o", "$", "s", "5", "s", "7", "t", "+", "t", "2", "z")
+
+// Strength is the result of estimating how hard a password is to guess.
+type Strength struct {
+	Score        int      `json:"score"`
+	GuessesLog10 float64  `json:"guesses_log10"`
+	Warning      string   `json:"warning,omitempty"`
+	Suggestions  []string `json:"suggestions,omitempty"`
+}
+
+// match is a substring [i, j] recognised as a pattern with an estimated guess count.
+type match struct {
+	i, j    int
+	guesses float64
+	kind    string
+}
+
+// Estimate scores a password the way zxcvbn does: it finds guessable patterns
+// (common passwords and words, l33t variants, keyboard runs, sequences,
+// repeats, years and the supplied user inputs such as email or username) and
+// computes the cheapest way an attacker could cover the whole password with
+// them. Only the first MaxEstimateLength characters are scored.
+func Estimate(password string, userInputs ...string) Strength {
+	runes := []rune(password)
+	if len(runes) > MaxEstimateLength {
+		runes = runes[:MaxEstimateLength]
+	}
+	n := len(runes)
+	if n == 0 {
+		return Strength{Score: ScoreTooGuessable, Warning: "Password is empty."}
+	}
+
+	userDict := make(map[string]int)
+	for i, in := range userInputs {
+		in = strings.ToLower(strings.TrimSpace(in))
+		if local, _, ok := strings.Cut(in, "@"); ok {
+			in = local
+		}
+		if len(in) >= 3 {
+			userDict[in] = i + 1
+		}
+	}
+
+	matches := findMatches(runes, userDict)
+	bruteforce := float64(cardinality(runes))
+
+	// best[k] is the minimum guesses needed to cover runes[:k]; via[k] the
+	// match used for the last segment (nil for a bruteforced character).
+	best := make([]float64, n+1)
+	via := make([]*match, n+1)
+	best[0] = 1
+	for k := 1; k <= n; k++ {
+		best[k] = best[k-1] * bruteforce
+		via[k] = nil
+		for idx := range matches {
+			m := &matches[idx]
+			if m.j+1 != k {
+				continue
+			}
+			if g := best[m.i] * m.guesses; g < best[k] {
+				best[k] = g
+				via[k] = m
+			}
+		}
+	}
+
+	guesses := best[n]
+	s := Strength{GuessesLog10: math.Round(math.Log10(guesses)*100) / 100}
+	switch {
+	case guesses < 1e3:
+		s.Score = ScoreTooGuessable
+	case guesses < 1e6:
+		s.Score = ScoreVeryGuessable
+	case guesses < 1e8:
+		s.Score = ScoreSomewhatGuessable
+	case guesses < 1e10:
+		s.Score = ScoreSafelyUnguessable
+	default:
+		s.Score = ScoreVeryUnguessable
+	}
+
+	if s.Score <= ScoreSomewhatGuessable {
+		s.Warning, s.Suggestions = feedback(via, n)
+	}
+	return s
+}
+
+// findMatches returns every pattern match in the password.
+func findMatches(runes []rune, userDict map[string]int) []match {
+	lower := []rune(strings.ToLower(string(runes)))
+	n := len(runes)
+	var matches []match
+
+	for i := 0; i < n; i++ {
+		for j := i + 2; j < n; j++ {
+			word := string(lower[i : j+1])
+			variations := caseVariations(runes[i : j+1])
+
+			if rank, ok := userDict[word]; ok {
+				matches = append(matches, match{i, j, float64(rank) * variations, "user_input"})
+			}
+			if rank, ok := commonPasswords[word]; ok {
+				matches = append(matches, match{i, j, float64(rank) * variations, "common_password"})
+			}
+			if rank, ok := commonWords[word]; ok {
+				matches = append(matches, match{i, j, float64(rank) * variations, "dictionary"})
+			}
+			if unl33t := l33t.Replace(word); unl33t != word {
+				if rank, ok := commonPasswords[unl33t]; ok {
+					matches = append(matches, match{i, j, float64(rank) * variations * 4, "common_password"})
+				}
+				if rank, ok := commonWords[unl33t]; ok {
+					matches = append(matches, match{i, j, float64(rank) * variations * 4, "dictionary"})
+				}
+			}
+			if j-i >= 3 && isKeyboardRun(word) {
+				matches = append(matches, match{i, j, 6 * float64(j-i+1), "keyboard"})
+			}
+		}
+	}
+
+	// Repeats and sequences: maximal runs of length >= 3.
+	for i := 0; i < n; {
+		j := i
+		for j+1 < n && lower[j+1] == lower[i] {
+			j++
+		}
+		if j-i >= 2 {
+			matches = append(matches, match{i, j, float64(cardinality(runes[i:i+1])) * float64(j-i+1), "repeat"})
+		}
+		i = j + 1
+	}
+	for i := 0; i+2 < n; {
+		delta := lower[i+1] - lower[i]
+		j := i + 1
+		for j+1 < n && lower[j+1]-lower[j] == delta {
+			j++
+		}
+		if (delta == 1 || delta == -1) && j-i >= 2 {
+			matches = append(matches, match{i, j, float64(cardinality(runes[i:i+1])) * float64(j-i+1), "sequence"})
+		}
+		i = j
+	}
+
+	// Recent years (1900-2099) are a favourite suffix.
+	for i := 0; i+3 < n; i++ {
+		y := string(runes[i : i+4])
+		if (strings.HasPrefix(y, "19") || strings.HasPrefix(y, "20")) && isDigits(y) {
+			matches = append(matches, match{i, i + 3, 120, "year"})
+		}
+	}
+	return matches
+}
+
+// feedback explains the weakest pattern used in the cheapest guessing path.
+func feedback(via []*match, n int) (string, []string) {
+	suggestions := []string{"Add another word or two. Uncommon words are better."}
+	kinds := map[string]bool{}
+	for k := n; k > 0; {
+		if m := via[k]; m != nil {
+			kinds[m.kind] = true
+			k = m.i
+			continue
+		}
+		k--
+	}
+
+	switch {
+	case kinds["common_password"]:
+		return "This is a very common password.", suggestions
+	case kinds["user_input"]:
+		return "Avoid using your name, email or username.", suggestions
+	case kinds["keyboard"]:
+		return "Straight rows of keys are easy to guess.", append(suggestions, "Use a longer keyboard pattern with more turns.")
+	case kinds["sequence"]:
+		return "Sequences like abc or 6543 are easy to guess.", append(suggestions, "Avoid sequences.")
+	case kinds["repeat"]:
+		return "Repeats like \"aaa\" are easy to guess.", append(suggestions, "Avoid repeated words and characters.")
+	case kinds["year"]:
+		return "Recent years are easy to guess.", append(suggestions, "Avoid years that are associated with you.")
+	case kinds["dictionary"]:
+		return "A word by itself is easy to guess.", append(suggestions, "Capitalization and predictable substitutions like '@' instead of 'a' don't help much.")
+	default:
+		return "", append(suggestions, "Use a longer password.")
+	}
+}
+
+// cardinality is the size of the character space the runes are drawn from.
+func cardinality(runes []rune) int {
+	var lower, upper, digit, symbol, other bool
+	for _, r := range runes {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < unicode.MaxASCII:
+			symbol = true
+		default:
+			other = true
+		}
+	}
+	c := 0
+	if lower {
+		c += 26
+	}
+	if upper {
+		c += 26
+	}
+	if digit {
+		c += 10
+	}
+	if symbol {
+		c += 33
+	}
+	if other {
+		c += 100
+	}
+	return c
+}
+
+// caseVariations estimates the extra guesses needed to cover capitalization.
+func caseVariations(runes []rune) float64 {
+	upper := 0
+	for _, r := range runes {
+		if unicode.IsUpper(r) {
+			upper++
+		}
+	}
+	switch {
+	case upper == 0:
+		return 1
+	case upper == len(runes), upper == 1 && unicode.IsUpper(runes[0]):
+		// All caps or just the first letter capitalized.
+		return 2
+	default:
+		return float64(len(runes))
+	}
+}
+
+func isKeyboardRun(s string) bool {
+	for _, row := range keyboardRows {
+		if strings.Contains(row, s) || strings.Contains(reverse(row), s) {
+			return true
+		}
+	}
+	return false
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func reverse(s string) string {
+	r := []rune(s)
+	for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+		r[i], r[j] = r[j], r[i]
+	}
+	return string(r)
+}
+
+func rankedDictionary(list string) map[string]int {
+	d := make(map[string]int)
+	for i, w := range strings.Fields(list) {
+		w = strings.ToLower(w)
+		if _, dup := d[w]; !dup {
+			d[w] = i + 1
+		}
+	}
+	return d
+}
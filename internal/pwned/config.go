@@ -0,0 +1,19 @@
+package pwned
+
+import (
+	"github.com/SarathLUN/go-auth-service/internal/config"
+	"github.com/SarathLUN/go-auth-service/internal/outbound"
+)
+
+// NewFromConfig builds the Checker enabled by PWNED_PASSWORDS_CHECK. It
+// returns a nil Checker when the check is disabled.
+func NewFromConfig(cfg *config.Config) (Checker, error) {
+	if !cfg.PwnedPasswordsCheck {
+		return nil, nil
+	}
+	client, err := outbound.NewHTTPClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return NewHIBP(cfg.PwnedPasswordsEndpoint, client, cfg.PwnedPasswordsCacheTTL, nil), nil
+}
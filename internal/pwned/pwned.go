@@ -0,0 +1,138 @@
+// Package pwned checks passwords against the Have I Been Pwned corpus of
+// breached passwords.
+package pwned
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/clock"
+)
+
+// DefaultEndpoint is the Pwned Passwords range API.
+const DefaultEndpoint = "https://api.pwnedpasswords.com/range/"
+
+// maxCacheEntries bounds the number of hash prefixes kept in memory. Each
+// holds the suffixes of around a thousand breached passwords.
+const maxCacheEntries = 1000
+
+// Checker reports how often a password appears in known data breaches.
+type Checker interface {
+	Count(ctx context.Context, password string) (int, error)
+}
+
+type cacheEntry struct {
+	counts  map[string]int
+	expires time.Time
+}
+
+// HIBP is a Checker backed by the Pwned Passwords range API. Only the first
+// five hex digits of the password's SHA-1 leave the process (k-anonymity),
+// and the responses are cached per prefix.
+type HIBP struct {
+	endpoint string
+	client   *http.Client
+	ttl      time.Duration
+	clock    clock.Clock
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+// NewHIBP creates a HIBP checker querying endpoint, to which the hash prefix
+// is appended. A nil client uses http.DefaultClient and a nil clock the
+// system time.
+func NewHIBP(endpoint string, client *http.Client, ttl time.Duration, clk clock.Clock) *HIBP {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if clk == nil {
+		clk = clock.Real{}
+	}
+	return &HIBP{endpoint: endpoint, client: client, ttl: ttl, clock: clk, entries: make(map[string]cacheEntry)}
+}
+
+// Count implements Checker. Errors are not cached.
+func (h *HIBP) Count(ctx context.Context, password string) (int, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+	now := h.clock.Now()
+
+	h.mu.Lock()
+	if e, ok := h.entries[prefix]; ok && now.Before(e.expires) {
+		h.mu.Unlock()
+		return e.counts[suffix], nil
+	}
+	h.mu.Unlock()
+
+	counts, err := h.fetch(ctx, prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	h.mu.Lock()
+	if len(h.entries) >= maxCacheEntries {
+		// Drop expired entries, or everything if none have expired, so the
+		// map doesn't grow forever.
+		for k, e := range h.entries {
+			if now.After(e.expires) {
+				delete(h.entries, k)
+			}
+		}
+		if len(h.entries) >= maxCacheEntries {
+			clear(h.entries)
+		}
+	}
+	h.entries[prefix] = cacheEntry{counts: counts, expires: now.Add(h.ttl)}
+	h.mu.Unlock()
+	return counts[suffix], nil
+}
+
+// fetch returns the breach counts of the hash suffixes under prefix.
+func (h *HIBP) fetch(ctx context.Context, prefix string) (map[string]int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.endpoint+prefix, nil)
+	if err != nil {
+		return nil, err
+	}
+	// Padding hides the real number of suffixes from anyone watching the
+	// response size; padded entries have a count of 0.
+	req.Header.Set("Add-Padding", "true")
+	req.Header.Set("User-Agent", "go-auth-service")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("pwned passwords lookup: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("pwned passwords lookup: unexpected status %d", resp.StatusCode)
+	}
+
+	counts := make(map[string]int)
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		suffix, count, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(count)
+		if err != nil || n == 0 {
+			continue
+		}
+		counts[strings.ToUpper(suffix)] = n
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("pwned passwords lookup: read response: %w", err)
+	}
+	return counts, nil
+}
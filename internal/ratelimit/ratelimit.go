@@ -0,0 +1,88 @@
+// Package ratelimit implements in-memory token bucket rate limiting. The
+// buckets live in the process, so each instance of the service enforces its
+// limits separately.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/clock"
+)
+
+// maxBuckets bounds memory use; beyond it, buckets that have refilled are
+// dropped, which is the same as never having used them.
+const maxBuckets = 100000
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter keeps a token bucket per key. Each bucket holds up to burst
+// tokens and refills at a steady rate; a request takes one token.
+type Limiter struct {
+	rate  float64 // tokens per second
+	burst float64
+	clock clock.Clock
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+// New creates a Limiter allowing perMinute requests per key on average and
+// bursts of up to burst. It returns nil, which allows everything, when
+// perMinute is not positive. A burst below 1 is raised to 1, and a nil clock
+// uses the system time.
+func New(perMinute, burst int, clk clock.Clock) *Limiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	if clk == nil {
+		clk = clock.Real{}
+	}
+	return &Limiter{
+		rate:    float64(perMinute) / 60,
+		burst:   float64(max(burst, 1)),
+		clock:   clk,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow takes a token from key's bucket. If the bucket is empty it returns
+// false and how long until a token is available.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+	now := l.clock.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= maxBuckets {
+			l.dropFull(now)
+		}
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
+
+// dropFull removes the buckets that have refilled completely by now.
+func (l *Limiter) dropFull(now time.Time) {
+	for k, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, k)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/model"
+)
+
+// ActionTokenRepository persists single-use action tokens.
+type ActionTokenRepository interface {
+	Create(ctx context.Context, token *model.ActionToken) error
+	GetByHash(ctx context.Context, tokenHash string) (*model.ActionToken, error)
+	// MarkUsed consumes the token. It returns false if it was already used.
+	MarkUsed(ctx context.Context, id string, at time.Time) (bool, error)
+	// MarkClicked records that the link carrying an unused token was
+	// followed. It returns false if it was already followed or used.
+	MarkClicked(ctx context.Context, id string, at time.Time) (bool, error)
+}
+
+type actionTokenRepository struct {
+	db *sql.DB
+}
+
+// NewActionTokenRepository creates a Postgres-backed ActionTokenRepository.
+func NewActionTokenRepository(db *sql.DB) ActionTokenRepository {
+	return &actionTokenRepository{db: db}
+}
+
+func (r *actionTokenRepository) Create(ctx context.Context, t *model.ActionToken) error {
+	var payload any
+	if len(t.Payload) > 0 {
+		payload = string(t.Payload)
+	}
+	return conn(ctx, r.db).QueryRowContext(ctx, `
+		INSERT INTO action_tokens (purpose, subject, payload, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`,
+		t.Purpose, t.Subject, payload, t.TokenHash, t.ExpiresAt,
+	).Scan(&t.ID, &t.CreatedAt)
+}
+
+func (r *actionTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*model.ActionToken, error) {
+	var t model.ActionToken
+	var payload []byte
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		SELECT id, purpose, subject, payload, token_hash, expires_at, used_at, clicked_at, created_at
+		FROM action_tokens WHERE token_hash = $1`, tokenHash,
+	).Scan(&t.ID, &t.Purpose, &t.Subject, &payload, &t.TokenHash, &t.ExpiresAt, &t.UsedAt, &t.ClickedAt, &t.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	t.Payload = payload
+	return &t, nil
+}
+
+func (r *actionTokenRepository) MarkUsed(ctx context.Context, id string, at time.Time) (bool, error) {
+	res, err := conn(ctx, r.db).ExecContext(ctx,
+		`UPDATE action_tokens SET used_at = $1 WHERE id = $2 AND used_at IS NULL`, at, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (r *actionTokenRepository) MarkClicked(ctx context.Context, id string, at time.Time) (bool, error) {
+	res, err := conn(ctx, r.db).ExecContext(ctx,
+		`UPDATE action_tokens SET clicked_at = $1 WHERE id = $2 AND clicked_at IS NULL AND used_at IS NULL`, at, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/model"
+)
+
+// DeviceAuthorizationRepository persists pending OAuth device authorizations.
+type DeviceAuthorizationRepository interface {
+	Create(ctx context.Context, auth *model.DeviceAuthorization) error
+	GetByDeviceCodeHash(ctx context.Context, deviceCodeHash string) (*model.DeviceAuthorization, error)
+	GetByUserCodeHash(ctx context.Context, userCodeHash string) (*model.DeviceAuthorization, error)
+	// Approve assigns the authorization to userID and Deny rejects it. Both
+	// return false if it is expired or was already decided.
+	Approve(ctx context.Context, id, userID string, at time.Time) (bool, error)
+	Deny(ctx context.Context, id string, at time.Time) (bool, error)
+	// RecordPoll stores when the device last polled, for slow_down.
+	RecordPoll(ctx context.Context, id string, at time.Time) error
+	// Complete marks the approved authorization as exchanged for tokens. It
+	// returns false if it already was.
+	Complete(ctx context.Context, id string, at time.Time) (bool, error)
+}
+
+type deviceAuthorizationRepository struct {
+	db *sql.DB
+}
+
+// NewDeviceAuthorizationRepository creates a Postgres-backed DeviceAuthorizationRepository.
+func NewDeviceAuthorizationRepository(db *sql.DB) DeviceAuthorizationRepository {
+	return &deviceAuthorizationRepository{db: db}
+}
+
+func (r *deviceAuthorizationRepository) Create(ctx context.Context, a *model.DeviceAuthorization) error {
+	return conn(ctx, r.db).QueryRowContext(ctx, `
+		INSERT INTO device_authorizations (client_id, device_code_hash, user_code_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`,
+		a.ClientID, a.DeviceCodeHash, a.UserCodeHash, a.ExpiresAt,
+	).Scan(&a.ID, &a.CreatedAt)
+}
+
+const deviceAuthorizationColumns = `id, client_id, device_code_hash, user_code_hash, user_id, expires_at, approved_at, denied_at, last_polled_at, completed_at, created_at`
+
+func (r *deviceAuthorizationRepository) GetByDeviceCodeHash(ctx context.Context, deviceCodeHash string) (*model.DeviceAuthorization, error) {
+	return r.getOne(ctx, `SELECT `+deviceAuthorizationColumns+` FROM device_authorizations WHERE device_code_hash = $1`, deviceCodeHash)
+}
+
+func (r *deviceAuthorizationRepository) GetByUserCodeHash(ctx context.Context, userCodeHash string) (*model.DeviceAuthorization, error) {
+	return r.getOne(ctx, `SELECT `+deviceAuthorizationColumns+` FROM device_authorizations WHERE user_code_hash = $1`, userCodeHash)
+}
+
+func (r *deviceAuthorizationRepository) getOne(ctx context.Context, query string, args ...any) (*model.DeviceAuthorization, error) {
+	var a model.DeviceAuthorization
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, args...).Scan(
+		&a.ID, &a.ClientID, &a.DeviceCodeHash, &a.UserCodeHash, &a.UserID, &a.ExpiresAt,
+		&a.ApprovedAt, &a.DeniedAt, &a.LastPolledAt, &a.CompletedAt, &a.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *deviceAuthorizationRepository) Approve(ctx context.Context, id, userID string, at time.Time) (bool, error) {
+	res, err := conn(ctx, r.db).ExecContext(ctx, `
+		UPDATE device_authorizations SET user_id = $1, approved_at = $2
+		WHERE id = $3 AND approved_at IS NULL AND denied_at IS NULL AND expires_at > $2`,
+		userID, at, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (r *deviceAuthorizationRepository) Deny(ctx context.Context, id string, at time.Time) (bool, error) {
+	res, err := conn(ctx, r.db).ExecContext(ctx, `
+		UPDATE device_authorizations SET denied_at = $1
+		WHERE id = $2 AND approved_at IS NULL AND denied_at IS NULL AND expires_at > $1`,
+		at, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (r *deviceAuthorizationRepository) RecordPoll(ctx context.Context, id string, at time.Time) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE device_authorizations SET last_polled_at = $1 WHERE id = $2`, at, id)
+	return err
+}
+
+func (r *deviceAuthorizationRepository) Complete(ctx context.Context, id string, at time.Time) (bool, error) {
+	res, err := conn(ctx, r.db).ExecContext(ctx,
+		`UPDATE device_authorizations SET completed_at = $1 WHERE id = $2 AND approved_at IS NOT NULL AND completed_at IS NULL`, at, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
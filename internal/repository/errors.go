@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// uniqueViolation is the Postgres SQLSTATE for unique constraint violations.
+const uniqueViolation = "23505"
+
+var (
+	// ErrNotFound is returned when a lookup matches no rows.
+	ErrNotFound = errors.New("record not found")
+	// ErrDuplicateEmail is returned when the email is already registered (case-insensitively).
+	ErrDuplicateEmail = errors.New("email already registered")
+	// ErrDuplicateUsername is returned when the username is already taken (case-insensitively).
+	ErrDuplicateUsername = errors.New("username already taken")
+	// ErrDuplicateExternalID is returned when the external ID is already linked to another user.
+	ErrDuplicateExternalID = errors.New("external id already in use")
+)
+
+// constraintErrors maps unique constraints on the users table to domain errors.
+var constraintErrors = map[string]error{
+	"users_email_key":            ErrDuplicateEmail,
+	"users_email_lower_key":      ErrDuplicateEmail,
+	"users_email_normalized_key": ErrDuplicateEmail,
+	"users_username_key":         ErrDuplicateUsername,
+	"users_username_lower_key":   ErrDuplicateUsername,
+	"users_external_id_key":      ErrDuplicateExternalID,
+}
+
+// TranslateError converts database constraint violations into domain errors
+// so callers can respond with a conflict instead of a generic failure.
+// Errors it does not recognise are returned unchanged.
+func TranslateError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		if mapped, ok := constraintErrors[pgErr.ConstraintName]; ok {
+			return mapped
+		}
+	}
+	return err
+}
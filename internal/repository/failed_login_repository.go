@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// FailedLoginRepository keeps an audit trail of logins with a wrong password
+// or MFA code for known accounts, which the lockout policy counts.
+type FailedLoginRepository interface {
+	Record(ctx context.Context, userID, userAgent string, at time.Time) error
+	// CountSince returns how many failed logins the user had after since.
+	CountSince(ctx context.Context, userID string, since time.Time) (int, error)
+}
+
+type failedLoginRepository struct {
+	db *sql.DB
+}
+
+// NewFailedLoginRepository creates a Postgres-backed FailedLoginRepository.
+func NewFailedLoginRepository(db *sql.DB) FailedLoginRepository {
+	return &failedLoginRepository{db: db}
+}
+
+func (r *failedLoginRepository) Record(ctx context.Context, userID, userAgent string, at time.Time) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx,
+		`INSERT INTO failed_logins (user_id, user_agent, attempted_at) VALUES ($1, $2, $3)`,
+		userID, userAgent, at)
+	return err
+}
+
+func (r *failedLoginRepository) CountSince(ctx context.Context, userID string, since time.Time) (int, error) {
+	var n int
+	err := conn(ctx, r.db).QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM failed_logins WHERE user_id = $1 AND attempted_at > $2`,
+		userID, since).Scan(&n)
+	return n, err
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/model"
+)
+
+// MFAChallengeRepository persists pending second-factor logins.
+type MFAChallengeRepository interface {
+	Create(ctx context.Context, challenge *model.MFAChallenge) error
+	GetByHash(ctx context.Context, tokenHash string) (*model.MFAChallenge, error)
+	// RecordFailure counts a wrong code against the challenge and returns the
+	// new number of attempts.
+	RecordFailure(ctx context.Context, id string) (int, error)
+	// MarkUsed consumes the challenge. It returns false if it was already used.
+	MarkUsed(ctx context.Context, id string, at time.Time) (bool, error)
+}
+
+type mfaChallengeRepository struct {
+	db *sql.DB
+}
+
+// NewMFAChallengeRepository creates a Postgres-backed MFAChallengeRepository.
+func NewMFAChallengeRepository(db *sql.DB) MFAChallengeRepository {
+	return &mfaChallengeRepository{db: db}
+}
+
+func (r *mfaChallengeRepository) Create(ctx context.Context, c *model.MFAChallenge) error {
+	return conn(ctx, r.db).QueryRowContext(ctx, `
+		INSERT INTO mfa_challenges (user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at`,
+		c.UserID, c.TokenHash, c.ExpiresAt,
+	).Scan(&c.ID, &c.CreatedAt)
+}
+
+func (r *mfaChallengeRepository) GetByHash(ctx context.Context, tokenHash string) (*model.MFAChallenge, error) {
+	var c model.MFAChallenge
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		SELECT id, user_id, token_hash, expires_at, attempts, used_at, created_at
+		FROM mfa_challenges WHERE token_hash = $1`, tokenHash,
+	).Scan(&c.ID, &c.UserID, &c.TokenHash, &c.ExpiresAt, &c.Attempts, &c.UsedAt, &c.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *mfaChallengeRepository) RecordFailure(ctx context.Context, id string) (int, error) {
+	var attempts int
+	err := conn(ctx, r.db).QueryRowContext(ctx,
+		`UPDATE mfa_challenges SET attempts = attempts + 1 WHERE id = $1 RETURNING attempts`, id,
+	).Scan(&attempts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNotFound
+	}
+	return attempts, err
+}
+
+func (r *mfaChallengeRepository) MarkUsed(ctx context.Context, id string, at time.Time) (bool, error) {
+	res, err := conn(ctx, r.db).ExecContext(ctx,
+		`UPDATE mfa_challenges SET used_at = $1 WHERE id = $2 AND used_at IS NULL`, at, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
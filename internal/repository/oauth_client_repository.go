@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/SarathLUN/go-auth-service/internal/model"
+)
+
+// OAuthClientRepository persists registered OAuth clients.
+type OAuthClientRepository interface {
+	Create(ctx context.Context, client *model.OAuthClient) error
+	GetByID(ctx context.Context, id string) (*model.OAuthClient, error)
+	// Delete removes the client or returns ErrNotFound.
+	Delete(ctx context.Context, id string) error
+}
+
+type oauthClientRepository struct {
+	db *sql.DB
+}
+
+// NewOAuthClientRepository creates a Postgres-backed OAuthClientRepository.
+func NewOAuthClientRepository(db *sql.DB) OAuthClientRepository {
+	return &oauthClientRepository{db: db}
+}
+
+func (r *oauthClientRepository) Create(ctx context.Context, c *model.OAuthClient) error {
+	return conn(ctx, r.db).QueryRowContext(ctx, `
+		INSERT INTO oauth_clients (name, secret_hash, scope, public)
+		VALUES ($1, NULLIF($2, ''), $3, $4)
+		RETURNING id, created_at`,
+		c.Name, c.SecretHash, c.Scope, c.Public,
+	).Scan(&c.ID, &c.CreatedAt)
+}
+
+func (r *oauthClientRepository) GetByID(ctx context.Context, id string) (*model.OAuthClient, error) {
+	var c model.OAuthClient
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		SELECT id, name, COALESCE(secret_hash, ''), scope, public, created_at
+		FROM oauth_clients WHERE id = $1`, id,
+	).Scan(&c.ID, &c.Name, &c.SecretHash, &c.Scope, &c.Public, &c.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *oauthClientRepository) Delete(ctx context.Context, id string) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM oauth_clients WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/model"
+)
+
+// QRLoginRepository persists pending cross-device logins.
+type QRLoginRepository interface {
+	Create(ctx context.Context, login *model.QRLogin) error
+	GetByPollTokenHash(ctx context.Context, pollTokenHash string) (*model.QRLogin, error)
+	GetByCodeHash(ctx context.Context, codeHash string) (*model.QRLogin, error)
+	// Approve assigns the login to userID. It returns false if the code is
+	// unknown, expired or already approved.
+	Approve(ctx context.Context, codeHash, userID string, at time.Time) (bool, error)
+	// Complete marks the approved login as exchanged for tokens. It returns
+	// false if it already was.
+	Complete(ctx context.Context, id string, at time.Time) (bool, error)
+	// DeleteExpired removes logins that expired before the given time and
+	// returns how many it removed.
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+type qrLoginRepository struct {
+	db *sql.DB
+}
+
+// NewQRLoginRepository creates a Postgres-backed QRLoginRepository.
+func NewQRLoginRepository(db *sql.DB) QRLoginRepository {
+	return &qrLoginRepository{db: db}
+}
+
+func (r *qrLoginRepository) Create(ctx context.Context, l *model.QRLogin) error {
+	return conn(ctx, r.db).QueryRowContext(ctx, `
+		INSERT INTO qr_logins (code_hash, poll_token_hash, expires_at, user_agent, client_ip)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`,
+		l.CodeHash, l.PollTokenHash, l.ExpiresAt, l.UserAgent, l.ClientIP,
+	).Scan(&l.ID, &l.CreatedAt)
+}
+
+const qrLoginColumns = `id, code_hash, poll_token_hash, user_id, expires_at, approved_at, completed_at, user_agent, client_ip, created_at`
+
+func (r *qrLoginRepository) GetByPollTokenHash(ctx context.Context, pollTokenHash string) (*model.QRLogin, error) {
+	return r.getOne(ctx, `SELECT `+qrLoginColumns+` FROM qr_logins WHERE poll_token_hash = $1`, pollTokenHash)
+}
+
+func (r *qrLoginRepository) GetByCodeHash(ctx context.Context, codeHash string) (*model.QRLogin, error) {
+	return r.getOne(ctx, `SELECT `+qrLoginColumns+` FROM qr_logins WHERE code_hash = $1`, codeHash)
+}
+
+func (r *qrLoginRepository) getOne(ctx context.Context, query string, args ...any) (*model.QRLogin, error) {
+	var l model.QRLogin
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, args...).Scan(
+		&l.ID, &l.CodeHash, &l.PollTokenHash, &l.UserID, &l.ExpiresAt, &l.ApprovedAt, &l.CompletedAt,
+		&l.UserAgent, &l.ClientIP, &l.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (r *qrLoginRepository) Approve(ctx context.Context, codeHash, userID string, at time.Time) (bool, error) {
+	res, err := conn(ctx, r.db).ExecContext(ctx, `
+		UPDATE qr_logins SET user_id = $1, approved_at = $2
+		WHERE code_hash = $3 AND approved_at IS NULL AND expires_at > $2`,
+		userID, at, codeHash)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (r *qrLoginRepository) Complete(ctx context.Context, id string, at time.Time) (bool, error) {
+	res, err := conn(ctx, r.db).ExecContext(ctx,
+		`UPDATE qr_logins SET completed_at = $1 WHERE id = $2 AND approved_at IS NOT NULL AND completed_at IS NULL`, at, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (r *qrLoginRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	res, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM qr_logins WHERE expires_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// RecoveryCodeRepository persists the hashes of users' MFA recovery codes.
+type RecoveryCodeRepository interface {
+	// Replace deletes all of the user's codes and stores codeHashes instead.
+	Replace(ctx context.Context, userID string, codeHashes []string) error
+	// Use consumes an unused code. It returns false if the user has no such
+	// unused code.
+	Use(ctx context.Context, userID, codeHash string, at time.Time) (bool, error)
+	CountUnused(ctx context.Context, userID string) (int, error)
+}
+
+type recoveryCodeRepository struct {
+	db *sql.DB
+}
+
+// NewRecoveryCodeRepository creates a Postgres-backed RecoveryCodeRepository.
+func NewRecoveryCodeRepository(db *sql.DB) RecoveryCodeRepository {
+	return &recoveryCodeRepository{db: db}
+}
+
+func (r *recoveryCodeRepository) Replace(ctx context.Context, userID string, codeHashes []string) error {
+	return inTx(ctx, r.db, func(ctx context.Context) error {
+		tx := conn(ctx, r.db)
+		if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+			return err
+		}
+		for _, h := range codeHashes {
+			if _, err := tx.ExecContext(ctx,
+				`INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, h); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *recoveryCodeRepository) Use(ctx context.Context, userID, codeHash string, at time.Time) (bool, error) {
+	res, err := conn(ctx, r.db).ExecContext(ctx, `
+		UPDATE mfa_recovery_codes SET used_at = $1
+		WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL`,
+		at, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (r *recoveryCodeRepository) CountUnused(ctx context.Context, userID string) (int, error) {
+	var n int
+	err := conn(ctx, r.db).QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM mfa_recovery_codes WHERE user_id = $1 AND used_at IS NULL`, userID).Scan(&n)
+	return n, err
+}
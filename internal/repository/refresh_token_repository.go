@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/model"
+)
+
+// RefreshTokenRepository persists refresh tokens and their rotation state.
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *model.RefreshToken) error
+	GetByHash(ctx context.Context, tokenHash string) (*model.RefreshToken, error)
+	// MarkRotated flags the token as exchanged. It returns false if the token
+	// had already been rotated, which means it is being replayed.
+	MarkRotated(ctx context.Context, id string, at time.Time) (bool, error)
+	RevokeFamily(ctx context.Context, familyID string, at time.Time) error
+	// RevokeAllForUser revokes every refresh token of the user, ending all
+	// their sessions.
+	RevokeAllForUser(ctx context.Context, userID string, at time.Time) error
+	// ListSessions returns the user's sessions that are still usable at now,
+	// most recently used first.
+	ListSessions(ctx context.Context, userID string, now time.Time) ([]*model.Session, error)
+	// RevokeUserFamily revokes a family only if it belongs to the user. It
+	// returns false if the user has no unrevoked tokens in that family.
+	RevokeUserFamily(ctx context.Context, userID, familyID string, at time.Time) (bool, error)
+}
+
+type refreshTokenRepository struct {
+	db *sql.DB
+}
+
+// NewRefreshTokenRepository creates a Postgres-backed RefreshTokenRepository.
+func NewRefreshTokenRepository(db *sql.DB) RefreshTokenRepository {
+	return &refreshTokenRepository{db: db}
+}
+
+func (r *refreshTokenRepository) Create(ctx context.Context, t *model.RefreshToken) error {
+	return conn(ctx, r.db).QueryRowContext(ctx, `
+		INSERT INTO refresh_tokens (user_id, family_id, token_hash, user_agent, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`,
+		t.UserID, t.FamilyID, t.TokenHash, t.UserAgent, t.ExpiresAt,
+	).Scan(&t.ID, &t.CreatedAt)
+}
+
+func (r *refreshTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*model.RefreshToken, error) {
+	var t model.RefreshToken
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		SELECT id, user_id, family_id, token_hash, user_agent, expires_at, rotated_at, revoked_at, created_at
+		FROM refresh_tokens WHERE token_hash = $1`, tokenHash,
+	).Scan(&t.ID, &t.UserID, &t.FamilyID, &t.TokenHash, &t.UserAgent, &t.ExpiresAt, &t.RotatedAt, &t.RevokedAt, &t.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *refreshTokenRepository) MarkRotated(ctx context.Context, id string, at time.Time) (bool, error) {
+	res, err := conn(ctx, r.db).ExecContext(ctx,
+		`UPDATE refresh_tokens SET rotated_at = $1 WHERE id = $2 AND rotated_at IS NULL`, at, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (r *refreshTokenRepository) RevokeFamily(ctx context.Context, familyID string, at time.Time) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx,
+		`UPDATE refresh_tokens SET revoked_at = $1 WHERE family_id = $2 AND revoked_at IS NULL`, at, familyID)
+	return err
+}
+
+func (r *refreshTokenRepository) RevokeAllForUser(ctx context.Context, userID string, at time.Time) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx,
+		`UPDATE refresh_tokens SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL`, at, userID)
+	return err
+}
+
+// ListSessions reads each session from the family's current token: the one
+// that has been neither rotated nor revoked.
+func (r *refreshTokenRepository) ListSessions(ctx context.Context, userID string, now time.Time) ([]*model.Session, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT t.family_id, t.user_agent,
+			(SELECT MIN(f.created_at) FROM refresh_tokens f WHERE f.family_id = t.family_id),
+			t.created_at, t.expires_at
+		FROM refresh_tokens t
+		WHERE t.user_id = $1 AND t.rotated_at IS NULL AND t.revoked_at IS NULL AND t.expires_at > $2
+		ORDER BY t.created_at DESC`, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []*model.Session{}
+	for rows.Next() {
+		var s model.Session
+		if err := rows.Scan(&s.ID, &s.UserAgent, &s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, &s)
+	}
+	return sessions, rows.Err()
+}
+
+func (r *refreshTokenRepository) RevokeUserFamily(ctx context.Context, userID, familyID string, at time.Time) (bool, error) {
+	res, err := conn(ctx, r.db).ExecContext(ctx,
+		`UPDATE refresh_tokens SET revoked_at = $1 WHERE user_id = $2 AND family_id = $3 AND revoked_at IS NULL`, at, userID, familyID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
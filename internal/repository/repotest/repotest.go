@@ -0,0 +1,507 @@
+// Package repotest provides in-memory repositories for tests. The zero
+// value of each is empty and ready to use. Users and RefreshTokens only
+// implement the methods tests have needed so far; the others panic on the
+// embedded nil interface.
+package repotest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/model"
+	"github.com/SarathLUN/go-auth-service/internal/repository"
+	"github.com/SarathLUN/go-auth-service/internal/util"
+)
+
+var (
+	_ repository.UserRepository           = (*Users)(nil)
+	_ repository.FailedLoginRepository    = (*FailedLogins)(nil)
+	_ repository.RefreshTokenRepository   = (*RefreshTokens)(nil)
+	_ repository.ActionTokenRepository    = (*ActionTokens)(nil)
+	_ repository.RevokedTokenRepository   = (*RevokedTokens)(nil)
+	_ repository.TOTPCredentialRepository = (*TOTPCredentials)(nil)
+	_ repository.RecoveryCodeRepository   = (*RecoveryCodes)(nil)
+	_ repository.MFAChallengeRepository   = (*MFAChallenges)(nil)
+	_ repository.QRLoginRepository        = (*QRLogins)(nil)
+	_ repository.UnitOfWork               = Tx{}
+)
+
+// Users is an in-memory repository.UserRepository.
+type Users struct {
+	repository.UserRepository
+	mu    sync.Mutex
+	users map[string]*model.User
+}
+
+func (f *Users) Create(_ context.Context, u *model.User) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, x := range f.users {
+		if x.EmailNormalized == u.EmailNormalized {
+			return repository.ErrDuplicateEmail
+		}
+	}
+	if f.users == nil {
+		f.users = make(map[string]*model.User)
+	}
+	u.ID = util.NewID()
+	c := *u
+	f.users[u.ID] = &c
+	return nil
+}
+
+func (f *Users) GetByID(_ context.Context, id string) (*model.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	u, ok := f.users[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	c := *u
+	return &c, nil
+}
+
+func (f *Users) GetByEmail(_ context.Context, email string) (*model.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, u := range f.users {
+		if u.EmailNormalized == email {
+			c := *u
+			return &c, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (f *Users) update(id string, fn func(*model.User)) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	u, ok := f.users[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	fn(u)
+	return nil
+}
+
+func (f *Users) Activate(_ context.Context, id string) error {
+	return f.update(id, func(u *model.User) { u.IsActive = true })
+}
+
+func (f *Users) SetPasswordHash(_ context.Context, id, hash string) error {
+	return f.update(id, func(u *model.User) { u.PasswordHash = hash })
+}
+
+func (f *Users) Lock(_ context.Context, id string, at, until time.Time) error {
+	return f.update(id, func(u *model.User) { u.LockedUntil, u.FailedLoginsResetAt = &until, &at })
+}
+
+func (f *Users) Unlock(_ context.Context, id string, at time.Time) error {
+	return f.update(id, func(u *model.User) { u.LockedUntil, u.FailedLoginsResetAt = nil, &at })
+}
+
+// FailedLogins is an in-memory repository.FailedLoginRepository.
+type FailedLogins struct {
+	mu sync.Mutex
+	at map[string][]time.Time
+}
+
+func (f *FailedLogins) Record(_ context.Context, userID, _ string, at time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.at == nil {
+		f.at = make(map[string][]time.Time)
+	}
+	f.at[userID] = append(f.at[userID], at)
+	return nil
+}
+
+func (f *FailedLogins) CountSince(_ context.Context, userID string, since time.Time) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, at := range f.at[userID] {
+		if at.After(since) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// RefreshTokens is an in-memory repository.RefreshTokenRepository.
+type RefreshTokens struct {
+	repository.RefreshTokenRepository
+	mu     sync.Mutex
+	tokens []*model.RefreshToken
+}
+
+func (f *RefreshTokens) Create(_ context.Context, t *model.RefreshToken) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t.ID = util.NewID()
+	c := *t
+	f.tokens = append(f.tokens, &c)
+	return nil
+}
+
+func (f *RefreshTokens) GetByHash(_ context.Context, hash string) (*model.RefreshToken, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, t := range f.tokens {
+		if t.TokenHash == hash {
+			c := *t
+			return &c, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (f *RefreshTokens) MarkRotated(_ context.Context, id string, at time.Time) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, t := range f.tokens {
+		if t.ID == id && t.RotatedAt == nil {
+			t.RotatedAt = &at
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *RefreshTokens) RevokeFamily(_ context.Context, familyID string, at time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, t := range f.tokens {
+		if t.FamilyID == familyID && t.RevokedAt == nil {
+			t.RevokedAt = &at
+		}
+	}
+	return nil
+}
+
+func (f *RefreshTokens) RevokeAllForUser(_ context.Context, userID string, at time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, t := range f.tokens {
+		if t.UserID == userID && t.RevokedAt == nil {
+			t.RevokedAt = &at
+		}
+	}
+	return nil
+}
+
+// ActionTokens is an in-memory repository.ActionTokenRepository.
+type ActionTokens struct {
+	mu     sync.Mutex
+	tokens []*model.ActionToken
+}
+
+func (f *ActionTokens) Create(_ context.Context, t *model.ActionToken) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t.ID = util.NewID()
+	c := *t
+	f.tokens = append(f.tokens, &c)
+	return nil
+}
+
+func (f *ActionTokens) GetByHash(_ context.Context, hash string) (*model.ActionToken, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, t := range f.tokens {
+		if t.TokenHash == hash {
+			c := *t
+			return &c, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (f *ActionTokens) MarkUsed(_ context.Context, id string, at time.Time) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, t := range f.tokens {
+		if t.ID == id && t.UsedAt == nil {
+			t.UsedAt = &at
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *ActionTokens) MarkClicked(_ context.Context, id string, at time.Time) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, t := range f.tokens {
+		if t.ID == id && t.ClickedAt == nil && t.UsedAt == nil {
+			t.ClickedAt = &at
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// RevokedTokens is an in-memory repository.RevokedTokenRepository.
+type RevokedTokens struct {
+	mu      sync.Mutex
+	expires map[string]time.Time
+}
+
+func (f *RevokedTokens) Revoke(_ context.Context, jti, _ string, expiresAt time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.expires == nil {
+		f.expires = make(map[string]time.Time)
+	}
+	if _, ok := f.expires[jti]; !ok {
+		f.expires[jti] = expiresAt
+	}
+	return nil
+}
+
+func (f *RevokedTokens) IsRevoked(_ context.Context, jti string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.expires[jti]
+	return ok, nil
+}
+
+func (f *RevokedTokens) DeleteExpired(_ context.Context, before time.Time) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var n int64
+	for jti, exp := range f.expires {
+		if exp.Before(before) {
+			delete(f.expires, jti)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// TOTPCredentials is an in-memory repository.TOTPCredentialRepository.
+type TOTPCredentials struct {
+	mu    sync.Mutex
+	creds map[string]*model.TOTPCredential
+}
+
+func (f *TOTPCredentials) SavePending(_ context.Context, cred *model.TOTPCredential) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.creds == nil {
+		f.creds = make(map[string]*model.TOTPCredential)
+	}
+	if c, ok := f.creds[cred.UserID]; ok && c.ConfirmedAt != nil {
+		return false, nil
+	}
+	c := *cred
+	f.creds[cred.UserID] = &c
+	return true, nil
+}
+
+func (f *TOTPCredentials) Confirm(_ context.Context, userID string, at time.Time) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, ok := f.creds[userID]
+	if !ok || c.ConfirmedAt != nil {
+		return false, nil
+	}
+	c.ConfirmedAt = &at
+	return true, nil
+}
+
+func (f *TOTPCredentials) GetByUserID(_ context.Context, userID string) (*model.TOTPCredential, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	cred, ok := f.creds[userID]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	c := *cred
+	return &c, nil
+}
+
+func (f *TOTPCredentials) UseStep(_ context.Context, userID string, step int64) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	cred, ok := f.creds[userID]
+	if !ok || step <= cred.LastUsedStep {
+		return false, nil
+	}
+	cred.LastUsedStep = step
+	return true, nil
+}
+
+// RecoveryCodes is an in-memory repository.RecoveryCodeRepository.
+type RecoveryCodes struct {
+	mu     sync.Mutex
+	hashes map[string]map[string]bool // user ID -> code hash -> used
+}
+
+func (f *RecoveryCodes) Replace(_ context.Context, userID string, codeHashes []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.hashes == nil {
+		f.hashes = make(map[string]map[string]bool)
+	}
+	f.hashes[userID] = map[string]bool{}
+	for _, h := range codeHashes {
+		f.hashes[userID][h] = false
+	}
+	return nil
+}
+
+func (f *RecoveryCodes) Use(_ context.Context, userID, codeHash string, _ time.Time) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	used, ok := f.hashes[userID][codeHash]
+	if !ok || used {
+		return false, nil
+	}
+	f.hashes[userID][codeHash] = true
+	return true, nil
+}
+
+func (f *RecoveryCodes) CountUnused(_ context.Context, userID string) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, used := range f.hashes[userID] {
+		if !used {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// MFAChallenges is an in-memory repository.MFAChallengeRepository.
+type MFAChallenges struct {
+	mu         sync.Mutex
+	challenges []*model.MFAChallenge
+}
+
+func (f *MFAChallenges) Create(_ context.Context, c *model.MFAChallenge) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c.ID = util.NewID()
+	cp := *c
+	f.challenges = append(f.challenges, &cp)
+	return nil
+}
+
+func (f *MFAChallenges) GetByHash(_ context.Context, hash string) (*model.MFAChallenge, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range f.challenges {
+		if c.TokenHash == hash {
+			cp := *c
+			return &cp, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (f *MFAChallenges) RecordFailure(_ context.Context, id string) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range f.challenges {
+		if c.ID == id {
+			c.Attempts++
+			return c.Attempts, nil
+		}
+	}
+	return 0, repository.ErrNotFound
+}
+
+func (f *MFAChallenges) MarkUsed(_ context.Context, id string, at time.Time) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range f.challenges {
+		if c.ID == id && c.UsedAt == nil {
+			c.UsedAt = &at
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// QRLogins is an in-memory repository.QRLoginRepository.
+type QRLogins struct {
+	mu     sync.Mutex
+	logins []*model.QRLogin
+}
+
+func (f *QRLogins) Create(_ context.Context, l *model.QRLogin) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	l.ID = util.NewID()
+	c := *l
+	f.logins = append(f.logins, &c)
+	return nil
+}
+
+func (f *QRLogins) find(match func(*model.QRLogin) bool) (*model.QRLogin, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, l := range f.logins {
+		if match(l) {
+			c := *l
+			return &c, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (f *QRLogins) GetByPollTokenHash(_ context.Context, hash string) (*model.QRLogin, error) {
+	return f.find(func(l *model.QRLogin) bool { return l.PollTokenHash == hash })
+}
+
+func (f *QRLogins) GetByCodeHash(_ context.Context, hash string) (*model.QRLogin, error) {
+	return f.find(func(l *model.QRLogin) bool { return l.CodeHash == hash })
+}
+
+func (f *QRLogins) Approve(_ context.Context, codeHash, userID string, at time.Time) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, l := range f.logins {
+		if l.CodeHash == codeHash && l.ApprovedAt == nil && l.ExpiresAt.After(at) {
+			l.UserID, l.ApprovedAt = &userID, &at
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *QRLogins) Complete(_ context.Context, id string, at time.Time) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, l := range f.logins {
+		if l.ID == id && l.ApprovedAt != nil && l.CompletedAt == nil {
+			l.CompletedAt = &at
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *QRLogins) DeleteExpired(_ context.Context, before time.Time) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	kept := f.logins[:0]
+	for _, l := range f.logins {
+		if !l.ExpiresAt.Before(before) {
+			kept = append(kept, l)
+		}
+	}
+	n := int64(len(f.logins) - len(kept))
+	f.logins = kept
+	return n, nil
+}
+
+// Tx runs fn without a transaction; the repositories apply changes directly.
+type Tx struct{}
+
+func (Tx) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// RevokedTokenRepository records access tokens that were invalidated before
+// they expired, keyed by their jti claim.
+type RevokedTokenRepository interface {
+	// Revoke adds jti to the revocation list. Revoking a token twice is not an error.
+	Revoke(ctx context.Context, jti string, userID string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+	// DeleteExpired removes entries for tokens that expired before the given
+	// time, which are rejected anyway, and returns how many it removed.
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+type revokedTokenRepository struct {
+	db *sql.DB
+}
+
+// NewRevokedTokenRepository creates a Postgres-backed RevokedTokenRepository.
+func NewRevokedTokenRepository(db *sql.DB) RevokedTokenRepository {
+	return &revokedTokenRepository{db: db}
+}
+
+func (r *revokedTokenRepository) Revoke(ctx context.Context, jti string, userID string, expiresAt time.Time) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `
+		INSERT INTO revoked_tokens (jti, user_id, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (jti) DO NOTHING`,
+		jti, userID, expiresAt)
+	return err
+}
+
+// IsRevoked is on the path of every authenticated request, so it is a single
+// primary key lookup.
+func (r *revokedTokenRepository) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	var revoked bool
+	err := conn(ctx, r.db).QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)`, jti).Scan(&revoked)
+	return revoked, err
+}
+
+func (r *revokedTokenRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	res, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM revoked_tokens WHERE expires_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
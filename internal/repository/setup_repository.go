@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/SarathLUN/go-auth-service/internal/model"
+)
+
+// SetupRepository records the first-run setup of an installation.
+type SetupRepository interface {
+	IsComplete(ctx context.Context) (bool, error)
+	// Complete creates admin and marks setup as done, atomically. It returns
+	// false without creating the user if setup was already completed. Like
+	// UserRepository.Create it returns ErrDuplicateEmail or
+	// ErrDuplicateUsername on conflicts.
+	Complete(ctx context.Context, admin *model.User) (bool, error)
+}
+
+type setupRepository struct {
+	db *sql.DB
+}
+
+// NewSetupRepository creates a Postgres-backed SetupRepository.
+func NewSetupRepository(db *sql.DB) SetupRepository {
+	return &setupRepository{db: db}
+}
+
+func (r *setupRepository) IsComplete(ctx context.Context) (bool, error) {
+	var done bool
+	err := conn(ctx, r.db).QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM setup_completion)`).Scan(&done)
+	return done, err
+}
+
+func (r *setupRepository) Complete(ctx context.Context, u *model.User) (bool, error) {
+	var done bool
+	err := inTx(ctx, r.db, func(ctx context.Context) error {
+		tx := conn(ctx, r.db)
+
+		// Claiming the row first makes a concurrent setup wait for this one
+		// and then find it done.
+		res, err := tx.ExecContext(ctx, `INSERT INTO setup_completion (singleton) VALUES (TRUE) ON CONFLICT DO NOTHING`)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			return err
+		}
+
+		err = tx.QueryRowContext(ctx, `
+			INSERT INTO users (username, email, email_normalized, password_hash, is_active, role, locale, timezone)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING id, created_at, updated_at`,
+			u.Username, u.Email, u.EmailNormalized, u.PasswordHash, u.IsActive, u.Role, u.Locale, u.Timezone,
+		).Scan(&u.ID, &u.CreatedAt, &u.UpdatedAt)
+		if err != nil {
+			return TranslateError(err)
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE setup_completion SET admin_user_id = $1`, u.ID); err != nil {
+			return err
+		}
+		done = true
+		return nil
+	})
+	return done && err == nil, err
+}
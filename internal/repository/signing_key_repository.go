@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/model"
+)
+
+// SigningKeyRepository persists JWT signing keys for rotation.
+type SigningKeyRepository interface {
+	// Create stores the first key. It does nothing if any key already exists,
+	// so instances starting concurrently don't each create one.
+	Create(ctx context.Context, key *model.SigningKey) error
+	// ListUsable returns keys that are not retired or were retired after
+	// retiredAfter, newest first.
+	ListUsable(ctx context.Context, retiredAfter time.Time) ([]*model.SigningKey, error)
+	// Rotate stores key and retires every other unretired key at retireAt.
+	Rotate(ctx context.Context, key *model.SigningKey, retireAt time.Time) error
+}
+
+type signingKeyRepository struct {
+	db *sql.DB
+}
+
+// NewSigningKeyRepository creates a Postgres-backed SigningKeyRepository.
+func NewSigningKeyRepository(db *sql.DB) SigningKeyRepository {
+	return &signingKeyRepository{db: db}
+}
+
+func (r *signingKeyRepository) Create(ctx context.Context, k *model.SigningKey) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `
+		INSERT INTO signing_keys (kid, algorithm, private_key, created_at)
+		SELECT $1, $2, $3, $4
+		WHERE NOT EXISTS (SELECT 1 FROM signing_keys)`,
+		k.KeyID, k.Algorithm, k.PrivateKey, k.CreatedAt)
+	return err
+}
+
+func (r *signingKeyRepository) ListUsable(ctx context.Context, retiredAfter time.Time) ([]*model.SigningKey, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT kid, algorithm, private_key, created_at, retired_at
+		FROM signing_keys
+		WHERE retired_at IS NULL OR retired_at > $1
+		ORDER BY created_at DESC`, retiredAfter)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*model.SigningKey
+	for rows.Next() {
+		var k model.SigningKey
+		if err := rows.Scan(&k.KeyID, &k.Algorithm, &k.PrivateKey, &k.CreatedAt, &k.RetiredAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, &k)
+	}
+	return keys, rows.Err()
+}
+
+func (r *signingKeyRepository) Rotate(ctx context.Context, k *model.SigningKey, retireAt time.Time) error {
+	return inTx(ctx, r.db, func(ctx context.Context) error {
+		tx := conn(ctx, r.db)
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE signing_keys SET retired_at = $1 WHERE retired_at IS NULL`, retireAt); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO signing_keys (kid, algorithm, private_key, created_at)
+			VALUES ($1, $2, $3, $4)`,
+			k.KeyID, k.Algorithm, k.PrivateKey, k.CreatedAt)
+		return err
+	})
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/model"
+)
+
+// TOTPCredentialRepository persists users' TOTP enrollments.
+type TOTPCredentialRepository interface {
+	// SavePending stores a new unconfirmed secret for the user, replacing an
+	// earlier unconfirmed one. It returns false if the user already has a
+	// confirmed credential.
+	SavePending(ctx context.Context, cred *model.TOTPCredential) (bool, error)
+	GetByUserID(ctx context.Context, userID string) (*model.TOTPCredential, error)
+	// Confirm marks the credential as confirmed. It returns false if it
+	// already was.
+	Confirm(ctx context.Context, userID string, at time.Time) (bool, error)
+	// UseStep records step as the last accepted time step. It returns false
+	// if a code from the same or a later step was already accepted.
+	UseStep(ctx context.Context, userID string, step int64) (bool, error)
+}
+
+type totpCredentialRepository struct {
+	db *sql.DB
+}
+
+// NewTOTPCredentialRepository creates a Postgres-backed TOTPCredentialRepository.
+func NewTOTPCredentialRepository(db *sql.DB) TOTPCredentialRepository {
+	return &totpCredentialRepository{db: db}
+}
+
+func (r *totpCredentialRepository) SavePending(ctx context.Context, c *model.TOTPCredential) (bool, error) {
+	res, err := conn(ctx, r.db).ExecContext(ctx, `
+		INSERT INTO totp_credentials (user_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_used_step = 0, created_at = CURRENT_TIMESTAMP
+		WHERE totp_credentials.confirmed_at IS NULL`,
+		c.UserID, c.Secret)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (r *totpCredentialRepository) GetByUserID(ctx context.Context, userID string) (*model.TOTPCredential, error) {
+	var c model.TOTPCredential
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		SELECT user_id, secret, confirmed_at, last_used_step, created_at
+		FROM totp_credentials WHERE user_id = $1`, userID,
+	).Scan(&c.UserID, &c.Secret, &c.ConfirmedAt, &c.LastUsedStep, &c.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *totpCredentialRepository) Confirm(ctx context.Context, userID string, at time.Time) (bool, error) {
+	res, err := conn(ctx, r.db).ExecContext(ctx,
+		`UPDATE totp_credentials SET confirmed_at = $1 WHERE user_id = $2 AND confirmed_at IS NULL`, at, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (r *totpCredentialRepository) UseStep(ctx context.Context, userID string, step int64) (bool, error) {
+	res, err := conn(ctx, r.db).ExecContext(ctx,
+		`UPDATE totp_credentials SET last_used_step = $1 WHERE user_id = $2 AND last_used_step < $1`, step, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+// DBTX is what repositories run their queries on: the *sql.DB, or the
+// *sql.Tx of the unit of work in progress.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// UnitOfWork groups repository calls into one transaction, so that a service
+// operation either takes effect as a whole or not at all.
+type UnitOfWork interface {
+	// Do calls fn with a context carrying a transaction. Repository calls
+	// made with that context run in the transaction, which is committed if
+	// fn returns nil and rolled back otherwise. Do within another Do joins
+	// the outer transaction.
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type txKey struct{}
+
+type unitOfWork struct {
+	db *sql.DB
+}
+
+// NewUnitOfWork creates a UnitOfWork running transactions on db, which must
+// be the database the repositories were created with.
+func NewUnitOfWork(db *sql.DB) UnitOfWork {
+	return &unitOfWork{db: db}
+}
+
+func (u *unitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	return inTx(ctx, u.db, fn)
+}
+
+// inTx runs fn in the transaction carried by ctx, or in a new one on db if
+// there is none.
+func inTx(ctx context.Context, db *sql.DB, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// conn returns the transaction carried by ctx, or db outside a unit of work.
+func conn(ctx context.Context, db *sql.DB) DBTX {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return db
+}
@@ -0,0 +1,255 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/model"
+)
+
+// UserRepository persists users.
+type UserRepository interface {
+	Create(ctx context.Context, user *model.User) error
+	GetByID(ctx context.Context, id string) (*model.User, error)
+	GetByEmail(ctx context.Context, normalizedEmail string) (*model.User, error)
+	GetByExternalID(ctx context.Context, externalID string) (*model.User, error)
+	List(ctx context.Context, q UserListQuery) ([]*model.User, error)
+	// SetExternalID links the user to an ID in a legacy system; nil clears it.
+	SetExternalID(ctx context.Context, id string, externalID *string) error
+	Activate(ctx context.Context, id string) error
+	Deactivate(ctx context.Context, id string) error
+	SetPasswordHash(ctx context.Context, id, passwordHash string) error
+	ReplacePasswordHash(ctx context.Context, id, oldHash, newHash string) error
+	SetPreferences(ctx context.Context, id, locale, timezone string) error
+	Lock(ctx context.Context, id string, at, until time.Time) error
+	Unlock(ctx context.Context, id string, at time.Time) error
+	Delete(ctx context.Context, id string) error
+}
+
+type userRepository struct {
+	db *sql.DB
+}
+
+// NewUserRepository creates a Postgres-backed UserRepository.
+func NewUserRepository(db *sql.DB) UserRepository {
+	return &userRepository{db: db}
+}
+
+// UserSort is a column the user list can be ordered by.
+type UserSort string
+
+// Orders of the user list. Ties are broken by ID, so the order is total and
+// a listing can be resumed from any row.
+const (
+	UserSortCreatedAt UserSort = "created_at"
+	UserSortEmail     UserSort = "email"
+	UserSortUsername  UserSort = "username"
+)
+
+// UserListQuery selects a page of users. Zero fields do not filter.
+type UserListQuery struct {
+	IsActive *bool
+	// Email matches a case-insensitive substring of the email address.
+	Email string
+	// CreatedFrom is inclusive, CreatedBefore exclusive.
+	CreatedFrom   time.Time
+	CreatedBefore time.Time
+
+	Sort       UserSort
+	Descending bool
+	// After resumes the listing behind the row with this sort value and ID.
+	// The value is a time.Time for UserSortCreatedAt and a string otherwise.
+	After *UserCursor
+	Limit int
+}
+
+// UserCursor is the position of a row in a sorted user list.
+type UserCursor struct {
+	Value any
+	ID    string
+}
+
+const userColumns = `id, username, email, email_normalized, password_hash, external_id, is_active, role, locale, timezone, created_at, updated_at, locked_until, failed_logins_reset_at`
+
+// Create inserts a new user and fills in the generated fields.
+// It returns ErrDuplicateEmail or ErrDuplicateUsername on conflicts.
+func (r *userRepository) Create(ctx context.Context, user *model.User) error {
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		INSERT INTO users (username, email, email_normalized, password_hash, is_active, role, locale, timezone)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at, updated_at`,
+		user.Username, user.Email, user.EmailNormalized, user.PasswordHash, user.IsActive, user.Role, user.Locale, user.Timezone,
+	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+	return TranslateError(err)
+}
+
+// GetByID returns the user with the given ID or ErrNotFound.
+func (r *userRepository) GetByID(ctx context.Context, id string) (*model.User, error) {
+	return r.getOne(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1`, id)
+}
+
+// GetByEmail looks a user up by canonical email address or returns ErrNotFound.
+func (r *userRepository) GetByEmail(ctx context.Context, normalizedEmail string) (*model.User, error) {
+	return r.getOne(ctx, `SELECT `+userColumns+` FROM users WHERE email_normalized = $1`, normalizedEmail)
+}
+
+// GetByExternalID looks a user up by their legacy system ID or returns ErrNotFound.
+func (r *userRepository) GetByExternalID(ctx context.Context, externalID string) (*model.User, error) {
+	return r.getOne(ctx, `SELECT `+userColumns+` FROM users WHERE external_id = $1`, externalID)
+}
+
+// List returns the users matching q in the requested order.
+func (r *userRepository) List(ctx context.Context, q UserListQuery) ([]*model.User, error) {
+	var where []string
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if q.IsActive != nil {
+		where = append(where, "is_active = "+arg(*q.IsActive))
+	}
+	if q.Email != "" {
+		where = append(where, `email ILIKE '%' || `+arg(escapeLike(q.Email))+` || '%'`)
+	}
+	if !q.CreatedFrom.IsZero() {
+		where = append(where, "created_at >= "+arg(q.CreatedFrom))
+	}
+	if !q.CreatedBefore.IsZero() {
+		where = append(where, "created_at < "+arg(q.CreatedBefore))
+	}
+
+	column := "created_at"
+	switch q.Sort {
+	case UserSortEmail:
+		column = "email"
+	case UserSortUsername:
+		column = "username"
+	}
+	cmp, dir := ">", "ASC"
+	if q.Descending {
+		cmp, dir = "<", "DESC"
+	}
+	if q.After != nil {
+		where = append(where, "("+column+", id) "+cmp+" ("+arg(q.After.Value)+", "+arg(q.After.ID)+")")
+	}
+
+	query := `SELECT ` + userColumns + ` FROM users`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY " + column + " " + dir + ", id " + dir + " LIMIT " + arg(q.Limit)
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []*model.User{}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// escapeLike escapes the LIKE wildcards in s so it matches literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// SetExternalID returns ErrDuplicateExternalID if another user already has externalID.
+func (r *userRepository) SetExternalID(ctx context.Context, id string, externalID *string) error {
+	return TranslateError(r.execOne(ctx, `UPDATE users SET external_id = $1, updated_at = NOW() WHERE id = $2`, externalID, id))
+}
+
+// Activate marks the user as active.
+func (r *userRepository) Activate(ctx context.Context, id string) error {
+	return r.execOne(ctx, `UPDATE users SET is_active = TRUE, updated_at = NOW() WHERE id = $1`, id)
+}
+
+// Deactivate marks the user as inactive, which blocks login and token refresh.
+func (r *userRepository) Deactivate(ctx context.Context, id string) error {
+	return r.execOne(ctx, `UPDATE users SET is_active = FALSE, updated_at = NOW() WHERE id = $1`, id)
+}
+
+// SetPasswordHash replaces the user's password hash.
+func (r *userRepository) SetPasswordHash(ctx context.Context, id, passwordHash string) error {
+	return r.execOne(ctx, `UPDATE users SET password_hash = $1, updated_at = NOW() WHERE id = $2`, passwordHash, id)
+}
+
+// ReplacePasswordHash swaps oldHash for newHash, a hash of the same password,
+// and returns ErrNotFound if the password changed in the meantime. The
+// password itself is unchanged, so updated_at is left alone.
+func (r *userRepository) ReplacePasswordHash(ctx context.Context, id, oldHash, newHash string) error {
+	return r.execOne(ctx, `UPDATE users SET password_hash = $1 WHERE id = $2 AND password_hash = $3`, newHash, id, oldHash)
+}
+
+// SetPreferences replaces the user's locale and time zone.
+func (r *userRepository) SetPreferences(ctx context.Context, id, locale, timezone string) error {
+	return r.execOne(ctx, `UPDATE users SET locale = $1, timezone = $2, updated_at = NOW() WHERE id = $3`, locale, timezone, id)
+}
+
+// Lock blocks logins until the given time. Failed logins up to at no longer
+// count towards the next lockout.
+func (r *userRepository) Lock(ctx context.Context, id string, at, until time.Time) error {
+	return r.execOne(ctx, `UPDATE users SET locked_until = $1, failed_logins_reset_at = $2 WHERE id = $3`, until, at, id)
+}
+
+// Unlock lifts any lockout. Failed logins up to at no longer count towards
+// the next one.
+func (r *userRepository) Unlock(ctx context.Context, id string, at time.Time) error {
+	return r.execOne(ctx, `UPDATE users SET locked_until = NULL, failed_logins_reset_at = $1 WHERE id = $2`, at, id)
+}
+
+// Delete removes the user and, through foreign keys, everything they own.
+func (r *userRepository) Delete(ctx context.Context, id string) error {
+	return r.execOne(ctx, `DELETE FROM users WHERE id = $1`, id)
+}
+
+// execOne runs a statement expected to affect exactly one row, returning
+// ErrNotFound if it affected none.
+func (r *userRepository) execOne(ctx context.Context, query string, args ...any) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *userRepository) getOne(ctx context.Context, query string, args ...any) (*model.User, error) {
+	u, err := scanUser(conn(ctx, r.db).QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return u, err
+}
+
+// scanUser reads the userColumns of a *sql.Row or *sql.Rows.
+func scanUser(row interface{ Scan(dest ...any) error }) (*model.User, error) {
+	var u model.User
+	err := row.Scan(
+		&u.ID, &u.Username, &u.Email, &u.EmailNormalized, &u.PasswordHash, &u.ExternalID, &u.IsActive, &u.Role, &u.Locale, &u.Timezone, &u.CreatedAt, &u.UpdatedAt,
+		&u.LockedUntil, &u.FailedLoginsResetAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
@@ -0,0 +1,60 @@
+package reputation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"net/url"
+)
+
+const abuseIPDBEndpoint = "https://api.abuseipdb.com/api/v2/check"
+
+// AbuseIPDB is a Provider backed by the AbuseIPDB check API.
+type AbuseIPDB struct {
+	apiKey string
+	client *http.Client
+}
+
+// NewAbuseIPDB creates an AbuseIPDB provider. A nil client uses http.DefaultClient.
+func NewAbuseIPDB(apiKey string, client *http.Client) *AbuseIPDB {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &AbuseIPDB{apiKey: apiKey, client: client}
+}
+
+// Lookup implements Provider.
+func (a *AbuseIPDB) Lookup(ctx context.Context, ip netip.Addr) (Verdict, error) {
+	q := url.Values{}
+	q.Set("ipAddress", ip.Unmap().String())
+	q.Set("maxAgeInDays", "90")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, abuseIPDBEndpoint+"?"+q.Encode(), nil)
+	if err != nil {
+		return Verdict{}, err
+	}
+	req.Header.Set("Key", a.apiKey)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return Verdict{}, fmt.Errorf("abuseipdb lookup: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Verdict{}, fmt.Errorf("abuseipdb lookup: unexpected status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Data struct {
+			AbuseConfidenceScore int `json:"abuseConfidenceScore"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return Verdict{}, fmt.Errorf("abuseipdb lookup: decode response: %w", err)
+	}
+	return Verdict{Score: body.Data.AbuseConfidenceScore, Source: "abuseipdb"}, nil
+}
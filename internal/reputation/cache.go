@@ -0,0 +1,70 @@
+package reputation
+
+import (
+	"context"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/clock"
+)
+
+const maxCacheEntries = 10000
+
+type cacheEntry struct {
+	verdict Verdict
+	expires time.Time
+}
+
+// Cache wraps a Provider and remembers verdicts for a fixed TTL.
+type Cache struct {
+	next  Provider
+	ttl   time.Duration
+	clock clock.Clock
+
+	mu      sync.Mutex
+	entries map[netip.Addr]cacheEntry
+}
+
+// NewCache creates a caching Provider around next. A nil clock uses the system time.
+func NewCache(next Provider, ttl time.Duration, clk clock.Clock) *Cache {
+	if clk == nil {
+		clk = clock.Real{}
+	}
+	return &Cache{next: next, ttl: ttl, clock: clk, entries: make(map[netip.Addr]cacheEntry)}
+}
+
+// Lookup implements Provider. Errors are not cached.
+func (c *Cache) Lookup(ctx context.Context, ip netip.Addr) (Verdict, error) {
+	ip = ip.Unmap()
+	now := c.clock.Now()
+
+	c.mu.Lock()
+	if e, ok := c.entries[ip]; ok && now.Before(e.expires) {
+		c.mu.Unlock()
+		return e.verdict, nil
+	}
+	c.mu.Unlock()
+
+	v, err := c.next.Lookup(ctx, ip)
+	if err != nil {
+		return Verdict{}, err
+	}
+
+	c.mu.Lock()
+	if len(c.entries) >= maxCacheEntries {
+		// Drop expired entries, or everything if none have expired, so the
+		// map doesn't grow forever.
+		for k, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= maxCacheEntries {
+			clear(c.entries)
+		}
+	}
+	c.entries[ip] = cacheEntry{verdict: v, expires: now.Add(c.ttl)}
+	c.mu.Unlock()
+	return v, nil
+}
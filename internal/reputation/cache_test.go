@@ -0,0 +1,64 @@
+package reputation
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/clock"
+)
+
+type countingProvider struct {
+	calls int
+}
+
+func (p *countingProvider) Lookup(_ context.Context, ip netip.Addr) (Verdict, error) {
+	p.calls++
+	return Verdict{Score: int(ip.As4()[3]), Source: "test"}, nil
+}
+
+func TestCacheLookup(t *testing.T) {
+	next := &countingProvider{}
+	clk := clock.NewFake(time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC))
+	c := NewCache(next, time.Hour, clk)
+	ip := netip.MustParseAddr("192.0.2.7")
+
+	for i := 0; i < 2; i++ {
+		v, err := c.Lookup(context.Background(), ip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.Score != 7 {
+			t.Errorf("Score = %d, want 7", v.Score)
+		}
+	}
+	if next.calls != 1 {
+		t.Errorf("provider called %d times, want 1", next.calls)
+	}
+
+	clk.Advance(time.Hour)
+	if _, err := c.Lookup(context.Background(), ip); err != nil {
+		t.Fatal(err)
+	}
+	if next.calls != 2 {
+		t.Errorf("provider called %d times after the TTL, want 2", next.calls)
+	}
+}
+
+func TestCacheBoundedWhenFresh(t *testing.T) {
+	clk := clock.NewFake(time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC))
+	c := NewCache(&countingProvider{}, time.Hour, clk)
+
+	// None of the entries expire, so making room has to drop fresh ones.
+	ip := netip.MustParseAddr("10.0.0.0")
+	for i := 0; i < 2*maxCacheEntries; i++ {
+		if _, err := c.Lookup(context.Background(), ip); err != nil {
+			t.Fatal(err)
+		}
+		if n := len(c.entries); n > maxCacheEntries {
+			t.Fatalf("cache holds %d entries after %d lookups, want at most %d", n, i+1, maxCacheEntries)
+		}
+		ip = ip.Next()
+	}
+}
@@ -0,0 +1,46 @@
+package reputation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/SarathLUN/go-auth-service/internal/config"
+	"github.com/SarathLUN/go-auth-service/internal/outbound"
+)
+
+// NewFromConfig builds the Provider selected by IP_REPUTATION_PROVIDER.
+// It returns a nil Provider when reputation lookups are disabled.
+func NewFromConfig(cfg *config.Config) (Provider, error) {
+	var providers Multi
+	for _, name := range strings.Split(cfg.IPReputationProvider, ",") {
+		switch strings.TrimSpace(strings.ToLower(name)) {
+		case "", "none":
+		case "static":
+			l, err := NewStaticList(strings.Split(cfg.IPReputationBlocklist, ","))
+			if err != nil {
+				return nil, err
+			}
+			providers = append(providers, l)
+		case "abuseipdb":
+			if cfg.AbuseIPDBAPIKey == "" {
+				return nil, fmt.Errorf("ABUSEIPDB_API_KEY is required for the abuseipdb provider")
+			}
+			client, err := outbound.NewHTTPClient(cfg)
+			if err != nil {
+				return nil, err
+			}
+			providers = append(providers, NewAbuseIPDB(cfg.AbuseIPDBAPIKey, client))
+		default:
+			return nil, fmt.Errorf("unknown IP reputation provider %q", name)
+		}
+	}
+
+	switch len(providers) {
+	case 0:
+		return nil, nil
+	case 1:
+		return NewCache(providers[0], cfg.IPReputationCacheTTL, nil), nil
+	default:
+		return NewCache(providers, cfg.IPReputationCacheTTL, nil), nil
+	}
+}
@@ -0,0 +1,50 @@
+package reputation
+
+import (
+	"context"
+	"net/netip"
+)
+
+// Verdict describes what a provider knows about an IP address.
+type Verdict struct {
+	Score  int    // 0 (clean) to 100 (known bad)
+	Source string // provider that produced the score
+}
+
+// IsBad reports whether the verdict meets or exceeds the given threshold.
+func (v Verdict) IsBad(threshold int) bool {
+	return v.Score >= threshold
+}
+
+// Provider looks up the reputation of an IP address.
+type Provider interface {
+	Lookup(ctx context.Context, ip netip.Addr) (Verdict, error)
+}
+
+// Multi queries several providers and returns the worst verdict.
+type Multi []Provider
+
+// Lookup implements Provider. Providers that fail are skipped so that one
+// unreachable provider does not hide a verdict from the others.
+func (m Multi) Lookup(ctx context.Context, ip netip.Addr) (Verdict, error) {
+	var (
+		worst   Verdict
+		lastErr error
+		ok      bool
+	)
+	for _, p := range m {
+		v, err := p.Lookup(ctx, ip)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		ok = true
+		if v.Score > worst.Score {
+			worst = v
+		}
+	}
+	if !ok && lastErr != nil {
+		return Verdict{}, lastErr
+	}
+	return worst, nil
+}
@@ -0,0 +1,49 @@
+package reputation
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// StaticList is a Provider backed by an internal list of known-bad networks.
+type StaticList struct {
+	prefixes []netip.Prefix
+}
+
+// NewStaticList parses a list of CIDRs or bare IP addresses.
+func NewStaticList(entries []string) (*StaticList, error) {
+	l := &StaticList{}
+	for _, e := range entries {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		if !strings.Contains(e, "/") {
+			addr, err := netip.ParseAddr(e)
+			if err != nil {
+				return nil, fmt.Errorf("invalid blocklist entry %q: %w", e, err)
+			}
+			l.prefixes = append(l.prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		p, err := netip.ParsePrefix(e)
+		if err != nil {
+			return nil, fmt.Errorf("invalid blocklist entry %q: %w", e, err)
+		}
+		l.prefixes = append(l.prefixes, p.Masked())
+	}
+	return l, nil
+}
+
+// Lookup implements Provider.
+func (l *StaticList) Lookup(_ context.Context, ip netip.Addr) (Verdict, error) {
+	ip = ip.Unmap()
+	for _, p := range l.prefixes {
+		if p.Contains(ip) {
+			return Verdict{Score: 100, Source: "static"}, nil
+		}
+	}
+	return Verdict{Source: "static"}, nil
+}
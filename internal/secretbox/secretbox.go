@@ -0,0 +1,65 @@
+// Package secretbox encrypts small secrets at rest, such as signing keys and
+// TOTP seeds, with AES-256-GCM.
+package secretbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+)
+
+// KeySize is the size of the encryption key in bytes.
+const KeySize = 32
+
+// ErrDecrypt is returned when a sealed value cannot be opened, either because
+// the key is wrong or because the value was modified.
+var ErrDecrypt = errors.New("secretbox: decryption failed")
+
+// Box seals and opens values under one key.
+type Box struct {
+	aead cipher.AEAD
+}
+
+// New creates a Box from a base64 encoded 32 byte key, e.g. the output of
+// `openssl rand -base64 32`.
+func New(encodedKey string) (*Box, error) {
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil || len(key) != KeySize {
+		return nil, errors.New("encryption key must be 32 random bytes, base64 encoded")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Box{aead: aead}, nil
+}
+
+// Seal encrypts plain, returning nonce || ciphertext. additionalData is
+// authenticated but not stored; pass the same value to Open, typically the
+// ID of the row the secret belongs to so it cannot be moved to another row.
+func (b *Box) Seal(plain, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return b.aead.Seal(nonce, nonce, plain, additionalData), nil
+}
+
+// Open decrypts a value produced by Seal.
+func (b *Box) Open(sealed, additionalData []byte) ([]byte, error) {
+	n := b.aead.NonceSize()
+	if len(sealed) < n {
+		return nil, ErrDecrypt
+	}
+	plain, err := b.aead.Open(nil, sealed[:n], sealed[n:], additionalData)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plain, nil
+}
@@ -0,0 +1,189 @@
+package actiontoken
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/clock"
+	"github.com/SarathLUN/go-auth-service/internal/model"
+	"github.com/SarathLUN/go-auth-service/internal/repository"
+	"github.com/SarathLUN/go-auth-service/internal/util"
+)
+
+const (
+	// PurposeActivation is used for account activation links.
+	PurposeActivation = "activation"
+	// PurposePasswordReset is used for links that let a user choose a new password.
+	PurposePasswordReset = "password_reset"
+
+	// tokenBytes is the entropy of action tokens.
+	tokenBytes = 32
+	// MaxTTL bounds how long an action token can stay valid.
+	MaxTTL = 30 * 24 * time.Hour
+	// MaxPayloadBytes bounds the payload stored with a token.
+	MaxPayloadBytes = 4 << 10
+	// maxSubjectLength matches the action_tokens.subject column.
+	maxSubjectLength = 255
+)
+
+// purposePattern matches the purposes that may be issued; they end up in
+// URLs and logs, so they are kept to a simple alphabet.
+var purposePattern = regexp.MustCompile(`^[a-z][a-z0-9_.-]{0,63}$`)
+
+// reservedPurposes are issued by the service itself and cannot be issued
+// or consumed through the external API.
+var reservedPurposes = map[string]bool{
+	PurposeActivation:    true,
+	PurposePasswordReset: true,
+}
+
+var (
+	// ErrInvalidActionToken is returned for unknown, expired or already used
+	// tokens, and for tokens issued for a different purpose.
+	ErrInvalidActionToken = errors.New("invalid or expired action token")
+	// ErrInvalidPurpose is returned for malformed or reserved purposes.
+	ErrInvalidPurpose = errors.New("purpose must be 1-64 lowercase letters, digits, '_', '.' or '-' and not reserved")
+	// ErrInvalidTTL is returned for TTLs that are not positive or exceed MaxTTL.
+	ErrInvalidTTL = errors.New("ttl must be positive and at most 30 days")
+	// ErrInvalidPayload is returned for oversized payloads or subjects.
+	ErrInvalidPayload = errors.New("payload must be at most 4 KiB of JSON and subject at most 255 characters")
+)
+
+// Deps holds the collaborators of the Service.
+type Deps struct {
+	Tokens repository.ActionTokenRepository
+	Clock  clock.Clock
+}
+
+// Service issues and consumes single-use action tokens.
+type Service struct {
+	tokens repository.ActionTokenRepository
+	clock  clock.Clock
+}
+
+// NewService creates an action token service.
+func NewService(deps Deps) *Service {
+	return &Service{tokens: deps.Tokens, clock: deps.Clock}
+}
+
+// IssueInput describes a token to issue.
+type IssueInput struct {
+	Purpose string
+	Subject string
+	Payload json.RawMessage
+	TTL     time.Duration
+}
+
+// Issue creates a token and returns it with its stored record. The token
+// itself is not stored and cannot be retrieved again.
+func (s *Service) Issue(ctx context.Context, in IssueInput) (string, *model.ActionToken, error) {
+	if !purposePattern.MatchString(in.Purpose) {
+		return "", nil, ErrInvalidPurpose
+	}
+	if in.TTL <= 0 || in.TTL > MaxTTL {
+		return "", nil, ErrInvalidTTL
+	}
+	if len(in.Payload) > MaxPayloadBytes || len(in.Subject) > maxSubjectLength {
+		return "", nil, ErrInvalidPayload
+	}
+
+	token, err := util.GenerateRandomToken(tokenBytes)
+	if err != nil {
+		return "", nil, err
+	}
+	stored := &model.ActionToken{
+		Purpose:   in.Purpose,
+		Subject:   in.Subject,
+		Payload:   in.Payload,
+		TokenHash: util.HashToken(token),
+		ExpiresAt: s.clock.Now().Add(in.TTL),
+	}
+	if err := s.tokens.Create(ctx, stored); err != nil {
+		return "", nil, err
+	}
+	return token, stored, nil
+}
+
+// Consume uses up a token issued for purpose and returns its record.
+func (s *Service) Consume(ctx context.Context, purpose, token string) (*model.ActionToken, error) {
+	stored, err := s.tokens.GetByHash(ctx, util.HashToken(token))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidActionToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	if stored.Purpose != purpose || stored.UsedAt != nil || !now.Before(stored.ExpiresAt) {
+		return nil, ErrInvalidActionToken
+	}
+	used, err := s.tokens.MarkUsed(ctx, stored.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !used {
+		return nil, ErrInvalidActionToken
+	}
+	return stored, nil
+}
+
+// Click records that the emailed link carrying a token issued for one of
+// purposes was followed. The emailed token is used up and replaced by a new
+// token for the same purpose and subject, valid for at most ttl, which is
+// returned with its record. Each link can be followed once, and a copy of it
+// is useless afterwards. Call Click within a transaction, so that the old
+// token is not lost if issuing the new one fails.
+func (s *Service) Click(ctx context.Context, token string, ttl time.Duration, purposes ...string) (string, *model.ActionToken, error) {
+	stored, err := s.tokens.GetByHash(ctx, util.HashToken(token))
+	if errors.Is(err, repository.ErrNotFound) {
+		return "", nil, ErrInvalidActionToken
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := s.clock.Now()
+	if !slices.Contains(purposes, stored.Purpose) || stored.UsedAt != nil || stored.ClickedAt != nil || !now.Before(stored.ExpiresAt) {
+		return "", nil, ErrInvalidActionToken
+	}
+	clicked, err := s.tokens.MarkClicked(ctx, stored.ID, now)
+	if err != nil {
+		return "", nil, err
+	}
+	if !clicked {
+		return "", nil, ErrInvalidActionToken
+	}
+	if _, err := s.tokens.MarkUsed(ctx, stored.ID, now); err != nil {
+		return "", nil, err
+	}
+
+	return s.Issue(ctx, IssueInput{
+		Purpose: stored.Purpose,
+		Subject: stored.Subject,
+		Payload: stored.Payload,
+		TTL:     min(ttl, stored.ExpiresAt.Sub(now)),
+	})
+}
+
+// IssueExternal is Issue for trusted callers, who may not use the purposes
+// the service reserves for itself.
+func (s *Service) IssueExternal(ctx context.Context, in IssueInput) (string, *model.ActionToken, error) {
+	if reservedPurposes[in.Purpose] {
+		return "", nil, ErrInvalidPurpose
+	}
+	return s.Issue(ctx, in)
+}
+
+// ConsumeExternal is Consume for trusted callers. Tokens with a reserved
+// purpose cannot be consumed this way.
+func (s *Service) ConsumeExternal(ctx context.Context, purpose, token string) (*model.ActionToken, error) {
+	if reservedPurposes[purpose] {
+		return nil, ErrInvalidPurpose
+	}
+	return s.Consume(ctx, purpose, token)
+}
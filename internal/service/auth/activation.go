@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/SarathLUN/go-auth-service/internal/model"
+	"github.com/SarathLUN/go-auth-service/internal/repository"
+	"github.com/SarathLUN/go-auth-service/internal/service/actiontoken"
+)
+
+// ErrInvalidActivationToken is returned for unknown, expired or already used activation tokens.
+var ErrInvalidActivationToken = errors.New("invalid or expired activation token")
+
+// sendActivation creates a one-time activation token for the user and emails
+// them a link built from ACTIVATE_BASE_URL, or the link proxy, with its
+// expiry in the user's time zone.
+func (s *Service) sendActivation(ctx context.Context, user *model.User) error {
+	token, stored, err := s.actionTokens.Issue(ctx, actiontoken.IssueInput{
+		Purpose: actiontoken.PurposeActivation,
+		Subject: user.ID,
+		TTL:     s.activationTokenTTL,
+	})
+	if err != nil {
+		return err
+	}
+
+	link, err := s.emailLink("ACTIVATE_BASE_URL", s.activateBaseURL, token)
+	if err != nil {
+		return err
+	}
+	return s.mailer.SendActivationEmail(ctx, user.Email, user.Username, link, stored.ExpiresAt.In(userLocation(user)))
+}
+
+// Activate consumes an activation token and marks its user as active. Both
+// happen in one transaction, so the token is not used up if activating fails.
+func (s *Service) Activate(ctx context.Context, token string) error {
+	return s.tx.Do(ctx, func(ctx context.Context) error {
+		stored, err := s.actionTokens.Consume(ctx, actiontoken.PurposeActivation, token)
+		if errors.Is(err, actiontoken.ErrInvalidActionToken) {
+			return ErrInvalidActivationToken
+		}
+		if err != nil {
+			return err
+		}
+
+		err = s.users.Activate(ctx, stored.Subject)
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrInvalidActivationToken
+		}
+		return err
+	})
+}
+
+// tokenLink appends the token as a query parameter to the base URL, which
+// is configured by the named setting.
+func tokenLink(setting, baseURL, token string) (string, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s: %w", setting, err)
+	}
+	q := u.Query()
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
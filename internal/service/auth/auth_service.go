@@ -0,0 +1,410 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/clock"
+	"github.com/SarathLUN/go-auth-service/internal/config"
+	"github.com/SarathLUN/go-auth-service/internal/jwtkeys"
+	"github.com/SarathLUN/go-auth-service/internal/model"
+	"github.com/SarathLUN/go-auth-service/internal/password"
+	"github.com/SarathLUN/go-auth-service/internal/pwned"
+	"github.com/SarathLUN/go-auth-service/internal/repository"
+	"github.com/SarathLUN/go-auth-service/internal/reputation"
+	"github.com/SarathLUN/go-auth-service/internal/secretbox"
+	"github.com/SarathLUN/go-auth-service/internal/service/actiontoken"
+	"github.com/SarathLUN/go-auth-service/internal/service/email"
+	"github.com/SarathLUN/go-auth-service/internal/util"
+)
+
+// bcrypt ignores everything past 72 bytes, so longer passwords would
+// silently be truncated. The limit holds for argon2id too, so that
+// PASSWORD_HASH_ALGORITHM can be switched back to bcrypt.
+const maxPasswordBytes = 72
+
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]{3,32}$`)
+
+var (
+	// ErrInvalidCredentials is returned when the email or password is wrong.
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	// ErrUserNotActive is returned when the account has not been activated yet.
+	ErrUserNotActive = errors.New("user account is not activated")
+)
+
+// ValidationError reports invalid user input. Its message is safe to show to clients.
+type ValidationError struct {
+	Field   string
+	Message string
+	// Violations lists each password requirement that was not met, for
+	// errors about the password.
+	Violations []password.Violation
+}
+
+func (e *ValidationError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// Deps holds the collaborators of the Service.
+type Deps struct {
+	Users           repository.UserRepository
+	RefreshTokens   repository.RefreshTokenRepository
+	ActionTokens    *actiontoken.Service
+	RevokedTokens   repository.RevokedTokenRepository
+	TOTPCredentials repository.TOTPCredentialRepository
+	MFAChallenges   repository.MFAChallengeRepository
+	RecoveryCodes   repository.RecoveryCodeRepository
+	QRLogins        repository.QRLoginRepository
+	FailedLogins    repository.FailedLoginRepository
+	Tx              repository.UnitOfWork
+	Mailer          email.Service
+	Clock           clock.Clock
+	Keys            *jwtkeys.KeySet
+	// MFABox encrypts TOTP secrets. MFA enrollment is unavailable when nil.
+	MFABox *secretbox.Box
+	// LoginObserver is told about login attempts; nil disables the events.
+	LoginObserver LoginObserver
+	// Reputation scores client addresses for login events. It may be nil.
+	Reputation reputation.Provider
+	// PwnedPasswords rejects breached passwords; nil disables the check.
+	PwnedPasswords pwned.Checker
+}
+
+// Service implements the authentication use cases.
+type Service struct {
+	users           repository.UserRepository
+	refreshTokens   repository.RefreshTokenRepository
+	actionTokens    *actiontoken.Service
+	revokedTokens   repository.RevokedTokenRepository
+	totpCredentials repository.TOTPCredentialRepository
+	mfaChallenges   repository.MFAChallengeRepository
+	recoveryCodes   repository.RecoveryCodeRepository
+	qrLogins        repository.QRLoginRepository
+	failedLogins    repository.FailedLoginRepository
+	tx              repository.UnitOfWork
+	mailer          email.Service
+	clock           clock.Clock
+	keys            *jwtkeys.KeySet
+	mfaBox          *secretbox.Box
+	loginObserver   LoginObserver
+	reputation      reputation.Provider
+	pwnedPasswords  pwned.Checker
+
+	emailNorm          util.EmailNormalization
+	passwordPolicy     password.Policy
+	hasher             util.PasswordHasher
+	dummyHash          string
+	claimMapping       ClaimMapping
+	accessTokenTTL     time.Duration
+	refreshTokenTTL    time.Duration
+	activationTokenTTL time.Duration
+	activateBaseURL    string
+	resetBaseURL       string
+	linkProxyURL       string // empty unless EMAIL_LINK_PROXY is set
+	resetTokenTTL      time.Duration
+	lockoutThreshold   int
+	lockoutWindow      time.Duration
+	lockoutDuration    time.Duration
+	mfaIssuer          string
+	mfaChallengeTTL    time.Duration
+	qrLoginTTL         time.Duration
+}
+
+// NewService creates an authentication service.
+func NewService(cfg *config.Config, deps Deps) *Service {
+	s := &Service{
+		users:           deps.Users,
+		refreshTokens:   deps.RefreshTokens,
+		actionTokens:    deps.ActionTokens,
+		revokedTokens:   deps.RevokedTokens,
+		totpCredentials: deps.TOTPCredentials,
+		mfaChallenges:   deps.MFAChallenges,
+		recoveryCodes:   deps.RecoveryCodes,
+		qrLogins:        deps.QRLogins,
+		failedLogins:    deps.FailedLogins,
+		tx:              deps.Tx,
+		mailer:          deps.Mailer,
+		clock:           deps.Clock,
+		keys:            deps.Keys,
+		mfaBox:          deps.MFABox,
+		loginObserver:   deps.LoginObserver,
+		reputation:      deps.Reputation,
+		pwnedPasswords:  deps.PwnedPasswords,
+
+		emailNorm:          util.EmailNormalization{Gmail: cfg.EmailNormalizeGmail, StripSubaddress: cfg.EmailStripSubaddress},
+		passwordPolicy:     newPasswordPolicy(cfg),
+		hasher:             newPasswordHasher(cfg),
+		claimMapping:       newClaimMapping(cfg),
+		accessTokenTTL:     cfg.AccessTokenTTL,
+		refreshTokenTTL:    cfg.RefreshTokenTTL,
+		activationTokenTTL: cfg.ActivationTokenTTL,
+		activateBaseURL:    cfg.ActivateBaseURL,
+		resetBaseURL:       cfg.PasswordResetBaseURL,
+		linkProxyURL:       linkProxyURL(cfg),
+		resetTokenTTL:      cfg.PasswordResetTokenTTL,
+		lockoutThreshold:   cfg.LockoutThreshold,
+		lockoutWindow:      cfg.LockoutWindow,
+		lockoutDuration:    cfg.LockoutDuration,
+		mfaIssuer:          cfg.MFAIssuer,
+		mfaChallengeTTL:    cfg.MFAChallengeTTL,
+		qrLoginTTL:         cfg.QRLoginTTL,
+	}
+	// dummyHash is compared against when a login email is unknown, so that
+	// the response time doesn't reveal whether the account exists.
+	s.dummyHash, _ = s.hasher.Hash("dummy-password-for-timing")
+	return s
+}
+
+// RegisterInput holds the data needed to register a user.
+type RegisterInput struct {
+	Email    string
+	Username string
+	Password string
+	// Locale and Timezone are optional, see Preferences.
+	Locale   string
+	Timezone string
+}
+
+// Register validates the input, hashes the password, creates an inactive
+// user and emails them an activation link. It returns a *ValidationError for
+// bad input and repository.ErrDuplicateEmail or repository.ErrDuplicateUsername
+// when the account already exists. The user and activation token are stored
+// in one transaction that is rolled back if the activation email cannot be
+// sent, so that registering can simply be retried.
+func (s *Service) Register(ctx context.Context, in RegisterInput) (*model.User, error) {
+	user, err := s.NewUser(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	err = s.tx.Do(ctx, func(ctx context.Context) error {
+		if err := s.users.Create(ctx, user); err != nil {
+			return err
+		}
+		return s.sendActivation(ctx, user)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// NewUser validates the input and returns an unsaved, inactive user with
+// role RoleUser, the password hashed and the email normalized, for callers
+// that store users themselves. It returns a *ValidationError for bad input.
+func (s *Service) NewUser(ctx context.Context, in RegisterInput) (*model.User, error) {
+	in.Email = strings.TrimSpace(in.Email)
+	in.Username = strings.TrimSpace(in.Username)
+	if err := s.validateRegistration(in); err != nil {
+		return nil, err
+	}
+	if err := s.checkPwned(ctx, in.Password); err != nil {
+		return nil, err
+	}
+	prefs, err := normalizePreferences(Preferences{Locale: in.Locale, Timezone: in.Timezone})
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := s.hasher.Hash(in.Password)
+	if err != nil {
+		return nil, err
+	}
+	return &model.User{
+		Username:        in.Username,
+		Email:           in.Email,
+		EmailNormalized: util.NormalizeEmail(in.Email, s.emailNorm),
+		PasswordHash:    hash,
+		Role:            model.RoleUser,
+		Locale:          prefs.Locale,
+		Timezone:        prefs.Timezone,
+	}, nil
+}
+
+// Login verifies the credentials and starts a new refresh token family. For
+// accounts with MFA enabled it returns a challenge instead of tokens. It
+// returns ErrInvalidCredentials for an unknown email or wrong password,
+// ErrAccountLocked while failed logins keep the account locked and
+// ErrUserNotActive for accounts that have not been activated.
+func (s *Service) Login(ctx context.Context, email, password string) (result *LoginResult, err error) {
+	event := s.beginLogin(ctx, LoginMethodPassword)
+	defer func() {
+		outcome := loginOutcome(err)
+		if err == nil && result.MFAChallenge != "" {
+			outcome = LoginMFARequired
+		}
+		s.endLogin(ctx, event, outcome)
+	}()
+
+	user, err := s.users.GetByEmail(ctx, util.NormalizeEmail(email, s.emailNorm))
+	if errors.Is(err, repository.ErrNotFound) {
+		util.CheckPassword(s.dummyHash, password)
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	event.UserID = user.ID
+
+	// A locked account is reported as such before the password is checked,
+	// so guessing on cannot tell right passwords from wrong ones.
+	if isLocked(user, s.clock.Now()) {
+		return nil, ErrAccountLocked
+	}
+	if !util.CheckPassword(user.PasswordHash, password) {
+		if err := s.recordFailedLogin(ctx, user); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
+	}
+	s.upgradePasswordHash(ctx, user, password)
+	if !user.IsActive {
+		return nil, ErrUserNotActive
+	}
+
+	// With MFA the failed logins are only reset once the code is right, so
+	// a known password does not buy unlimited guesses at codes.
+	challenge, err := s.mfaChallengeFor(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	if challenge != "" {
+		return &LoginResult{MFAChallenge: challenge}, nil
+	}
+	if err := s.resetFailedLogins(ctx, user); err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.issueTokens(ctx, user, util.NewID())
+	if err != nil {
+		return nil, err
+	}
+	return &LoginResult{Tokens: tokens}, nil
+}
+
+// upgradePasswordHash re-hashes the password of a successful login whose
+// hash was made with another algorithm or other parameters than new
+// passwords get, such as a legacy bcrypt hash. Failures are only logged,
+// since the old hash keeps working.
+func (s *Service) upgradePasswordHash(ctx context.Context, user *model.User, password string) {
+	if !s.hasher.NeedsRehash(user.PasswordHash) {
+		return
+	}
+	hash, err := s.hasher.Hash(password)
+	if err == nil {
+		// Only replaces the hash that was checked, so that a password reset
+		// in the meantime is not undone.
+		err = s.users.ReplacePasswordHash(ctx, user.ID, user.PasswordHash, hash)
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		return
+	}
+	if err != nil {
+		log.Printf("Error upgrading password hash of user %s: %v", user.ID, err)
+		return
+	}
+	user.PasswordHash = hash
+}
+
+func (s *Service) validateRegistration(in RegisterInput) error {
+	addr, err := mail.ParseAddress(in.Email)
+	if err != nil || addr.Address != in.Email {
+		return &ValidationError{Field: "email", Message: "must be a valid email address"}
+	}
+	if !usernamePattern.MatchString(in.Username) {
+		return &ValidationError{Field: "username", Message: "must be 3-32 characters of letters, digits, '.', '_' or '-'"}
+	}
+	return s.validatePassword(in.Password, in.Email, in.Username)
+}
+
+// validatePassword checks pw, the new password of the account with the given
+// email and username, against the password policy. The error lists every
+// requirement that was not met.
+func (s *Service) validatePassword(pw, email, username string) error {
+	violations := s.passwordPolicy.Check(pw, email, username)
+	if len(pw) > maxPasswordBytes {
+		violations = append(violations, password.Violation{Rule: "max_length", Message: "must be at most 72 bytes"})
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	messages := make([]string, len(violations))
+	for i, v := range violations {
+		messages[i] = v.Message
+	}
+	return &ValidationError{Field: "password", Message: strings.Join(messages, "; "), Violations: violations}
+}
+
+// checkPwned rejects passwords found in data breaches. Lookup failures are
+// logged and the password accepted, so that an unreachable API does not
+// block registrations and resets.
+func (s *Service) checkPwned(ctx context.Context, pw string) error {
+	if s.pwnedPasswords == nil {
+		return nil
+	}
+	n, err := s.pwnedPasswords.Count(ctx, pw)
+	if err != nil {
+		log.Printf("Warning: skipping breached password check: %v", err)
+		return nil
+	}
+	if n == 0 {
+		return nil
+	}
+	v := password.Violation{Rule: "breached", Message: "has appeared in a data breach"}
+	return &ValidationError{Field: "password", Message: v.Message, Violations: []password.Violation{v}}
+}
+
+// PasswordHasherFromConfig returns the hasher for new passwords selected by
+// PASSWORD_HASH_ALGORITHM and the ARGON2_ settings.
+func PasswordHasherFromConfig(cfg *config.Config) (util.PasswordHasher, error) {
+	// Negative values fail Validate below; values too large for the argon2
+	// parameters are rejected rather than truncated.
+	if cfg.Argon2Parallelism > math.MaxUint8 {
+		return util.PasswordHasher{}, fmt.Errorf("argon2id parallelism %d is above %d", cfg.Argon2Parallelism, math.MaxUint8)
+	}
+	if uint64(max(cfg.Argon2MemoryKiB, 0)) > math.MaxUint32 || uint64(max(cfg.Argon2Iterations, 0)) > math.MaxUint32 {
+		return util.PasswordHasher{}, errors.New("argon2id memory and iterations must fit in 32 bits")
+	}
+	h := util.PasswordHasher{
+		Algorithm:   cfg.PasswordHashAlgorithm,
+		Memory:      uint32(max(cfg.Argon2MemoryKiB, 0)),
+		Iterations:  uint32(max(cfg.Argon2Iterations, 0)),
+		Parallelism: uint8(max(cfg.Argon2Parallelism, 0)),
+	}
+	return h, h.Validate()
+}
+
+// newPasswordHasher is PasswordHasherFromConfig with invalid settings
+// logged and replaced by the defaults; authctl doctor reports them as
+// errors.
+func newPasswordHasher(cfg *config.Config) util.PasswordHasher {
+	h, err := PasswordHasherFromConfig(cfg)
+	if err != nil {
+		log.Printf("Warning: password hashing: %v; using %s", err, util.DefaultPasswordHasher.Algorithm)
+		return util.DefaultPasswordHasher
+	}
+	return h
+}
+
+// newPasswordPolicy builds the password policy from the PASSWORD_ settings.
+// Unknown character classes are logged and ignored; authctl doctor reports
+// them as errors.
+func newPasswordPolicy(cfg *config.Config) password.Policy {
+	classes, err := password.ParseClasses(cfg.PasswordRequiredClasses)
+	if err != nil {
+		log.Printf("Warning: PASSWORD_REQUIRED_CLASSES: %v", err)
+	}
+	return password.Policy{
+		MinLength:          cfg.PasswordMinLength,
+		RequiredClasses:    classes,
+		DenyCommon:         cfg.PasswordDenyCommon,
+		DenyEmailLocalPart: cfg.PasswordDenyEmail,
+		MinScore:           cfg.PasswordMinScore,
+	}
+}
@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/SarathLUN/go-auth-service/internal/config"
+	"github.com/SarathLUN/go-auth-service/internal/model"
+)
+
+// Sources of the user claims of access tokens. Locale and zoneinfo are
+// included whenever the user set them; the others only when CLAIM_MAPPING
+// lists them.
+const (
+	ClaimLocale   = "locale"
+	ClaimZoneInfo = "zoneinfo"
+	ClaimEmail    = "email"
+	ClaimRole     = "role"
+	// ClaimRoles is the user's role as a single-element array, the shape
+	// Auth0-style consumers expect.
+	ClaimRoles = "roles"
+)
+
+// claimSources lists the sources in the order mappings are checked.
+var claimSources = []string{ClaimLocale, ClaimZoneInfo, ClaimEmail, ClaimRole, ClaimRoles}
+
+// reservedClaims are claims the service relies on when it verifies its own
+// tokens, so they cannot be the target of a mapping.
+var reservedClaims = map[string]bool{
+	"iss": true, "sub": true, "aud": true, "exp": true, "nbf": true, "iat": true, "jti": true,
+	"client_id": true, "scope": true, "resource": true,
+}
+
+// ClaimMapping names the user claims of access tokens, so that resource
+// servers expecting namespaced claims such as https://example.com/roles can
+// read them unchanged. The zero value issues locale and zoneinfo under their
+// standard names.
+type ClaimMapping struct {
+	names map[string]string
+}
+
+// ParseClaimMapping parses CLAIM_NAMESPACE and CLAIM_MAPPING. mapping is a
+// comma-separated list of sources, each optionally followed by =name to
+// choose the claim name verbatim. Other claims are named namespace+source.
+// Invalid entries are left out of the result and reported in the error.
+func ParseClaimMapping(namespace, mapping string) (ClaimMapping, error) {
+	m := ClaimMapping{names: map[string]string{
+		ClaimLocale:   namespace + ClaimLocale,
+		ClaimZoneInfo: namespace + ClaimZoneInfo,
+	}}
+	var problems []string
+	for _, entry := range strings.Split(mapping, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		source, name, renamed := strings.Cut(entry, "=")
+		source, name = strings.TrimSpace(source), strings.TrimSpace(name)
+		if !renamed {
+			name = namespace + source
+		}
+		if !slices.Contains(claimSources, source) {
+			problems = append(problems, fmt.Sprintf("unknown claim %q", source))
+			continue
+		}
+		if name == "" || reservedClaims[name] {
+			problems = append(problems, fmt.Sprintf("%q cannot be renamed to %q", source, name))
+			continue
+		}
+		m.names[source] = name
+	}
+
+	seen := make(map[string]string, len(m.names))
+	for _, source := range claimSources {
+		name, ok := m.names[source]
+		if !ok {
+			continue
+		}
+		if other, ok := seen[name]; ok {
+			problems = append(problems, fmt.Sprintf("%q and %q are both named %q", other, source, name))
+			delete(m.names, source)
+			continue
+		}
+		seen[name] = source
+	}
+	if len(problems) > 0 {
+		return m, errors.New(strings.Join(problems, "; "))
+	}
+	return m, nil
+}
+
+// newClaimMapping builds the claim mapping from CLAIM_NAMESPACE and
+// CLAIM_MAPPING. Invalid entries are logged and ignored; authctl doctor
+// reports them as errors.
+func newClaimMapping(cfg *config.Config) ClaimMapping {
+	m, err := ParseClaimMapping(cfg.ClaimNamespace, cfg.ClaimMapping)
+	if err != nil {
+		log.Printf("Warning: CLAIM_MAPPING: %v", err)
+	}
+	return m
+}
+
+// claims returns the user claims of an access token for user by name.
+func (m ClaimMapping) claims(user *model.User) map[string]any {
+	claims := make(map[string]any, len(m.names))
+	add := func(source string, value any) {
+		if name, ok := m.name(source); ok {
+			claims[name] = value
+		}
+	}
+	if user.Locale != "" {
+		add(ClaimLocale, user.Locale)
+	}
+	if user.Timezone != "" {
+		add(ClaimZoneInfo, user.Timezone)
+	}
+	add(ClaimEmail, user.Email)
+	add(ClaimRole, user.Role)
+	add(ClaimRoles, []string{user.Role})
+	return claims
+}
+
+// name returns the claim name of source, if tokens carry it.
+func (m ClaimMapping) name(source string) (string, bool) {
+	if m.names == nil {
+		switch source {
+		case ClaimLocale, ClaimZoneInfo:
+			return source, true
+		}
+		return "", false
+	}
+	name, ok := m.names[source]
+	return name, ok
+}
+
+// stringClaim returns the string value of source in raw, the claims of a
+// parsed token.
+func (m ClaimMapping) stringClaim(raw map[string]any, source string) string {
+	name, ok := m.name(source)
+	if !ok {
+		return ""
+	}
+	value, _ := raw[name].(string)
+	return value
+}
@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// cleanupInterval is how often RunCleanup runs.
+const cleanupInterval = time.Hour
+
+// RunCleanup deletes expired revocation list entries and QR logins now and
+// then every hour until ctx is done, so that those tables only hold rows
+// that still matter.
+func (s *Service) RunCleanup(ctx context.Context) {
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+	for {
+		if n, err := s.PurgeRevokedTokens(ctx); err != nil {
+			log.Printf("Error purging revoked tokens: %v", err)
+		} else if n > 0 {
+			log.Printf("Purged %d expired revoked tokens", n)
+		}
+		if n, err := s.PurgeQRLogins(ctx); err != nil {
+			log.Printf("Error purging QR logins: %v", err)
+		} else if n > 0 {
+			log.Printf("Purged %d expired QR logins", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/config"
+	"github.com/SarathLUN/go-auth-service/internal/model"
+	"github.com/SarathLUN/go-auth-service/internal/service/actiontoken"
+)
+
+// ErrInvalidEmailLink is returned for email links that are unknown, expired,
+// already followed or whose token was already used.
+var ErrInvalidEmailLink = errors.New("invalid, expired or already used link")
+
+// linkTokenTTL is how long the token a followed email link redirects with
+// stays valid, at most. The page it lands on uses it straight away.
+const linkTokenTTL = 15 * time.Minute
+
+// linkClicks counts followed email links by token purpose, and rejected ones
+// under "invalid". It is served at GET /admin/metrics.
+var linkClicks = expvar.NewMap("email_link_clicks")
+
+// linkProxyURL returns where email links point when EMAIL_LINK_PROXY is set,
+// or "" to link straight to ACTIVATE_BASE_URL and PASSWORD_RESET_BASE_URL.
+func linkProxyURL(cfg *config.Config) string {
+	if !cfg.EmailLinkProxy {
+		return ""
+	}
+	return cfg.PublicURL + "/links"
+}
+
+// emailLink returns the link to email for token: the link proxy if enabled,
+// otherwise baseURL, which is configured by the named setting.
+func (s *Service) emailLink(setting, baseURL, token string) (string, error) {
+	if s.linkProxyURL != "" {
+		return tokenLink("PUBLIC_URL", s.linkProxyURL, token)
+	}
+	return tokenLink(setting, baseURL, token)
+}
+
+// FollowEmailLink records that the activation or password reset link
+// carrying token was followed and returns the page to redirect to. The
+// emailed token is used up and the page gets a new single-use token, valid
+// for linkTokenTTL at most, so a leaked email link or a copy of the token in
+// a browser history or proxy log is useless after the first click.
+func (s *Service) FollowEmailLink(ctx context.Context, token string) (string, error) {
+	var (
+		next   string
+		stored *model.ActionToken
+	)
+	err := s.tx.Do(ctx, func(ctx context.Context) error {
+		var err error
+		next, stored, err = s.actionTokens.Click(ctx, token, linkTokenTTL, actiontoken.PurposeActivation, actiontoken.PurposePasswordReset)
+		return err
+	})
+	if errors.Is(err, actiontoken.ErrInvalidActionToken) {
+		linkClicks.Add("invalid", 1)
+		return "", ErrInvalidEmailLink
+	}
+	if err != nil {
+		return "", err
+	}
+	linkClicks.Add(stored.Purpose, 1)
+
+	if stored.Purpose == actiontoken.PurposeActivation {
+		return tokenLink("ACTIVATE_BASE_URL", s.activateBaseURL, next)
+	}
+	return tokenLink("PASSWORD_RESET_BASE_URL", s.resetBaseURL, next)
+}
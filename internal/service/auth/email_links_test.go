@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFollowEmailLink(t *testing.T) {
+	cfg := testConfig()
+	cfg.EmailLinkProxy = true
+	cfg.PublicURL = "https://auth.example.com"
+	ts := newTestService(t, cfg)
+	ctx := context.Background()
+	u, emailed := ts.register(t)
+
+	target, err := ts.FollowEmailLink(ctx, emailed)
+	if err != nil {
+		t.Fatalf("FollowEmailLink() = %v", err)
+	}
+	if !strings.HasPrefix(target, cfg.ActivateBaseURL+"?") {
+		t.Fatalf("redirect to %q, want %s", target, cfg.ActivateBaseURL)
+	}
+	parsed, err := url.Parse(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	redirected := parsed.Query().Get("token")
+	if redirected == "" || redirected == emailed {
+		t.Fatalf("redirect token = %q, want a new token", redirected)
+	}
+
+	if _, err := ts.FollowEmailLink(ctx, emailed); !errors.Is(err, ErrInvalidEmailLink) {
+		t.Errorf("second FollowEmailLink() = %v, want ErrInvalidEmailLink", err)
+	}
+	if err := ts.Activate(ctx, emailed); !errors.Is(err, ErrInvalidActivationToken) {
+		t.Errorf("Activate(emailed token) = %v, want ErrInvalidActivationToken", err)
+	}
+	if err := ts.Activate(ctx, redirected); err != nil {
+		t.Fatalf("Activate(redirect token) = %v", err)
+	}
+	if got, _ := ts.users.GetByID(ctx, u.ID); !got.IsActive {
+		t.Error("user is not active")
+	}
+}
+
+func TestFollowEmailLinkTokenExpiry(t *testing.T) {
+	cfg := testConfig()
+	cfg.EmailLinkProxy = true
+	cfg.PublicURL = "https://auth.example.com"
+	tests := []struct {
+		name      string
+		beforeTTL time.Duration // how long before the emailed token expires the link is followed
+		after     time.Duration // how long after following it the page activates
+		valid     bool
+	}{
+		{"used right away", cfg.ActivationTokenTTL, 0, true},
+		{"just before the redirect token expires", cfg.ActivationTokenTTL, linkTokenTTL - time.Second, true},
+		{"redirect token expired", cfg.ActivationTokenTTL, linkTokenTTL, false},
+		{"emailed token about to expire", time.Minute, time.Minute, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestService(t, cfg)
+			ctx := context.Background()
+			_, emailed := ts.register(t)
+
+			ts.clock.Advance(cfg.ActivationTokenTTL - tt.beforeTTL)
+			target, err := ts.FollowEmailLink(ctx, emailed)
+			if err != nil {
+				t.Fatalf("FollowEmailLink() = %v", err)
+			}
+			parsed, _ := url.Parse(target)
+
+			ts.clock.Advance(tt.after)
+			err = ts.Activate(ctx, parsed.Query().Get("token"))
+			if tt.valid && err != nil {
+				t.Fatalf("Activate() = %v", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidActivationToken) {
+				t.Fatalf("Activate() = %v, want ErrInvalidActivationToken", err)
+			}
+		})
+	}
+}
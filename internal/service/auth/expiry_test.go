@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// expiryCases travel to just before and to the end of a lifetime of ttl.
+func expiryCases(ttl time.Duration) []struct {
+	name  string
+	after time.Duration
+	valid bool
+} {
+	return []struct {
+		name  string
+		after time.Duration
+		valid bool
+	}{
+		{"fresh", 0, true},
+		{"just before expiry", ttl - time.Second, true},
+		{"at expiry", ttl, false},
+		{"long after expiry", 10 * ttl, false},
+	}
+}
+
+func TestAccessTokenExpiry(t *testing.T) {
+	cfg := testConfig()
+	for _, tt := range expiryCases(cfg.AccessTokenTTL) {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestService(t, cfg)
+			u, _ := ts.register(t)
+			ts.users.Activate(context.Background(), u.ID)
+			tokens, err := ts.StartSession(context.Background(), u.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tokens.ExpiresIn != cfg.AccessTokenTTL {
+				t.Errorf("ExpiresIn = %v, want %v", tokens.ExpiresIn, cfg.AccessTokenTTL)
+			}
+
+			ts.clock.Advance(tt.after)
+			claims, err := ts.Authenticate(context.Background(), tokens.AccessToken)
+			if tt.valid {
+				if err != nil {
+					t.Fatalf("Authenticate() = %v", err)
+				}
+				if claims.UserID != u.ID {
+					t.Errorf("UserID = %q, want %q", claims.UserID, u.ID)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidAccessToken) {
+				t.Fatalf("Authenticate() = %v, want ErrInvalidAccessToken", err)
+			}
+		})
+	}
+}
+
+func TestRefreshTokenExpiry(t *testing.T) {
+	cfg := testConfig()
+	for _, tt := range expiryCases(cfg.RefreshTokenTTL) {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestService(t, cfg)
+			u, _ := ts.register(t)
+			ts.users.Activate(context.Background(), u.ID)
+			tokens, err := ts.StartSession(context.Background(), u.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ts.clock.Advance(tt.after)
+			refreshed, err := ts.Refresh(context.Background(), tokens.RefreshToken)
+			if !tt.valid {
+				if !errors.Is(err, ErrInvalidRefreshToken) {
+					t.Fatalf("Refresh() = %v, want ErrInvalidRefreshToken", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Refresh() = %v", err)
+			}
+			// The new access token lives from the time of the refresh.
+			ts.clock.Advance(cfg.AccessTokenTTL - time.Second)
+			if _, err := ts.Authenticate(context.Background(), refreshed.AccessToken); err != nil {
+				t.Errorf("Authenticate(refreshed) = %v", err)
+			}
+		})
+	}
+}
+
+func TestActivationTokenExpiry(t *testing.T) {
+	cfg := testConfig()
+	for _, tt := range expiryCases(cfg.ActivationTokenTTL) {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestService(t, cfg)
+			u, token := ts.register(t)
+
+			ts.clock.Advance(tt.after)
+			err := ts.Activate(context.Background(), token)
+			if !tt.valid {
+				if !errors.Is(err, ErrInvalidActivationToken) {
+					t.Fatalf("Activate() = %v, want ErrInvalidActivationToken", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Activate() = %v", err)
+			}
+			if got, _ := ts.users.GetByID(context.Background(), u.ID); !got.IsActive {
+				t.Error("user is not active")
+			}
+		})
+	}
+}
+
+func TestPasswordResetTokenExpiry(t *testing.T) {
+	cfg := testConfig()
+	for _, tt := range expiryCases(cfg.PasswordResetTokenTTL) {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestService(t, cfg)
+			u, _ := ts.register(t)
+			if err := ts.ForcePasswordReset(context.Background(), u.ID); err != nil {
+				t.Fatal(err)
+			}
+			token := ts.mailer.lastToken()
+
+			ts.clock.Advance(tt.after)
+			err := ts.ResetPassword(context.Background(), token, "Another-Long-Passphrase-7")
+			if !tt.valid {
+				if !errors.Is(err, ErrInvalidPasswordResetToken) {
+					t.Fatalf("ResetPassword() = %v, want ErrInvalidPasswordResetToken", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResetPassword() = %v", err)
+			}
+		})
+	}
+}
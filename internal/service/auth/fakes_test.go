@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/clock"
+	"github.com/SarathLUN/go-auth-service/internal/config"
+	"github.com/SarathLUN/go-auth-service/internal/jwtkeys"
+	"github.com/SarathLUN/go-auth-service/internal/model"
+	"github.com/SarathLUN/go-auth-service/internal/repository/repotest"
+	"github.com/SarathLUN/go-auth-service/internal/secretbox"
+	"github.com/SarathLUN/go-auth-service/internal/service/actiontoken"
+	"github.com/SarathLUN/go-auth-service/internal/util"
+)
+
+// fakeMailer remembers the token of the last link it was asked to send.
+type fakeMailer struct {
+	mu    sync.Mutex
+	token string
+}
+
+func (f *fakeMailer) SendActivationEmail(_ context.Context, _, _, link string, _ time.Time) error {
+	return f.remember(link)
+}
+
+func (f *fakeMailer) SendPasswordResetEmail(_ context.Context, _, _, link string, _ time.Time) error {
+	return f.remember(link)
+}
+
+func (f *fakeMailer) remember(link string) error {
+	u, err := url.Parse(link)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.token = u.Query().Get("token")
+	return nil
+}
+
+func (f *fakeMailer) lastToken() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.token
+}
+
+// testService is a Service on in-memory repositories, with a fake clock to travel
+// through token lifetimes.
+type testService struct {
+	*Service
+	cfg    *config.Config
+	clock  *clock.Fake
+	users  *repotest.Users
+	totp   *repotest.TOTPCredentials
+	mailer *fakeMailer
+}
+
+func testConfig() *config.Config {
+	return &config.Config{
+		AccessTokenTTL:        15 * time.Minute,
+		RefreshTokenTTL:       720 * time.Hour,
+		ActivationTokenTTL:    24 * time.Hour,
+		PasswordResetTokenTTL: time.Hour,
+		MFAChallengeTTL:       5 * time.Minute,
+		QRLoginTTL:            2 * time.Minute,
+		ActivateBaseURL:       "https://auth.example.com/activate",
+		PasswordResetBaseURL:  "https://auth.example.com/password/reset",
+		PasswordMinLength:     8,
+		// Cheap hashing keeps the tests fast.
+		PasswordHashAlgorithm: util.PasswordHashArgon2id,
+		Argon2MemoryKiB:       64,
+		Argon2Iterations:      1,
+		Argon2Parallelism:     1,
+	}
+}
+
+func newTestService(t *testing.T, cfg *config.Config) *testService {
+	t.Helper()
+	clk := clock.NewFake(time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC))
+	users := &repotest.Users{}
+	totpCreds := &repotest.TOTPCredentials{}
+	mailer := &fakeMailer{}
+	box, err := secretbox.New(base64.StdEncoding.EncodeToString(make([]byte, secretbox.KeySize)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewService(cfg, Deps{
+		Users:         users,
+		RefreshTokens: &repotest.RefreshTokens{},
+		ActionTokens: actiontoken.NewService(actiontoken.Deps{
+			Tokens: &repotest.ActionTokens{},
+			Clock:  clk,
+		}),
+		RevokedTokens:   &repotest.RevokedTokens{},
+		TOTPCredentials: totpCreds,
+		MFAChallenges:   &repotest.MFAChallenges{},
+		RecoveryCodes:   &repotest.RecoveryCodes{},
+		QRLogins:        &repotest.QRLogins{},
+		FailedLogins:    &repotest.FailedLogins{},
+		Tx:              repotest.Tx{},
+		Mailer:          mailer,
+		Clock:           clk,
+		Keys:            jwtkeys.NewHMAC([]byte("test-secret")),
+		MFABox:          box,
+	})
+	return &testService{Service: s, cfg: cfg, clock: clk, users: users, totp: totpCreds, mailer: mailer}
+}
+
+// register creates an inactive user and returns it with the token of the
+// activation email.
+func (ts *testService) register(t *testing.T) (*model.User, string) {
+	t.Helper()
+	u, err := ts.Register(context.Background(), RegisterInput{
+		Email:    "ada@example.com",
+		Username: "ada",
+		Password: testPassword,
+	})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	return u, ts.mailer.lastToken()
+}
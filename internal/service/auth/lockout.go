@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/model"
+)
+
+// ErrAccountLocked is returned by Login and CompleteMFALogin while too many
+// failed logins keep the account locked.
+var ErrAccountLocked = errors.New("account is temporarily locked")
+
+// isLocked reports whether a lockout is in effect for the user at now.
+func isLocked(user *model.User, now time.Time) bool {
+	return user.LockedUntil != nil && now.Before(*user.LockedUntil)
+}
+
+// failedLoginsSince returns the time after which the user's failed logins
+// count towards a lockout: the start of LOCKOUT_WINDOW, or the last reset
+// if that is later.
+func (s *Service) failedLoginsSince(user *model.User, now time.Time) time.Time {
+	since := now.Add(-s.lockoutWindow)
+	if r := user.FailedLoginsResetAt; r != nil && r.After(since) {
+		since = *r
+	}
+	return since
+}
+
+// recordFailedLogin audits a wrong password or MFA code for the user and
+// locks the account for LOCKOUT_DURATION once LOCKOUT_THRESHOLD failures
+// fall within LOCKOUT_WINDOW.
+func (s *Service) recordFailedLogin(ctx context.Context, user *model.User) error {
+	now := s.clock.Now()
+	if err := s.failedLogins.Record(ctx, user.ID, userAgent(ctx), now); err != nil {
+		return err
+	}
+	if s.lockoutThreshold <= 0 {
+		return nil
+	}
+
+	n, err := s.failedLogins.CountSince(ctx, user.ID, s.failedLoginsSince(user, now))
+	if err != nil || n < s.lockoutThreshold {
+		return err
+	}
+	return s.users.Lock(ctx, user.ID, now, now.Add(s.lockoutDuration))
+}
+
+// resetFailedLogins stops the user's earlier failed logins from counting
+// towards a lockout after they logged in successfully. Users without recent
+// failures are left alone, so most logins cost no write.
+func (s *Service) resetFailedLogins(ctx context.Context, user *model.User) error {
+	if s.lockoutThreshold <= 0 {
+		return nil
+	}
+	now := s.clock.Now()
+	n, err := s.failedLogins.CountSince(ctx, user.ID, s.failedLoginsSince(user, now))
+	if err != nil || n == 0 {
+		return err
+	}
+	return s.users.Unlock(ctx, user.ID, now)
+}
+
+// Unlock lifts a lockout of the user's account before it runs out and
+// restarts their failed login count. It returns repository.ErrNotFound for
+// unknown users.
+func (s *Service) Unlock(ctx context.Context, userID string) error {
+	return s.users.Unlock(ctx, userID, s.clock.Now())
+}
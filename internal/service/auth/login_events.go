@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"time"
+)
+
+// Login methods reported in LoginEvent.Method.
+const (
+	LoginMethodPassword = "password"
+	LoginMethodMFA      = "mfa" // the second step of a password login
+	LoginMethodQR       = "qr"
+)
+
+// Login outcomes reported in LoginEvent.Outcome.
+const (
+	LoginSucceeded   = "success"
+	LoginMFARequired = "mfa_required"
+	// LoginRejected covers wrong passwords, MFA codes and challenges and
+	// invalid QR logins.
+	LoginRejected = "rejected"
+	LoginLocked   = "locked"
+	LoginInactive = "inactive"
+	LoginError    = "error"
+)
+
+// LoginEvent describes the outcome of one login attempt.
+type LoginEvent struct {
+	Time    time.Time
+	Method  string
+	Outcome string
+	// UserID is empty when the attempt could not be tied to an account.
+	UserID    string
+	UserAgent string
+	// ClientIP is the zero Addr when the client's address is unknown.
+	ClientIP netip.Addr
+	// RiskScore is the reputation of ClientIP from IP_REPUTATION_PROVIDER,
+	// 0 (clean) to 100 (known bad), or -1 if it could not be looked up.
+	RiskScore int
+	Latency   time.Duration
+}
+
+// LoginObserver is told about login attempts, for metrics, auditing and
+// fraud detection. It is called on the login path, so it must return
+// quickly and hand slow work off.
+type LoginObserver interface {
+	ObserveLogin(ctx context.Context, e LoginEvent)
+}
+
+// LoginObservers passes each event on to all of its observers.
+type LoginObservers []LoginObserver
+
+// ObserveLogin implements LoginObserver.
+func (o LoginObservers) ObserveLogin(ctx context.Context, e LoginEvent) {
+	for _, obs := range o {
+		obs.ObserveLogin(ctx, e)
+	}
+}
+
+type clientIPKey struct{}
+
+// WithClientIP returns a context carrying the address of the client a
+// request came from, for the login events reported with it.
+func WithClientIP(ctx context.Context, ip netip.Addr) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+func clientIP(ctx context.Context) netip.Addr {
+	ip, _ := ctx.Value(clientIPKey{}).(netip.Addr)
+	return ip
+}
+
+// beginLogin starts the event for a login attempt made with ctx.
+func (s *Service) beginLogin(ctx context.Context, method string) *LoginEvent {
+	return &LoginEvent{
+		Time:      s.clock.Now(),
+		Method:    method,
+		UserAgent: userAgent(ctx),
+		ClientIP:  clientIP(ctx),
+		RiskScore: -1,
+	}
+}
+
+// endLogin completes the event with the attempt's outcome and reports it.
+func (s *Service) endLogin(ctx context.Context, e *LoginEvent, outcome string) {
+	if s.loginObserver == nil {
+		return
+	}
+	e.Outcome = outcome
+	e.Latency = s.clock.Now().Sub(e.Time)
+	if s.reputation != nil && e.ClientIP.IsValid() {
+		if v, err := s.reputation.Lookup(ctx, e.ClientIP); err == nil {
+			e.RiskScore = v.Score
+		}
+	}
+	s.loginObserver.ObserveLogin(ctx, *e)
+}
+
+// loginOutcome classifies the error a login attempt ended with.
+func loginOutcome(err error) string {
+	switch {
+	case err == nil:
+		return LoginSucceeded
+	case errors.Is(err, ErrInvalidCredentials), errors.Is(err, ErrInvalidMFACode),
+		errors.Is(err, ErrInvalidMFAChallenge), errors.Is(err, ErrInvalidQRLogin):
+		return LoginRejected
+	case errors.Is(err, ErrAccountLocked):
+		return LoginLocked
+	case errors.Is(err, ErrUserNotActive):
+		return LoginInactive
+	default:
+		return LoginError
+	}
+}
@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/repository"
+	"github.com/SarathLUN/go-auth-service/internal/util"
+)
+
+// ErrInvalidAccessToken is returned for access tokens that are malformed,
+// badly signed, expired or revoked.
+var ErrInvalidAccessToken = errors.New("invalid access token")
+
+// AccessClaims identifies the user and the access token behind an
+// authenticated request. Locale and ZoneInfo are the user's preferences
+// when the token was issued, empty if they had none.
+type AccessClaims struct {
+	UserID    string
+	TokenID   string
+	ExpiresAt time.Time
+	Locale    string
+	ZoneInfo  string
+}
+
+// Authenticate validates an access token and checks it against the
+// revocation list.
+func (s *Service) Authenticate(ctx context.Context, accessToken string) (*AccessClaims, error) {
+	claims, err := util.ParseAccessToken(s.keys, accessToken, s.clock.Now())
+	if err != nil {
+		return nil, ErrInvalidAccessToken
+	}
+	// Tokens issued to OAuth clients identify a service, not a user.
+	// ParseAccessToken already rejects share tokens.
+	if claims.ClientID != "" {
+		return nil, ErrInvalidAccessToken
+	}
+	revoked, err := s.revokedTokens.IsRevoked(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrInvalidAccessToken
+	}
+	return &AccessClaims{
+		UserID:    claims.Subject,
+		TokenID:   claims.ID,
+		ExpiresAt: claims.ExpiresAt.Time,
+		Locale:    s.claimMapping.stringClaim(claims.Raw, ClaimLocale),
+		ZoneInfo:  s.claimMapping.stringClaim(claims.Raw, ClaimZoneInfo),
+	}, nil
+}
+
+// Logout revokes the access token described by claims. If refreshToken is
+// given, its whole family is revoked as well so the session cannot be
+// refreshed; a refresh token belonging to another user is rejected with
+// ErrInvalidRefreshToken.
+func (s *Service) Logout(ctx context.Context, claims *AccessClaims, refreshToken string) error {
+	now := s.clock.Now()
+	if refreshToken != "" {
+		stored, err := s.refreshTokens.GetByHash(ctx, util.HashToken(refreshToken))
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrInvalidRefreshToken
+		}
+		if err != nil {
+			return err
+		}
+		if stored.UserID != claims.UserID {
+			return ErrInvalidRefreshToken
+		}
+		if err := s.refreshTokens.RevokeFamily(ctx, stored.FamilyID, now); err != nil {
+			return err
+		}
+	}
+	return s.revokedTokens.Revoke(ctx, claims.TokenID, claims.UserID, claims.ExpiresAt)
+}
+
+// PurgeRevokedTokens drops revocation list entries of access tokens that
+// have expired, since those are rejected without the list.
+func (s *Service) PurgeRevokedTokens(ctx context.Context) (int64, error) {
+	return s.revokedTokens.DeleteExpired(ctx, s.clock.Now())
+}
@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPurgeRevokedTokens(t *testing.T) {
+	cfg := testConfig()
+	ts := newTestService(t, cfg)
+	ctx := context.Background()
+	u, _ := ts.register(t)
+	ts.users.Activate(ctx, u.ID)
+	tokens, err := ts.StartSession(ctx, u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := ts.Authenticate(ctx, tokens.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ts.Logout(ctx, claims, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// While the token is valid its entry has to stay.
+	ts.clock.Advance(cfg.AccessTokenTTL - time.Second)
+	if n, err := ts.PurgeRevokedTokens(ctx); err != nil || n != 0 {
+		t.Fatalf("PurgeRevokedTokens() = %d, %v, want 0", n, err)
+	}
+	if _, err := ts.Authenticate(ctx, tokens.AccessToken); !errors.Is(err, ErrInvalidAccessToken) {
+		t.Fatalf("Authenticate(revoked) = %v, want ErrInvalidAccessToken", err)
+	}
+
+	ts.clock.Advance(2 * time.Second)
+	if n, err := ts.PurgeRevokedTokens(ctx); err != nil || n != 1 {
+		t.Fatalf("PurgeRevokedTokens() = %d, %v, want 1", n, err)
+	}
+	if _, err := ts.Authenticate(ctx, tokens.AccessToken); !errors.Is(err, ErrInvalidAccessToken) {
+		t.Fatalf("Authenticate(expired) = %v, want ErrInvalidAccessToken", err)
+	}
+}
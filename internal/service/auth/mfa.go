@@ -0,0 +1,232 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/SarathLUN/go-auth-service/internal/model"
+	"github.com/SarathLUN/go-auth-service/internal/repository"
+	"github.com/SarathLUN/go-auth-service/internal/totp"
+	"github.com/SarathLUN/go-auth-service/internal/util"
+)
+
+const (
+	// mfaChallengeBytes is the entropy of MFA challenge tokens.
+	mfaChallengeBytes = 32
+	// maxMFAAttempts is how many wrong codes a challenge tolerates before
+	// the user has to log in with their password again.
+	maxMFAAttempts = 5
+)
+
+var (
+	// ErrMFAUnavailable is returned when MFA_ENCRYPTION_KEY is not configured.
+	ErrMFAUnavailable = errors.New("MFA is not configured")
+	// ErrMFAAlreadyEnabled is returned when enrolling a user whose TOTP
+	// credential is already confirmed.
+	ErrMFAAlreadyEnabled = errors.New("MFA is already enabled")
+	// ErrMFANotEnrolled is returned when confirming without a pending enrollment.
+	ErrMFANotEnrolled = errors.New("no pending MFA enrollment")
+	// ErrInvalidMFACode is returned for wrong, expired or already used codes.
+	ErrInvalidMFACode = errors.New("invalid MFA code")
+	// ErrInvalidMFAChallenge is returned for unknown, expired, used or
+	// exhausted MFA challenges.
+	ErrInvalidMFAChallenge = errors.New("invalid or expired MFA challenge")
+)
+
+// LoginResult is the outcome of a password login: either tokens, or for
+// accounts with MFA a challenge token to pass to CompleteMFALogin.
+type LoginResult struct {
+	Tokens       *TokenPair
+	MFAChallenge string
+}
+
+// TOTPEnrollment is what a user needs to add the account to an authenticator app.
+type TOTPEnrollment struct {
+	Secret string
+	// URI is the otpauth:// URI, usually shown as a QR code.
+	URI string
+}
+
+// EnrollTOTP generates a new TOTP secret for the user. MFA is not enforced
+// until the enrollment is confirmed with ConfirmTOTP; enrolling again before
+// that replaces the secret.
+func (s *Service) EnrollTOTP(ctx context.Context, userID string) (*TOTPEnrollment, error) {
+	if s.mfaBox == nil {
+		return nil, ErrMFAUnavailable
+	}
+	user, err := s.users.GetByID(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidAccessToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := s.mfaBox.Seal([]byte(secret), []byte(user.ID))
+	if err != nil {
+		return nil, err
+	}
+	saved, err := s.totpCredentials.SavePending(ctx, &model.TOTPCredential{UserID: user.ID, Secret: sealed})
+	if err != nil {
+		return nil, err
+	}
+	if !saved {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	return &TOTPEnrollment{Secret: secret, URI: totp.URI(s.mfaIssuer, user.Email, secret)}, nil
+}
+
+// ConfirmTOTP verifies the first code from the authenticator app, turns on
+// MFA for the user and returns their recovery codes. The codes are not
+// retrievable later, only regenerated.
+func (s *Service) ConfirmTOTP(ctx context.Context, userID, code string) ([]string, error) {
+	cred, err := s.totpCredentials.GetByUserID(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrMFANotEnrolled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cred.ConfirmedAt != nil {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if err := s.verifyTOTP(ctx, cred, code); err != nil {
+		return nil, err
+	}
+
+	confirmed, err := s.totpCredentials.Confirm(ctx, userID, s.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !confirmed {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	return s.issueRecoveryCodes(ctx, userID)
+}
+
+// CompleteMFALogin exchanges the challenge from Login and a TOTP or recovery
+// code for tokens. Each wrong code counts against the challenge and, like a
+// wrong password, towards locking the account; it returns ErrAccountLocked
+// while the account is locked.
+func (s *Service) CompleteMFALogin(ctx context.Context, challenge, code string) (tokens *TokenPair, err error) {
+	event := s.beginLogin(ctx, LoginMethodMFA)
+	defer func() { s.endLogin(ctx, event, loginOutcome(err)) }()
+
+	stored, err := s.mfaChallenges.GetByHash(ctx, util.HashToken(challenge))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidMFAChallenge
+	}
+	if err != nil {
+		return nil, err
+	}
+	event.UserID = stored.UserID
+
+	now := s.clock.Now()
+	if stored.UsedAt != nil || !now.Before(stored.ExpiresAt) || stored.Attempts >= maxMFAAttempts {
+		return nil, ErrInvalidMFAChallenge
+	}
+
+	user, err := s.users.GetByID(ctx, stored.UserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidMFAChallenge
+	}
+	if err != nil {
+		return nil, err
+	}
+	// As in Login, a locked account is reported before the code is checked.
+	if isLocked(user, now) {
+		return nil, ErrAccountLocked
+	}
+
+	cred, err := s.totpCredentials.GetByUserID(ctx, stored.UserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidMFAChallenge
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifySecondFactor(ctx, cred, code); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			if _, ferr := s.mfaChallenges.RecordFailure(ctx, stored.ID); ferr != nil {
+				return nil, ferr
+			}
+			if ferr := s.recordFailedLogin(ctx, user); ferr != nil {
+				return nil, ferr
+			}
+		}
+		return nil, err
+	}
+
+	used, err := s.mfaChallenges.MarkUsed(ctx, stored.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !used {
+		return nil, ErrInvalidMFAChallenge
+	}
+	if err := s.resetFailedLogins(ctx, user); err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, ErrUserNotActive
+	}
+	return s.issueTokens(ctx, user, util.NewID())
+}
+
+// mfaChallengeFor returns a new challenge token if the user has MFA enabled,
+// or "" if password login is enough.
+func (s *Service) mfaChallengeFor(ctx context.Context, user *model.User) (string, error) {
+	cred, err := s.totpCredentials.GetByUserID(ctx, user.ID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if cred.ConfirmedAt == nil {
+		return "", nil
+	}
+
+	token, err := util.GenerateRandomToken(mfaChallengeBytes)
+	if err != nil {
+		return "", err
+	}
+	err = s.mfaChallenges.Create(ctx, &model.MFAChallenge{
+		UserID:    user.ID,
+		TokenHash: util.HashToken(token),
+		ExpiresAt: s.clock.Now().Add(s.mfaChallengeTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// verifyTOTP checks code against the credential and records its time step,
+// so the same code cannot be used twice.
+func (s *Service) verifyTOTP(ctx context.Context, cred *model.TOTPCredential, code string) error {
+	if s.mfaBox == nil {
+		return ErrMFAUnavailable
+	}
+	secret, err := s.mfaBox.Open(cred.Secret, []byte(cred.UserID))
+	if err != nil {
+		return fmt.Errorf("open TOTP secret of user %s: %w", cred.UserID, err)
+	}
+	step, ok := totp.Validate(string(secret), code, s.clock.Now())
+	if !ok {
+		return ErrInvalidMFACode
+	}
+	used, err := s.totpCredentials.UseStep(ctx, cred.UserID, step)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/model"
+	"github.com/SarathLUN/go-auth-service/internal/totp"
+)
+
+const testPassword = "Correct-Horse-9-Battery"
+
+// enableTOTP gives the user a confirmed TOTP credential and returns its secret.
+func (ts *testService) enableTOTP(t *testing.T, userID string) string {
+	t.Helper()
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := ts.mfaBox.Seal([]byte(secret), []byte(userID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := ts.totp.SavePending(ctx, &model.TOTPCredential{UserID: userID, Secret: sealed}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ts.totp.Confirm(ctx, userID, ts.clock.Now()); err != nil {
+		t.Fatal(err)
+	}
+	return secret
+}
+
+// wrongCode returns a code that is not valid for secret right now.
+func (ts *testService) wrongCode(t *testing.T, secret string) string {
+	t.Helper()
+	for i := 0; ; i++ {
+		code := fmt.Sprintf("%06d", i)
+		if _, ok := totp.Validate(secret, code, ts.clock.Now()); !ok {
+			return code
+		}
+	}
+}
+
+func (ts *testService) mfaChallenge(t *testing.T) string {
+	t.Helper()
+	result, err := ts.Login(context.Background(), "ada@example.com", testPassword)
+	if err != nil {
+		t.Fatalf("Login() = %v", err)
+	}
+	if result.MFAChallenge == "" {
+		t.Fatal("Login() returned no MFA challenge")
+	}
+	return result.MFAChallenge
+}
+
+func TestCompleteMFALoginLockout(t *testing.T) {
+	cfg := testConfig()
+	cfg.LockoutThreshold = 3
+	cfg.LockoutWindow = time.Hour
+	cfg.LockoutDuration = 15 * time.Minute
+	ts := newTestService(t, cfg)
+	ctx := context.Background()
+	u, _ := ts.register(t)
+	ts.users.Activate(ctx, u.ID)
+	secret := ts.enableTOTP(t, u.ID)
+
+	challenge := ts.mfaChallenge(t)
+	for i := 0; i < 2; i++ {
+		if _, err := ts.CompleteMFALogin(ctx, challenge, ts.wrongCode(t, secret)); !errors.Is(err, ErrInvalidMFACode) {
+			t.Fatalf("CompleteMFALogin(wrong code) = %v, want ErrInvalidMFACode", err)
+		}
+	}
+
+	// Logging in with the password again must not wipe the wrong codes.
+	challenge = ts.mfaChallenge(t)
+	if _, err := ts.CompleteMFALogin(ctx, challenge, ts.wrongCode(t, secret)); !errors.Is(err, ErrInvalidMFACode) {
+		t.Fatalf("CompleteMFALogin(wrong code) = %v, want ErrInvalidMFACode", err)
+	}
+
+	code, err := totp.Code(secret, totp.Step(ts.clock.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ts.CompleteMFALogin(ctx, challenge, code); !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("CompleteMFALogin(right code) while locked = %v, want ErrAccountLocked", err)
+	}
+	if _, err := ts.Login(ctx, "ada@example.com", testPassword); !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("Login() while locked = %v, want ErrAccountLocked", err)
+	}
+
+	ts.clock.Advance(cfg.LockoutDuration)
+	challenge = ts.mfaChallenge(t)
+	code, err = totp.Code(secret, totp.Step(ts.clock.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ts.CompleteMFALogin(ctx, challenge, code); err != nil {
+		t.Fatalf("CompleteMFALogin() after the lockout = %v", err)
+	}
+}
+
+func TestRegenerateRecoveryCodesLockout(t *testing.T) {
+	cfg := testConfig()
+	cfg.LockoutThreshold = 3
+	cfg.LockoutWindow = time.Hour
+	cfg.LockoutDuration = 15 * time.Minute
+	ts := newTestService(t, cfg)
+	ctx := context.Background()
+	u, _ := ts.register(t)
+	ts.users.Activate(ctx, u.ID)
+	secret := ts.enableTOTP(t, u.ID)
+
+	for i := 0; i < cfg.LockoutThreshold; i++ {
+		if _, err := ts.RegenerateRecoveryCodes(ctx, u.ID, ts.wrongCode(t, secret)); !errors.Is(err, ErrInvalidMFACode) {
+			t.Fatalf("RegenerateRecoveryCodes(wrong code) = %v, want ErrInvalidMFACode", err)
+		}
+	}
+	code, err := totp.Code(secret, totp.Step(ts.clock.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ts.RegenerateRecoveryCodes(ctx, u.ID, code); !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("RegenerateRecoveryCodes(right code) while locked = %v, want ErrAccountLocked", err)
+	}
+
+	ts.clock.Advance(cfg.LockoutDuration)
+	code, err = totp.Code(secret, totp.Step(ts.clock.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	codes, err := ts.RegenerateRecoveryCodes(ctx, u.ID, code)
+	if err != nil {
+		t.Fatalf("RegenerateRecoveryCodes() after the lockout = %v", err)
+	}
+	if len(codes) != recoveryCodeCount {
+		t.Errorf("got %d codes, want %d", len(codes), recoveryCodeCount)
+	}
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	"github.com/SarathLUN/go-auth-service/internal/repository"
+	"github.com/SarathLUN/go-auth-service/internal/service/actiontoken"
+)
+
+// ErrInvalidPasswordResetToken is returned for unknown, expired or already used password reset tokens.
+var ErrInvalidPasswordResetToken = errors.New("invalid or expired password reset token")
+
+// ForcePasswordReset clears the user's password, ends all their sessions and
+// emails them a link to choose a new one. An empty hash never matches, so the
+// old password stops working straight away. Nothing changes if the email
+// cannot be sent. It returns repository.ErrNotFound for unknown users.
+func (s *Service) ForcePasswordReset(ctx context.Context, userID string) error {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return s.tx.Do(ctx, func(ctx context.Context) error {
+		if err := s.users.SetPasswordHash(ctx, user.ID, ""); err != nil {
+			return err
+		}
+		if err := s.refreshTokens.RevokeAllForUser(ctx, user.ID, s.clock.Now()); err != nil {
+			return err
+		}
+
+		token, stored, err := s.actionTokens.Issue(ctx, actiontoken.IssueInput{
+			Purpose: actiontoken.PurposePasswordReset,
+			Subject: user.ID,
+			TTL:     s.resetTokenTTL,
+		})
+		if err != nil {
+			return err
+		}
+		link, err := s.emailLink("PASSWORD_RESET_BASE_URL", s.resetBaseURL, token)
+		if err != nil {
+			return err
+		}
+		return s.mailer.SendPasswordResetEmail(ctx, user.Email, user.Username, link, stored.ExpiresAt.In(userLocation(user)))
+	})
+}
+
+// ResetPassword consumes a password reset token and sets the new password,
+// ending any sessions that are still open. The password is checked against
+// the policy once the token's user is known; a rejected password rolls the
+// transaction back, so the token is not used up and can simply be retried.
+// Breached passwords are looked up before the transaction starts.
+func (s *Service) ResetPassword(ctx context.Context, token, password string) error {
+	if err := s.checkPwned(ctx, password); err != nil {
+		return err
+	}
+	return s.tx.Do(ctx, func(ctx context.Context) error {
+		stored, err := s.actionTokens.Consume(ctx, actiontoken.PurposePasswordReset, token)
+		if errors.Is(err, actiontoken.ErrInvalidActionToken) {
+			return ErrInvalidPasswordResetToken
+		}
+		if err != nil {
+			return err
+		}
+
+		user, err := s.users.GetByID(ctx, stored.Subject)
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrInvalidPasswordResetToken
+		}
+		if err != nil {
+			return err
+		}
+		if err := s.validatePassword(password, user.Email, user.Username); err != nil {
+			return err
+		}
+		hash, err := s.hasher.Hash(password)
+		if err != nil {
+			return err
+		}
+
+		err = s.users.SetPasswordHash(ctx, user.ID, hash)
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrInvalidPasswordResetToken
+		}
+		if err != nil {
+			return err
+		}
+		return s.refreshTokens.RevokeAllForUser(ctx, user.ID, s.clock.Now())
+	})
+}
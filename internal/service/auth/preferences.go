@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	// Embedded so that time zones resolve on hosts without a zoneinfo database.
+	_ "time/tzdata"
+
+	"golang.org/x/text/language"
+
+	"github.com/SarathLUN/go-auth-service/internal/model"
+	"github.com/SarathLUN/go-auth-service/internal/repository"
+)
+
+const (
+	// maxLocaleLength is the longest language tag RFC 5646 asks
+	// implementations to support.
+	maxLocaleLength   = 35
+	maxTimezoneLength = 64
+)
+
+// Preferences are the user's locale and time zone. Either may be empty when
+// unknown.
+type Preferences struct {
+	// Locale is a BCP 47 language tag such as "en-US".
+	Locale string
+	// Timezone is an IANA time zone name such as "Europe/Paris".
+	Timezone string
+}
+
+// SetPreferences replaces the locale and time zone of userID and returns the
+// updated user. They are sent as the locale and zoneinfo claims of access
+// tokens issued from then on. It returns a *ValidationError for a malformed
+// tag or an unknown time zone.
+func (s *Service) SetPreferences(ctx context.Context, userID string, in Preferences) (*model.User, error) {
+	prefs, err := normalizePreferences(in)
+	if err != nil {
+		return nil, err
+	}
+	err = s.users.SetPreferences(ctx, userID, prefs.Locale, prefs.Timezone)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrUserNotActive
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.users.GetByID(ctx, userID)
+}
+
+// normalizePreferences validates in and returns it with the locale in
+// canonical form.
+func normalizePreferences(in Preferences) (Preferences, error) {
+	in.Locale = strings.TrimSpace(in.Locale)
+	in.Timezone = strings.TrimSpace(in.Timezone)
+	if in.Locale != "" {
+		tag, err := language.Parse(in.Locale)
+		if err != nil || len(tag.String()) > maxLocaleLength {
+			return in, &ValidationError{Field: "locale", Message: "must be a BCP 47 language tag such as en-US"}
+		}
+		in.Locale = tag.String()
+	}
+	if in.Timezone != "" {
+		// LoadLocation also accepts "Local", which names the server's zone
+		// rather than the user's.
+		if _, err := time.LoadLocation(in.Timezone); err != nil || in.Timezone == "Local" || len(in.Timezone) > maxTimezoneLength {
+			return in, &ValidationError{Field: "timezone", Message: "must be an IANA time zone such as Europe/Paris"}
+		}
+	}
+	return in, nil
+}
+
+// userLocation returns the user's time zone, or UTC when it is unknown.
+func userLocation(user *model.User) *time.Location {
+	if user.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(user.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
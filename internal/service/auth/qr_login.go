@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/model"
+	"github.com/SarathLUN/go-auth-service/internal/repository"
+	"github.com/SarathLUN/go-auth-service/internal/util"
+)
+
+const (
+	// qrLoginCodeBytes is the entropy of the code shown in the QR.
+	qrLoginCodeBytes = 24
+	// qrLoginPollTokenBytes is the entropy of the token the device polls with.
+	qrLoginPollTokenBytes = 32
+	// QRLoginPollInterval is how often devices should poll for approval. It
+	// leaves room under the per-IP rate limit for several devices behind
+	// one address.
+	QRLoginPollInterval = 5 * time.Second
+)
+
+var (
+	// ErrInvalidQRLogin is returned for unknown, expired or already
+	// completed QR logins.
+	ErrInvalidQRLogin = errors.New("invalid or expired QR login")
+	// ErrQRLoginPending is returned while a QR login awaits approval.
+	ErrQRLoginPending = errors.New("QR login has not been approved yet")
+)
+
+// QRLoginStart is handed to the device that wants to sign in. It shows
+// Code as a QR and polls with PollToken.
+type QRLoginStart struct {
+	Code      string
+	PollToken string
+	ExpiresIn time.Duration
+}
+
+// StartQRLogin begins a cross-device login for a device without credentials,
+// such as a TV. The device signed in with the code is shown by
+// LookupQRLogin, the code is approved with ApproveQRLogin from a device
+// where the user is signed in, and the tokens are collected with
+// PollQRLogin.
+func (s *Service) StartQRLogin(ctx context.Context) (*QRLoginStart, error) {
+	code, err := util.GenerateRandomToken(qrLoginCodeBytes)
+	if err != nil {
+		return nil, err
+	}
+	pollToken, err := util.GenerateRandomToken(qrLoginPollTokenBytes)
+	if err != nil {
+		return nil, err
+	}
+	login := &model.QRLogin{
+		CodeHash:      util.HashToken(code),
+		PollTokenHash: util.HashToken(pollToken),
+		ExpiresAt:     s.clock.Now().Add(s.qrLoginTTL),
+		UserAgent:     userAgent(ctx),
+	}
+	if ip := clientIP(ctx); ip.IsValid() {
+		login.ClientIP = ip.String()
+	}
+	if err := s.qrLogins.Create(ctx, login); err != nil {
+		return nil, err
+	}
+	return &QRLoginStart{Code: code, PollToken: pollToken, ExpiresIn: s.qrLoginTTL}, nil
+}
+
+// QRLoginRequest describes the device asking to be signed in by a QR login.
+type QRLoginRequest struct {
+	// UserAgent and ClientIP are empty if unknown.
+	UserAgent string
+	ClientIP  string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// LookupQRLogin returns the device that started the pending login for code,
+// so the user can check it is theirs before approving. A QR code shown by
+// someone else would otherwise sign the attacker's device in.
+func (s *Service) LookupQRLogin(ctx context.Context, code string) (*QRLoginRequest, error) {
+	login, err := s.qrLogins.GetByCodeHash(ctx, util.HashToken(code))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidQRLogin
+	}
+	if err != nil {
+		return nil, err
+	}
+	if login.ApprovedAt != nil || !s.clock.Now().Before(login.ExpiresAt) {
+		return nil, ErrInvalidQRLogin
+	}
+	return &QRLoginRequest{
+		UserAgent: login.UserAgent,
+		ClientIP:  login.ClientIP,
+		CreatedAt: login.CreatedAt,
+		ExpiresAt: login.ExpiresAt,
+	}, nil
+}
+
+// ApproveQRLogin signs the device that showed code in as userID. A code can
+// only be approved once.
+func (s *Service) ApproveQRLogin(ctx context.Context, userID, code string) error {
+	approved, err := s.qrLogins.Approve(ctx, util.HashToken(code), userID, s.clock.Now())
+	if err != nil {
+		return err
+	}
+	if !approved {
+		return ErrInvalidQRLogin
+	}
+	return nil
+}
+
+// PollQRLogin returns tokens for the approved user, once. It returns
+// ErrQRLoginPending until the login is approved.
+func (s *Service) PollQRLogin(ctx context.Context, pollToken string) (tokens *TokenPair, err error) {
+	event := s.beginLogin(ctx, LoginMethodQR)
+	defer func() {
+		// Polls before the approval are not login attempts of their own.
+		if !errors.Is(err, ErrQRLoginPending) {
+			s.endLogin(ctx, event, loginOutcome(err))
+		}
+	}()
+
+	login, err := s.qrLogins.GetByPollTokenHash(ctx, util.HashToken(pollToken))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidQRLogin
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	if login.CompletedAt != nil {
+		return nil, ErrInvalidQRLogin
+	}
+	if login.ApprovedAt == nil {
+		if !now.Before(login.ExpiresAt) {
+			return nil, ErrInvalidQRLogin
+		}
+		return nil, ErrQRLoginPending
+	}
+
+	event.UserID = *login.UserID
+
+	completed, err := s.qrLogins.Complete(ctx, login.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !completed {
+		return nil, ErrInvalidQRLogin
+	}
+
+	return s.StartSession(ctx, *login.UserID)
+}
+
+// PurgeQRLogins deletes QR logins that have expired, whether or not they
+// were approved.
+func (s *Service) PurgeQRLogins(ctx context.Context) (int64, error) {
+	return s.qrLogins.DeleteExpired(ctx, s.clock.Now())
+}
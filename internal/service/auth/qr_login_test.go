@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestQRLogin(t *testing.T) {
+	cfg := testConfig()
+	ts := newTestService(t, cfg)
+	ctx := context.Background()
+	u, _ := ts.register(t)
+	ts.users.Activate(ctx, u.ID)
+
+	tv := WithClientIP(WithUserAgent(ctx, "SmartTV/1.0"), netip.MustParseAddr("198.51.100.7"))
+	start, err := ts.StartQRLogin(tv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := ts.LookupQRLogin(ctx, start.Code)
+	if err != nil {
+		t.Fatalf("LookupQRLogin() = %v", err)
+	}
+	if req.UserAgent != "SmartTV/1.0" || req.ClientIP != "198.51.100.7" {
+		t.Errorf("LookupQRLogin() = %+v, want the TV's User-Agent and address", req)
+	}
+	if _, err := ts.PollQRLogin(tv, start.PollToken); !errors.Is(err, ErrQRLoginPending) {
+		t.Fatalf("PollQRLogin() before approval = %v, want ErrQRLoginPending", err)
+	}
+
+	if err := ts.ApproveQRLogin(ctx, u.ID, start.Code); err != nil {
+		t.Fatalf("ApproveQRLogin() = %v", err)
+	}
+	if _, err := ts.LookupQRLogin(ctx, start.Code); !errors.Is(err, ErrInvalidQRLogin) {
+		t.Errorf("LookupQRLogin() after approval = %v, want ErrInvalidQRLogin", err)
+	}
+	if _, err := ts.PollQRLogin(tv, start.PollToken); err != nil {
+		t.Fatalf("PollQRLogin() = %v", err)
+	}
+}
+
+func TestPurgeQRLogins(t *testing.T) {
+	cfg := testConfig()
+	ts := newTestService(t, cfg)
+	ctx := context.Background()
+
+	start, err := ts.StartQRLogin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := ts.PurgeQRLogins(ctx); err != nil || n != 0 {
+		t.Fatalf("PurgeQRLogins() = %d, %v, want 0", n, err)
+	}
+
+	ts.clock.Advance(cfg.QRLoginTTL)
+	if _, err := ts.LookupQRLogin(ctx, start.Code); !errors.Is(err, ErrInvalidQRLogin) {
+		t.Errorf("LookupQRLogin() after expiry = %v, want ErrInvalidQRLogin", err)
+	}
+	ts.clock.Advance(time.Second)
+	if n, err := ts.PurgeQRLogins(ctx); err != nil || n != 1 {
+		t.Fatalf("PurgeQRLogins() = %d, %v, want 1", n, err)
+	}
+}
@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+
+	"github.com/SarathLUN/go-auth-service/internal/model"
+	"github.com/SarathLUN/go-auth-service/internal/repository"
+	"github.com/SarathLUN/go-auth-service/internal/totp"
+	"github.com/SarathLUN/go-auth-service/internal/util"
+)
+
+const (
+	// recoveryCodeCount is how many recovery codes a user holds at a time.
+	recoveryCodeCount = 10
+	// recoveryCodeBytes gives each code 80 bits of entropy, shown as 16
+	// base32 characters.
+	recoveryCodeBytes = 10
+)
+
+// ErrMFANotEnabled is returned for recovery code operations on accounts
+// without a confirmed TOTP enrollment.
+var ErrMFANotEnabled = errors.New("MFA is not enabled")
+
+// RegenerateRecoveryCodes replaces the user's recovery codes. code must be a
+// current TOTP code or an unused recovery code, so that a stolen access
+// token alone is not enough to obtain codes that bypass MFA. Wrong codes
+// count towards locking the account like in CompleteMFALogin, and it
+// returns ErrAccountLocked while the account is locked.
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, userID, code string) ([]string, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidAccessToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if isLocked(user, s.clock.Now()) {
+		return nil, ErrAccountLocked
+	}
+
+	cred, err := s.confirmedTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifySecondFactor(ctx, cred, code); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			if ferr := s.recordFailedLogin(ctx, user); ferr != nil {
+				return nil, ferr
+			}
+		}
+		return nil, err
+	}
+	if err := s.resetFailedLogins(ctx, user); err != nil {
+		return nil, err
+	}
+	return s.issueRecoveryCodes(ctx, userID)
+}
+
+// RemainingRecoveryCodes returns how many unused recovery codes the user has.
+func (s *Service) RemainingRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	if _, err := s.confirmedTOTP(ctx, userID); err != nil {
+		return 0, err
+	}
+	return s.recoveryCodes.CountUnused(ctx, userID)
+}
+
+func (s *Service) confirmedTOTP(ctx context.Context, userID string) (*model.TOTPCredential, error) {
+	cred, err := s.totpCredentials.GetByUserID(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrMFANotEnabled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cred.ConfirmedAt == nil {
+		return nil, ErrMFANotEnabled
+	}
+	return cred, nil
+}
+
+// issueRecoveryCodes generates a new set of codes for the user, replacing
+// any earlier ones. Only their hashes are stored.
+func (s *Service) issueRecoveryCodes(ctx context.Context, userID string) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, recoveryCodeBytes)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(b))
+		codes[i] = code[0:4] + "-" + code[4:8] + "-" + code[8:12] + "-" + code[12:16]
+		hashes[i] = util.HashToken(normalizeRecoveryCode(codes[i]))
+	}
+	if err := s.recoveryCodes.Replace(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// verifySecondFactor accepts either a TOTP code or a recovery code, telling
+// them apart by length.
+func (s *Service) verifySecondFactor(ctx context.Context, cred *model.TOTPCredential, code string) error {
+	code = normalizeRecoveryCode(code)
+	if len(code) == totp.Digits {
+		return s.verifyTOTP(ctx, cred, code)
+	}
+
+	used, err := s.recoveryCodes.Use(ctx, cred.UserID, util.HashToken(code), s.clock.Now())
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+// normalizeRecoveryCode strips the separators users may or may not type.
+func normalizeRecoveryCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(code)))
+}
@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/model"
+	"github.com/SarathLUN/go-auth-service/internal/repository"
+	"github.com/SarathLUN/go-auth-service/internal/util"
+)
+
+// refreshTokenBytes is the entropy of opaque refresh tokens.
+const refreshTokenBytes = 32
+
+var (
+	// ErrInvalidRefreshToken is returned for unknown, expired or revoked refresh tokens.
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused is returned when an already rotated refresh token
+	// is presented again. The whole token family is revoked when this happens.
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+)
+
+// TokenPair is the result of a successful authentication or refresh.
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    time.Duration
+}
+
+// errRotationRace reports that a concurrent exchange rotated the token first.
+var errRotationRace = errors.New("refresh token rotated concurrently")
+
+// Refresh exchanges a refresh token for a new access token and a new refresh
+// token in the same family. Presenting a token that was already exchanged
+// revokes the family, since either the client or an attacker holds a stolen
+// copy. Rotating the old token and storing the new one happen in one
+// transaction, so a failure in between doesn't leave the session without a
+// usable refresh token.
+func (s *Service) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	stored, err := s.refreshTokens.GetByHash(ctx, util.HashToken(refreshToken))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	if stored.RevokedAt != nil || !now.Before(stored.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if stored.RotatedAt != nil {
+		return nil, s.revokeReusedFamily(ctx, stored, now)
+	}
+
+	var pair *TokenPair
+	err = s.tx.Do(ctx, func(ctx context.Context) error {
+		rotated, err := s.refreshTokens.MarkRotated(ctx, stored.ID, now)
+		if err != nil {
+			return err
+		}
+		if !rotated {
+			return errRotationRace
+		}
+
+		user, err := s.users.GetByID(ctx, stored.UserID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrInvalidRefreshToken
+		}
+		if err != nil {
+			return err
+		}
+		if !user.IsActive {
+			return ErrUserNotActive
+		}
+		pair, err = s.issueTokens(ctx, user, stored.FamilyID)
+		return err
+	})
+	if errors.Is(err, errRotationRace) {
+		// Lost a race with a concurrent exchange of the same token. The
+		// family is revoked outside the rolled back transaction.
+		return nil, s.revokeReusedFamily(ctx, stored, now)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+func (s *Service) revokeReusedFamily(ctx context.Context, stored *model.RefreshToken, now time.Time) error {
+	log.Printf("Refresh token reuse detected for user %s, revoking family %s", stored.UserID, stored.FamilyID)
+	if err := s.refreshTokens.RevokeFamily(ctx, stored.FamilyID, now); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+// StartSession issues tokens in a new refresh token family for a user who
+// proved their identity some other way, such as by approving an OAuth device
+// authorization. It returns ErrUserNotActive if the user is not activated or
+// no longer exists.
+func (s *Service) StartSession(ctx context.Context, userID string) (*TokenPair, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrUserNotActive
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, ErrUserNotActive
+	}
+	return s.issueTokens(ctx, user, util.NewID())
+}
+
+// issueTokens creates an access token and a refresh token belonging to familyID.
+func (s *Service) issueTokens(ctx context.Context, user *model.User, familyID string) (*TokenPair, error) {
+	now := s.clock.Now()
+	accessToken, err := util.GenerateAccessToken(s.keys, user.ID, s.claimMapping.claims(user), now, s.accessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := util.GenerateRandomToken(refreshTokenBytes)
+	if err != nil {
+		return nil, err
+	}
+	err = s.refreshTokens.Create(ctx, &model.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: util.HashToken(refreshToken),
+		UserAgent: userAgent(ctx),
+		ExpiresAt: now.Add(s.refreshTokenTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{AccessToken: accessToken, RefreshToken: refreshToken, ExpiresIn: s.accessTokenTTL}, nil
+}
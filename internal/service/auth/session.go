@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/SarathLUN/go-auth-service/internal/model"
+	"github.com/SarathLUN/go-auth-service/internal/util"
+)
+
+// maxUserAgentLength matches the refresh_tokens.user_agent column.
+const maxUserAgentLength = 512
+
+// ErrSessionNotFound is returned when the user has no active session with the given ID.
+var ErrSessionNotFound = errors.New("session not found")
+
+type userAgentKey struct{}
+
+// WithUserAgent returns a context carrying the User-Agent of the client a
+// request came from. Sessions started or refreshed with that context are
+// labelled with it.
+func WithUserAgent(ctx context.Context, userAgent string) context.Context {
+	return context.WithValue(ctx, userAgentKey{}, userAgent)
+}
+
+// userAgent returns the User-Agent stored by WithUserAgent, made valid UTF-8
+// and cut to fit the database column.
+func userAgent(ctx context.Context) string {
+	ua, _ := ctx.Value(userAgentKey{}).(string)
+	ua = strings.ToValidUTF8(ua, "\uFFFD")
+	if r := []rune(ua); len(r) > maxUserAgentLength {
+		ua = string(r[:maxUserAgentLength])
+	}
+	return ua
+}
+
+// ListSessions returns the user's active sessions, most recently used first.
+func (s *Service) ListSessions(ctx context.Context, userID string) ([]*model.Session, error) {
+	return s.refreshTokens.ListSessions(ctx, userID, s.clock.Now())
+}
+
+// RevokeSession signs the user out of one session by revoking its refresh
+// tokens. Access tokens already issued to it stay valid until they expire.
+// It returns ErrSessionNotFound for IDs that aren't one of the user's
+// sessions.
+func (s *Service) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	if !util.IsID(sessionID) {
+		return ErrSessionNotFound
+	}
+	revoked, err := s.refreshTokens.RevokeUserFamily(ctx, userID, sessionID, s.clock.Now())
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrSessionNotFound
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"log"
+	"strconv"
+	"time"
+
+	"gopkg.in/gomail.v2"
+
+	"github.com/SarathLUN/go-auth-service/internal/config"
+	"github.com/SarathLUN/go-auth-service/internal/outbound"
+)
+
+// Service sends transactional emails. expires is when the link stops
+// working, in the recipient's time zone, and is rendered as such.
+type Service interface {
+	SendActivationEmail(ctx context.Context, to, username, link string, expires time.Time) error
+	SendPasswordResetEmail(ctx context.Context, to, username, link string, expires time.Time) error
+}
+
+// expiryLayout renders link expiry times, with the zone abbreviation so the
+// time reads correctly wherever the recipient is.
+const expiryLayout = "Monday, 2 January 2006 at 15:04 MST"
+
+// NewService returns the mailer selected by EMAIL_DRIVER: "smtp" delivers
+// through the configured SMTP server, "log" only logs messages, which is
+// handy for local development.
+func NewService(cfg *config.Config) (Service, error) {
+	switch cfg.EmailDriver {
+	case "smtp":
+		return newSMTPService(cfg)
+	case "log":
+		return logService{}, nil
+	default:
+		return nil, fmt.Errorf("unknown EMAIL_DRIVER %q", cfg.EmailDriver)
+	}
+}
+
+var activationTemplate = template.Must(template.New("activation").Parse(`<p>Hi {{.Username}},</p>
+<p>Thanks for signing up. Please confirm your email address to activate your account:</p>
+<p><a href="{{.Link}}">Activate my account</a></p>
+<p>The link expires on {{.Expires}}.</p>
+<p>If you did not create an account, you can ignore this email.</p>
+`))
+
+var passwordResetTemplate = template.Must(template.New("password-reset").Parse(`<p>Hi {{.Username}},</p>
+<p>An administrator has reset the password of your account. Please choose a new password to sign in again:</p>
+<p><a href="{{.Link}}">Choose a new password</a></p>
+<p>The link expires on {{.Expires}}.</p>
+`))
+
+type smtpService struct {
+	dialer  *gomail.Dialer
+	from    string
+	timeout time.Duration
+}
+
+func newSMTPService(cfg *config.Config) (*smtpService, error) {
+	port, err := strconv.Atoi(cfg.SMTPPort)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP_PORT %q: %w", cfg.SMTPPort, err)
+	}
+	tlsConfig, err := outbound.TLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig.ServerName = cfg.SMTPHost
+
+	dialer := gomail.NewDialer(cfg.SMTPHost, port, cfg.SMTPUsername, cfg.SMTPPassword)
+	dialer.TLSConfig = tlsConfig
+	return &smtpService{dialer: dialer, from: cfg.SMTPFromEmail, timeout: cfg.OutboundTimeout}, nil
+}
+
+func (s *smtpService) SendActivationEmail(ctx context.Context, to, username, link string, expires time.Time) error {
+	when := expires.Format(expiryLayout)
+	var html bytes.Buffer
+	if err := activationTemplate.Execute(&html, struct{ Username, Link, Expires string }{username, link, when}); err != nil {
+		return err
+	}
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", s.from)
+	m.SetHeader("To", to)
+	m.SetHeader("Subject", "Activate your account")
+	m.SetBody("text/plain", fmt.Sprintf("Hi %s,\n\nPlease activate your account by opening this link:\n\n%s\n\nThe link expires on %s.\n", username, link, when))
+	m.AddAlternative("text/html", html.String())
+
+	if err := s.send(ctx, m); err != nil {
+		return fmt.Errorf("send activation email: %w", err)
+	}
+	return nil
+}
+
+func (s *smtpService) SendPasswordResetEmail(ctx context.Context, to, username, link string, expires time.Time) error {
+	when := expires.Format(expiryLayout)
+	var html bytes.Buffer
+	if err := passwordResetTemplate.Execute(&html, struct{ Username, Link, Expires string }{username, link, when}); err != nil {
+		return err
+	}
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", s.from)
+	m.SetHeader("To", to)
+	m.SetHeader("Subject", "Choose a new password")
+	m.SetBody("text/plain", fmt.Sprintf("Hi %s,\n\nAn administrator has reset the password of your account. Choose a new one by opening this link:\n\n%s\n\nThe link expires on %s.\n", username, link, when))
+	m.AddAlternative("text/html", html.String())
+
+	if err := s.send(ctx, m); err != nil {
+		return fmt.Errorf("send password reset email: %w", err)
+	}
+	return nil
+}
+
+// send delivers m, giving up once ctx is done or OUTBOUND_TIMEOUT has
+// passed. gomail cannot be interrupted, so an abandoned delivery carries on
+// in the background and the email may still arrive.
+func (s *smtpService) send(ctx context.Context, m *gomail.Message) error {
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- s.dialer.DialAndSend(m) }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type logService struct{}
+
+func (logService) SendActivationEmail(_ context.Context, to, username, link string, expires time.Time) error {
+	log.Printf("Activation email for %s <%s>: %s (expires %s)", username, to, link, expires.Format(expiryLayout))
+	return nil
+}
+
+func (logService) SendPasswordResetEmail(_ context.Context, to, username, link string, expires time.Time) error {
+	log.Printf("Password reset email for %s <%s>: %s (expires %s)", username, to, link, expires.Format(expiryLayout))
+	return nil
+}
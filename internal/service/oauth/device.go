@@ -0,0 +1,238 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/model"
+	"github.com/SarathLUN/go-auth-service/internal/repository"
+	"github.com/SarathLUN/go-auth-service/internal/service/auth"
+	"github.com/SarathLUN/go-auth-service/internal/util"
+)
+
+const (
+	// deviceCodeBytes is the entropy of device codes.
+	deviceCodeBytes = 32
+	// userCodeLength is the number of characters in a user code, shown to
+	// the user as two groups of four.
+	userCodeLength = 8
+	// userCodeAlphabet has no vowels, so codes don't spell words, and no
+	// characters that are easily confused (RFC 8628 section 6.1).
+	userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+	// DevicePollInterval is the minimum time between token requests of a
+	// device.
+	DevicePollInterval = 5 * time.Second
+)
+
+var (
+	// ErrAuthorizationPending is returned while the user has not decided.
+	ErrAuthorizationPending = errors.New("authorization pending")
+	// ErrSlowDown is returned when a device polls faster than
+	// DevicePollInterval.
+	ErrSlowDown = errors.New("polling too fast")
+	// ErrAccessDenied is returned when the user denied the request.
+	ErrAccessDenied = errors.New("the user denied the request")
+	// ErrExpiredToken is returned when the device code expired before the
+	// user approved it.
+	ErrExpiredToken = errors.New("device code has expired")
+	// ErrInvalidGrant is returned for unknown or already used device codes,
+	// and for codes issued to another client.
+	ErrInvalidGrant = errors.New("invalid device code")
+	// ErrInvalidUserCode is returned for unknown, expired or already decided
+	// user codes.
+	ErrInvalidUserCode = errors.New("invalid or expired user code")
+)
+
+// DeviceAuthorization is the response of the device authorization endpoint
+// (RFC 8628 section 3.2).
+type DeviceAuthorization struct {
+	DeviceCode              string
+	UserCode                string
+	VerificationURI         string
+	VerificationURIComplete string
+	ExpiresIn               time.Duration
+	Interval                time.Duration
+}
+
+// StartDeviceAuthorization begins the device authorization grant for an
+// authenticated client, or a public client identified by its ID. The device shows the user code and verification URI
+// and then polls DeviceToken with the device code. Device grants issue user
+// sessions, which carry no scope, so requesting one is an error.
+func (s *Service) StartDeviceAuthorization(ctx context.Context, clientID, clientSecret, scope string) (*DeviceAuthorization, error) {
+	client, err := s.identify(ctx, clientID, clientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(scope) != "" {
+		return nil, ErrInvalidScope
+	}
+
+	deviceCode, err := util.GenerateRandomToken(deviceCodeBytes)
+	if err != nil {
+		return nil, err
+	}
+	userCode, err := generateUserCode()
+	if err != nil {
+		return nil, err
+	}
+	err = s.deviceAuthorizations.Create(ctx, &model.DeviceAuthorization{
+		ClientID:       client.ID,
+		DeviceCodeHash: util.HashToken(deviceCode),
+		UserCodeHash:   util.HashToken(normalizeUserCode(userCode)),
+		ExpiresAt:      s.clock.Now().Add(s.deviceCodeTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	complete, err := url.Parse(s.deviceVerificationURL)
+	if err != nil {
+		return nil, err
+	}
+	q := complete.Query()
+	q.Set("user_code", userCode)
+	complete.RawQuery = q.Encode()
+
+	return &DeviceAuthorization{
+		DeviceCode:              deviceCode,
+		UserCode:                userCode,
+		VerificationURI:         s.deviceVerificationURL,
+		VerificationURIComplete: complete.String(),
+		ExpiresIn:               s.deviceCodeTTL,
+		Interval:                DevicePollInterval,
+	}, nil
+}
+
+// DeviceToken implements the device_code grant (RFC 8628 section 3.4). Once
+// the user approved the request it returns tokens for their account, once.
+func (s *Service) DeviceToken(ctx context.Context, clientID, clientSecret, deviceCode string) (*auth.TokenPair, error) {
+	client, err := s.identify(ctx, clientID, clientSecret)
+	if err != nil {
+		return nil, err
+	}
+	da, err := s.deviceAuthorizations.GetByDeviceCodeHash(ctx, util.HashToken(deviceCode))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidGrant
+	}
+	if err != nil {
+		return nil, err
+	}
+	if da.ClientID != client.ID || da.CompletedAt != nil {
+		return nil, ErrInvalidGrant
+	}
+
+	now := s.clock.Now()
+	switch {
+	case da.DeniedAt != nil:
+		return nil, ErrAccessDenied
+	case da.ApprovedAt == nil && !now.Before(da.ExpiresAt):
+		return nil, ErrExpiredToken
+	case da.ApprovedAt == nil:
+		if err := s.deviceAuthorizations.RecordPoll(ctx, da.ID, now); err != nil {
+			return nil, err
+		}
+		if da.LastPolledAt != nil && now.Sub(*da.LastPolledAt) < DevicePollInterval {
+			return nil, ErrSlowDown
+		}
+		return nil, ErrAuthorizationPending
+	}
+
+	completed, err := s.deviceAuthorizations.Complete(ctx, da.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !completed {
+		return nil, ErrInvalidGrant
+	}
+	return s.sessions.StartSession(ctx, *da.UserID)
+}
+
+// DeviceRequest describes a pending device authorization to the user who is
+// asked to approve it.
+type DeviceRequest struct {
+	ClientID   string
+	ClientName string
+	ExpiresAt  time.Time
+}
+
+// LookupDeviceAuthorization returns the pending request for userCode, so the
+// verification page can show which client is asking.
+func (s *Service) LookupDeviceAuthorization(ctx context.Context, userCode string) (*DeviceRequest, error) {
+	da, err := s.pendingDeviceAuthorization(ctx, userCode)
+	if err != nil {
+		return nil, err
+	}
+	client, err := s.clients.GetByID(ctx, da.ClientID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidUserCode
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &DeviceRequest{ClientID: client.ID, ClientName: client.Name, ExpiresAt: da.ExpiresAt}, nil
+}
+
+// DecideDeviceAuthorization approves the request for userCode on behalf of
+// userID, or denies it. A request can only be decided once.
+func (s *Service) DecideDeviceAuthorization(ctx context.Context, userID, userCode string, approve bool) error {
+	da, err := s.pendingDeviceAuthorization(ctx, userCode)
+	if err != nil {
+		return err
+	}
+	var decided bool
+	if approve {
+		decided, err = s.deviceAuthorizations.Approve(ctx, da.ID, userID, s.clock.Now())
+	} else {
+		decided, err = s.deviceAuthorizations.Deny(ctx, da.ID, s.clock.Now())
+	}
+	if err != nil {
+		return err
+	}
+	if !decided {
+		return ErrInvalidUserCode
+	}
+	return nil
+}
+
+func (s *Service) pendingDeviceAuthorization(ctx context.Context, userCode string) (*model.DeviceAuthorization, error) {
+	da, err := s.deviceAuthorizations.GetByUserCodeHash(ctx, util.HashToken(normalizeUserCode(userCode)))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidUserCode
+	}
+	if err != nil {
+		return nil, err
+	}
+	if da.ApprovedAt != nil || da.DeniedAt != nil || !s.clock.Now().Before(da.ExpiresAt) {
+		return nil, ErrInvalidUserCode
+	}
+	return da, nil
+}
+
+// generateUserCode returns a random code formatted as XXXX-XXXX.
+func generateUserCode() (string, error) {
+	var b strings.Builder
+	alphabetSize := big.NewInt(int64(len(userCodeAlphabet)))
+	for i := 0; i < userCodeLength; i++ {
+		if i == userCodeLength/2 {
+			b.WriteByte('-')
+		}
+		n, err := rand.Int(rand.Reader, alphabetSize)
+		if err != nil {
+			return "", err
+		}
+		b.WriteByte(userCodeAlphabet[n.Int64()])
+	}
+	return b.String(), nil
+}
+
+// normalizeUserCode makes user codes case-insensitive and ignores the dash
+// and spaces users may type.
+func normalizeUserCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -0,0 +1,78 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/repository"
+	"github.com/SarathLUN/go-auth-service/internal/util"
+)
+
+// Token types reported by Introspect, as registered for token_type_hint in
+// RFC 7009.
+const (
+	TokenTypeAccess  = "access_token"
+	TokenTypeRefresh = "refresh_token"
+)
+
+// Introspection describes a token as in RFC 7662 section 2.2. Only Active is
+// meaningful when it is false.
+type Introspection struct {
+	Active    bool
+	TokenType string
+	Scope     string
+	Subject   string
+	ClientID  string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// Introspect reports whether token is an access or refresh token the
+// service issued that is still valid: unexpired, not revoked and, for
+// refresh tokens, not yet exchanged. The caller must authenticate as a
+// registered client. Unknown tokens and share tokens are reported as
+// inactive, not as an error.
+func (s *Service) Introspect(ctx context.Context, clientID, clientSecret, token string) (*Introspection, error) {
+	if _, err := s.authenticate(ctx, clientID, clientSecret); err != nil {
+		return nil, err
+	}
+	now := s.clock.Now()
+
+	if claims, err := util.ParseAccessToken(s.keys, token, now); err == nil {
+		revoked, err := s.revokedTokens.IsRevoked(ctx, claims.ID)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return &Introspection{}, nil
+		}
+		return &Introspection{
+			Active:    true,
+			TokenType: TokenTypeAccess,
+			Scope:     claims.Scope,
+			Subject:   claims.Subject,
+			ClientID:  claims.ClientID,
+			IssuedAt:  claims.IssuedAt.Time,
+			ExpiresAt: claims.ExpiresAt.Time,
+		}, nil
+	}
+
+	stored, err := s.refreshTokens.GetByHash(ctx, util.HashToken(token))
+	if errors.Is(err, repository.ErrNotFound) {
+		return &Introspection{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if stored.RevokedAt != nil || stored.RotatedAt != nil || !now.Before(stored.ExpiresAt) {
+		return &Introspection{}, nil
+	}
+	return &Introspection{
+		Active:    true,
+		TokenType: TokenTypeRefresh,
+		Subject:   stored.UserID,
+		IssuedAt:  stored.CreatedAt,
+		ExpiresAt: stored.ExpiresAt,
+	}, nil
+}
@@ -0,0 +1,223 @@
+package oauth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/clock"
+	"github.com/SarathLUN/go-auth-service/internal/config"
+	"github.com/SarathLUN/go-auth-service/internal/jwtkeys"
+	"github.com/SarathLUN/go-auth-service/internal/model"
+	"github.com/SarathLUN/go-auth-service/internal/repository"
+	"github.com/SarathLUN/go-auth-service/internal/service/auth"
+	"github.com/SarathLUN/go-auth-service/internal/util"
+)
+
+const (
+	// clientSecretBytes is the entropy of generated client secrets.
+	clientSecretBytes = 32
+	// maxClientNameLength matches the oauth_clients.name column.
+	maxClientNameLength = 255
+)
+
+// scopeTokenPattern is the scope-token syntax of RFC 6749 section 3.3.
+var scopeTokenPattern = regexp.MustCompile(`^[\x21\x23-\x5B\x5D-\x7E]+$`)
+
+var (
+	// ErrInvalidClient is returned when client authentication fails.
+	ErrInvalidClient = errors.New("invalid client credentials")
+	// ErrInvalidScope is returned when a client requests a scope it was not
+	// registered with.
+	ErrInvalidScope = errors.New("requested scope is not allowed for this client")
+	// ErrClientNotFound is returned when no client matches a lookup.
+	ErrClientNotFound = errors.New("client not found")
+	// ErrInvalidClientName is returned for empty or overlong client names.
+	ErrInvalidClientName = errors.New("client name must be 1-255 characters")
+	// ErrInvalidScopeValue is returned when registering a scope that is not
+	// a valid RFC 6749 scope token.
+	ErrInvalidScopeValue = errors.New("scopes must be non-empty and must not contain spaces, quotes or backslashes")
+	// ErrPublicClientScope is returned when registering a public client
+	// with scopes; public clients only obtain user sessions.
+	ErrPublicClientScope = errors.New("public clients cannot have scopes")
+)
+
+// Deps holds the collaborators of the Service.
+type Deps struct {
+	Clients              repository.OAuthClientRepository
+	RefreshTokens        repository.RefreshTokenRepository
+	RevokedTokens        repository.RevokedTokenRepository
+	DeviceAuthorizations repository.DeviceAuthorizationRepository
+	// Sessions issues user tokens for approved device authorizations.
+	Sessions *auth.Service
+	Clock    clock.Clock
+	Keys     *jwtkeys.KeySet
+}
+
+// Service implements OAuth client registration, the token endpoint, the
+// device authorization grant and token introspection.
+type Service struct {
+	clients              repository.OAuthClientRepository
+	refreshTokens        repository.RefreshTokenRepository
+	revokedTokens        repository.RevokedTokenRepository
+	deviceAuthorizations repository.DeviceAuthorizationRepository
+	sessions             *auth.Service
+	clock                clock.Clock
+	keys                 *jwtkeys.KeySet
+
+	accessTokenTTL        time.Duration
+	deviceCodeTTL         time.Duration
+	deviceVerificationURL string
+}
+
+// NewService creates an OAuth service.
+func NewService(cfg *config.Config, deps Deps) *Service {
+	return &Service{
+		clients:              deps.Clients,
+		refreshTokens:        deps.RefreshTokens,
+		revokedTokens:        deps.RevokedTokens,
+		deviceAuthorizations: deps.DeviceAuthorizations,
+		sessions:             deps.Sessions,
+		clock:                deps.Clock,
+		keys:                 deps.Keys,
+
+		accessTokenTTL:        cfg.AccessTokenTTL,
+		deviceCodeTTL:         cfg.DeviceCodeTTL,
+		deviceVerificationURL: cfg.DeviceVerificationURL,
+	}
+}
+
+// RegisterClient creates a client that may request scopes. The returned
+// secret is not stored and cannot be retrieved again. Public clients get no
+// secret and no scopes; they may only use the device authorization grant.
+func (s *Service) RegisterClient(ctx context.Context, name string, scopes []string, public bool) (*model.OAuthClient, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxClientNameLength {
+		return nil, "", ErrInvalidClientName
+	}
+	for _, sc := range scopes {
+		if !scopeTokenPattern.MatchString(sc) {
+			return nil, "", ErrInvalidScopeValue
+		}
+	}
+	if public && len(scopes) > 0 {
+		return nil, "", ErrPublicClientScope
+	}
+
+	client := &model.OAuthClient{
+		Name:   name,
+		Scope:  strings.Join(dedupe(scopes), " "),
+		Public: public,
+	}
+	var secret string
+	if !public {
+		var err error
+		if secret, err = util.GenerateRandomToken(clientSecretBytes); err != nil {
+			return nil, "", err
+		}
+		client.SecretHash = util.HashToken(secret)
+	}
+	if err := s.clients.Create(ctx, client); err != nil {
+		return nil, "", err
+	}
+	return client, secret, nil
+}
+
+// DeleteClient removes a client. Tokens it already holds stay valid until
+// they expire.
+func (s *Service) DeleteClient(ctx context.Context, id string) error {
+	if !util.IsID(id) {
+		return ErrClientNotFound
+	}
+	err := s.clients.Delete(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrClientNotFound
+	}
+	return err
+}
+
+// ClientToken is the result of a successful token request.
+type ClientToken struct {
+	AccessToken string
+	Scope       string
+	ExpiresIn   time.Duration
+}
+
+// ClientCredentials implements the client_credentials grant of RFC 6749
+// section 4.4. An empty scope grants every scope the client is registered
+// with; otherwise each requested scope must be one of them.
+func (s *Service) ClientCredentials(ctx context.Context, clientID, clientSecret, scope string) (*ClientToken, error) {
+	client, err := s.authenticate(ctx, clientID, clientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	granted := client.Scope
+	if requested := strings.Fields(scope); len(requested) > 0 {
+		allowed := strings.Fields(client.Scope)
+		for _, sc := range requested {
+			if !slices.Contains(allowed, sc) {
+				return nil, ErrInvalidScope
+			}
+		}
+		granted = strings.Join(dedupe(requested), " ")
+	}
+
+	token, err := util.GenerateClientAccessToken(s.keys, client.ID, granted, s.clock.Now(), s.accessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &ClientToken{AccessToken: token, Scope: granted, ExpiresIn: s.accessTokenTTL}, nil
+}
+
+// authenticate checks the credentials of a confidential client.
+func (s *Service) authenticate(ctx context.Context, clientID, clientSecret string) (*model.OAuthClient, error) {
+	client, err := s.identify(ctx, clientID, clientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if client.Public {
+		return nil, ErrInvalidClient
+	}
+	return client, nil
+}
+
+// identify checks the credentials of a confidential client, or the client_id
+// of a public client, which must not send a secret.
+func (s *Service) identify(ctx context.Context, clientID, clientSecret string) (*model.OAuthClient, error) {
+	if !util.IsID(clientID) {
+		return nil, ErrInvalidClient
+	}
+	client, err := s.clients.GetByID(ctx, clientID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidClient
+	}
+	if err != nil {
+		return nil, err
+	}
+	if client.Public {
+		if clientSecret != "" {
+			return nil, ErrInvalidClient
+		}
+		return client, nil
+	}
+	if clientSecret == "" || subtle.ConstantTimeCompare([]byte(client.SecretHash), []byte(util.HashToken(clientSecret))) != 1 {
+		return nil, ErrInvalidClient
+	}
+	return client, nil
+}
+
+// dedupe drops repeated scopes, keeping the first occurrence.
+func dedupe(scopes []string) []string {
+	out := make([]string, 0, len(scopes))
+	for _, sc := range scopes {
+		if !slices.Contains(out, sc) {
+			out = append(out, sc)
+		}
+	}
+	return out
+}
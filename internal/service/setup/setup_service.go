@@ -0,0 +1,116 @@
+package setup
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"sync"
+
+	"github.com/SarathLUN/go-auth-service/internal/config"
+	"github.com/SarathLUN/go-auth-service/internal/model"
+	"github.com/SarathLUN/go-auth-service/internal/repository"
+	"github.com/SarathLUN/go-auth-service/internal/service/auth"
+	"github.com/SarathLUN/go-auth-service/internal/util"
+)
+
+// setupTokenBytes is the entropy of generated setup tokens.
+const setupTokenBytes = 32
+
+var (
+	// ErrSetupComplete is returned once the first admin has been created.
+	ErrSetupComplete = errors.New("setup has already been completed")
+	// ErrInvalidSetupToken is returned for a wrong setup token.
+	ErrInvalidSetupToken = errors.New("invalid setup token")
+)
+
+// Deps holds the collaborators of the Service.
+type Deps struct {
+	Setup repository.SetupRepository
+	// Accounts validates the admin's details like a registration.
+	Accounts *auth.Service
+}
+
+// Service implements the first-run setup of an installation: creating the
+// first admin through the API instead of with manual SQL. It is guarded by a
+// setup token that is either configured with SETUP_TOKEN or generated by
+// Start and printed to the log.
+type Service struct {
+	setup    repository.SetupRepository
+	accounts *auth.Service
+
+	configuredToken string
+
+	mu    sync.Mutex
+	token string
+}
+
+// NewService creates a setup service.
+func NewService(cfg *config.Config, deps Deps) *Service {
+	return &Service{
+		setup:    deps.Setup,
+		accounts: deps.Accounts,
+
+		configuredToken: cfg.SetupToken,
+	}
+}
+
+// Start arms setup if it has not been completed yet and reports whether it
+// did. generated is the setup token to log, or "" if it comes from
+// SETUP_TOKEN.
+func (s *Service) Start(ctx context.Context) (pending bool, generated string, err error) {
+	done, err := s.setup.IsComplete(ctx)
+	if err != nil || done {
+		return false, "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.configuredToken != "" {
+		s.token = s.configuredToken
+		return true, "", nil
+	}
+	s.token, err = util.GenerateRandomToken(setupTokenBytes)
+	if err != nil {
+		return false, "", err
+	}
+	return true, s.token, nil
+}
+
+// Required reports whether setup still has to be completed.
+func (s *Service) Required(ctx context.Context) (bool, error) {
+	done, err := s.setup.IsComplete(ctx)
+	return !done, err
+}
+
+// Complete creates the first admin, active right away, and disables setup.
+// It returns a *auth.ValidationError for bad input.
+func (s *Service) Complete(ctx context.Context, token string, in auth.RegisterInput) (*model.User, error) {
+	s.mu.Lock()
+	armed := s.token
+	s.mu.Unlock()
+	if armed == "" {
+		return nil, ErrSetupComplete
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(armed)) != 1 {
+		return nil, ErrInvalidSetupToken
+	}
+
+	admin, err := s.accounts.NewUser(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	admin.Role = model.RoleAdmin
+	admin.IsActive = true
+
+	created, err := s.setup.Complete(ctx, admin)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.token = ""
+	s.mu.Unlock()
+	if !created {
+		return nil, ErrSetupComplete
+	}
+	return admin, nil
+}
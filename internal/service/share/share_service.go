@@ -0,0 +1,84 @@
+package share
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/clock"
+	"github.com/SarathLUN/go-auth-service/internal/jwtkeys"
+	"github.com/SarathLUN/go-auth-service/internal/util"
+)
+
+const (
+	// MaxTTL bounds how long a share token can stay valid.
+	MaxTTL = 7 * 24 * time.Hour
+	// maxFieldLength bounds audience and subject, which end up in every
+	// request carrying the token.
+	maxFieldLength = 255
+	// maxResourceLength bounds the resource, typically a path or URN.
+	maxResourceLength = 2048
+)
+
+var (
+	// ErrInvalidAudience is returned for empty or overlong audiences.
+	ErrInvalidAudience = errors.New("audience must be 1-255 characters")
+	// ErrInvalidResource is returned for empty or overlong resources.
+	ErrInvalidResource = errors.New("resource must be 1-2048 characters")
+	// ErrInvalidSubject is returned for empty or overlong subjects.
+	ErrInvalidSubject = errors.New("subject must be 1-255 characters")
+	// ErrInvalidTTL is returned for TTLs that are not positive or exceed MaxTTL.
+	ErrInvalidTTL = errors.New("ttl must be positive and at most 7 days")
+)
+
+// Deps holds the collaborators of the Service.
+type Deps struct {
+	Clock clock.Clock
+	Keys  *jwtkeys.KeySet
+}
+
+// Service issues share tokens: signed, short-lived tokens that grant read
+// access to a single resource of one service, for "share this report" links.
+// They are stateless and cannot be revoked, so TTLs should be kept short.
+// The services holding the resources verify them with pkg/share.
+type Service struct {
+	clock clock.Clock
+	keys  *jwtkeys.KeySet
+}
+
+// NewService creates a share token service.
+func NewService(deps Deps) *Service {
+	return &Service{clock: deps.Clock, keys: deps.Keys}
+}
+
+// IssueInput describes a share token to issue.
+type IssueInput struct {
+	// Audience names the service that serves the resource.
+	Audience string
+	// Resource identifies what is shared, such as "/reports/42".
+	Resource string
+	// Subject identifies who shared it.
+	Subject string
+	TTL     time.Duration
+}
+
+// Issue creates a share token and returns it with its expiry.
+func (s *Service) Issue(ctx context.Context, in IssueInput) (string, time.Time, error) {
+	switch {
+	case in.Audience == "" || len(in.Audience) > maxFieldLength:
+		return "", time.Time{}, ErrInvalidAudience
+	case in.Resource == "" || len(in.Resource) > maxResourceLength:
+		return "", time.Time{}, ErrInvalidResource
+	case in.Subject == "" || len(in.Subject) > maxFieldLength:
+		return "", time.Time{}, ErrInvalidSubject
+	case in.TTL <= 0 || in.TTL > MaxTTL:
+		return "", time.Time{}, ErrInvalidTTL
+	}
+
+	now := s.clock.Now()
+	token, err := util.GenerateShareToken(s.keys, in.Audience, in.Resource, in.Subject, now, in.TTL)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, now.Add(in.TTL), nil
+}
@@ -0,0 +1,194 @@
+package signingkey
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/clock"
+	"github.com/SarathLUN/go-auth-service/internal/config"
+	"github.com/SarathLUN/go-auth-service/internal/jwtkeys"
+	"github.com/SarathLUN/go-auth-service/internal/model"
+	"github.com/SarathLUN/go-auth-service/internal/repository"
+)
+
+// Deps holds the collaborators of the Service.
+type Deps struct {
+	SigningKeys repository.SigningKeyRepository
+	KeySet      *jwtkeys.KeySet
+	Clock       clock.Clock
+}
+
+// Service manages the database key store: it loads the stored keys into the
+// KeySet and rotates them.
+//
+// A rotated key is published in the JWKS immediately but only starts signing
+// once every instance has had a chance to load it, one refresh interval
+// later. The keys it replaces keep verifying for the grace period after that,
+// so tokens they signed stay valid until they expire.
+type Service struct {
+	signingKeys repository.SigningKeyRepository
+	keySet      *jwtkeys.KeySet
+	clock       clock.Clock
+	sealer      *jwtkeys.Sealer
+
+	algorithm       string
+	grace           time.Duration
+	refreshInterval time.Duration
+}
+
+// NewService creates a signing key service. It fails if
+// JWT_KEY_ENCRYPTION_KEY is missing or malformed.
+func NewService(cfg *config.Config, deps Deps) (*Service, error) {
+	sealer, err := jwtkeys.NewSealer(cfg.JWTKeyEncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	return &Service{
+		signingKeys: deps.SigningKeys,
+		keySet:      deps.KeySet,
+		clock:       deps.Clock,
+		sealer:      sealer,
+
+		algorithm:       deps.KeySet.Algorithm(),
+		grace:           cfg.JWTKeyRotationGrace,
+		refreshInterval: cfg.JWTKeyRefreshInterval,
+	}, nil
+}
+
+// Load reads the usable keys into the KeySet, creating the first key if the
+// store is empty.
+func (s *Service) Load(ctx context.Context) error {
+	now := s.clock.Now()
+	stored, err := s.usableKeys(ctx, now)
+	if err != nil {
+		return err
+	}
+	if len(stored) == 0 {
+		if err := s.createFirst(ctx, now); err != nil {
+			return err
+		}
+		if stored, err = s.usableKeys(ctx, now); err != nil {
+			return err
+		}
+		if len(stored) == 0 {
+			return errors.New("no usable signing key after creating one")
+		}
+	}
+
+	// stored is newest first. Sign with the newest key that every instance
+	// has had time to load, falling back to the oldest one.
+	current := stored[len(stored)-1]
+	for _, k := range stored {
+		if !k.CreatedAt.After(now.Add(-s.refreshInterval)) {
+			current = k
+			break
+		}
+	}
+
+	signer, err := s.sealer.Open(current.KeyID, current.PrivateKey)
+	if err != nil {
+		return err
+	}
+	var previous []crypto.Signer
+	for _, k := range stored {
+		if k == current {
+			continue
+		}
+		p, err := s.sealer.Open(k.KeyID, k.PrivateKey)
+		if err != nil {
+			return err
+		}
+		previous = append(previous, p)
+	}
+	return s.keySet.SetKeys(signer, previous)
+}
+
+// usableKeys returns the stored keys for the configured algorithm that are
+// still within their grace period, newest first.
+func (s *Service) usableKeys(ctx context.Context, now time.Time) ([]*model.SigningKey, error) {
+	all, err := s.signingKeys.ListUsable(ctx, now.Add(-s.grace))
+	if err != nil {
+		return nil, err
+	}
+	var keys []*model.SigningKey
+	for _, k := range all {
+		if k.Algorithm == s.algorithm {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+func (s *Service) createFirst(ctx context.Context, now time.Time) error {
+	key, err := s.newKey(now)
+	if err != nil {
+		return err
+	}
+	if err := s.signingKeys.Create(ctx, key); err != nil {
+		return err
+	}
+	stored, err := s.usableKeys(ctx, now)
+	if err != nil {
+		return err
+	}
+	if len(stored) == 0 {
+		// Create was a no-op because keys for another algorithm exist. Their
+		// tokens no longer verify anyway, so replace them outright.
+		log.Printf("Replacing stored signing keys with a new %s key %s", s.algorithm, key.KeyID)
+		return s.signingKeys.Rotate(ctx, key, now)
+	}
+	log.Printf("Created signing key %s", key.KeyID)
+	return nil
+}
+
+// Rotate generates a new key and schedules it to take over signing. It
+// returns the new kid and the time it starts signing.
+func (s *Service) Rotate(ctx context.Context) (string, time.Time, error) {
+	now := s.clock.Now()
+	key, err := s.newKey(now)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	activeFrom := now.Add(s.refreshInterval)
+	if err := s.signingKeys.Rotate(ctx, key, activeFrom); err != nil {
+		return "", time.Time{}, err
+	}
+	log.Printf("Rotated signing keys: %s signs from %s", key.KeyID, activeFrom.Format(time.RFC3339))
+	return key.KeyID, activeFrom, s.Load(ctx)
+}
+
+// Run reloads the keys every refresh interval until ctx is done, so that
+// rotations made elsewhere are picked up.
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.refreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Load(ctx); err != nil {
+				log.Printf("Error reloading signing keys: %v", err)
+			}
+		}
+	}
+}
+
+func (s *Service) newKey(now time.Time) (*model.SigningKey, error) {
+	signer, err := jwtkeys.GenerateKey(s.algorithm)
+	if err != nil {
+		return nil, err
+	}
+	kid, err := jwtkeys.KeyID(signer)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := s.sealer.Seal(kid, signer)
+	if err != nil {
+		return nil, err
+	}
+	return &model.SigningKey{KeyID: kid, Algorithm: s.algorithm, PrivateKey: sealed, CreatedAt: now}, nil
+}
@@ -0,0 +1,331 @@
+package user
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/model"
+	"github.com/SarathLUN/go-auth-service/internal/repository"
+	"github.com/SarathLUN/go-auth-service/internal/service/auth"
+	"github.com/SarathLUN/go-auth-service/internal/util"
+)
+
+const (
+	// maxExternalIDLength matches the users.external_id column.
+	maxExternalIDLength = 255
+
+	// DefaultListLimit and MaxListLimit bound the page size of List.
+	DefaultListLimit = 50
+	MaxListLimit     = 200
+)
+
+var (
+	// ErrUserNotFound is returned when no user matches a lookup.
+	ErrUserNotFound = errors.New("user not found")
+	// ErrInvalidExternalID is returned for empty or overlong external IDs.
+	ErrInvalidExternalID = errors.New("external id must be 1-255 characters")
+	// ErrInvalidRole is returned for roles other than model.RoleUser and model.RoleAdmin.
+	ErrInvalidRole = errors.New("role must be user or admin")
+	// ErrInvalidPage is returned for negative or oversized list limits.
+	ErrInvalidPage = errors.New("limit must be 1-200")
+	// ErrInvalidCursor is returned for malformed cursors and for cursors
+	// from a listing with a different sort order.
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrInvalidSort is returned for unknown sort orders.
+	ErrInvalidSort = errors.New("sort must be created_at, email or username, optionally prefixed with '-' for descending order")
+	// ErrInvalidStatus is returned for status filters other than active and inactive.
+	ErrInvalidStatus = errors.New("status must be active or inactive")
+)
+
+// Accounts is the part of the authentication service used to manage accounts.
+type Accounts interface {
+	NewUser(ctx context.Context, in auth.RegisterInput) (*model.User, error)
+	ForcePasswordReset(ctx context.Context, userID string) error
+	Unlock(ctx context.Context, userID string) error
+}
+
+// Deps holds the collaborators of the Service.
+type Deps struct {
+	Users    repository.UserRepository
+	Accounts Accounts
+}
+
+// Service implements user lookups, the mapping to legacy system IDs and
+// account management for operators.
+type Service struct {
+	users    repository.UserRepository
+	accounts Accounts
+}
+
+// NewService creates a user service.
+func NewService(deps Deps) *Service {
+	return &Service{users: deps.Users, accounts: deps.Accounts}
+}
+
+// Get returns the user with the given UUID.
+func (s *Service) Get(ctx context.Context, id string) (*model.User, error) {
+	if !util.IsID(id) {
+		return nil, ErrUserNotFound
+	}
+	return notFound(s.users.GetByID(ctx, id))
+}
+
+// GetByExternalID returns the user linked to a legacy system ID.
+func (s *Service) GetByExternalID(ctx context.Context, externalID string) (*model.User, error) {
+	return notFound(s.users.GetByExternalID(ctx, externalID))
+}
+
+// SetExternalID links the user to externalID, or unlinks them when it is
+// nil. It returns repository.ErrDuplicateExternalID if another user already
+// has that ID.
+func (s *Service) SetExternalID(ctx context.Context, id string, externalID *string) (*model.User, error) {
+	if externalID != nil {
+		trimmed := strings.TrimSpace(*externalID)
+		if trimmed == "" || len(trimmed) > maxExternalIDLength {
+			return nil, ErrInvalidExternalID
+		}
+		externalID = &trimmed
+	}
+	if !util.IsID(id) {
+		return nil, ErrUserNotFound
+	}
+	if err := s.users.SetExternalID(ctx, id, externalID); err != nil {
+		return nil, mapNotFound(err)
+	}
+	return notFound(s.users.GetByID(ctx, id))
+}
+
+// ListInput selects a page of users. Zero fields do not filter.
+type ListInput struct {
+	// Limit defaults to DefaultListLimit.
+	Limit int
+	// Cursor is the NextCursor of the previous page.
+	Cursor string
+	// Status is "active" or "inactive".
+	Status string
+	// Email matches a case-insensitive substring of the email address.
+	Email string
+	// CreatedFrom is inclusive, CreatedBefore exclusive.
+	CreatedFrom   time.Time
+	CreatedBefore time.Time
+	// Sort is created_at (the default), email or username, prefixed with
+	// "-" for descending order.
+	Sort string
+}
+
+// ListPage is a page of users. NextCursor is empty on the last page.
+type ListPage struct {
+	Users      []*model.User
+	NextCursor string
+}
+
+// listCursor is the decoded form of ListPage.NextCursor. It records the sort
+// order so that it cannot be replayed against a differently sorted listing.
+type listCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+// List returns a page of users. Pages are cut by keyset rather than offset,
+// so they stay fast on large tables and don't skip or repeat users when
+// others are added or removed between requests.
+func (s *Service) List(ctx context.Context, in ListInput) (*ListPage, error) {
+	if in.Limit == 0 {
+		in.Limit = DefaultListLimit
+	}
+	if in.Limit < 0 || in.Limit > MaxListLimit {
+		return nil, ErrInvalidPage
+	}
+	if in.Sort == "" {
+		in.Sort = string(repository.UserSortCreatedAt)
+	}
+	sort := repository.UserSort(strings.TrimPrefix(in.Sort, "-"))
+	if sort != repository.UserSortCreatedAt && sort != repository.UserSortEmail && sort != repository.UserSortUsername {
+		return nil, ErrInvalidSort
+	}
+
+	q := repository.UserListQuery{
+		Email:         in.Email,
+		CreatedFrom:   in.CreatedFrom,
+		CreatedBefore: in.CreatedBefore,
+		Sort:          sort,
+		Descending:    strings.HasPrefix(in.Sort, "-"),
+		// One extra row tells whether there is a next page.
+		Limit: in.Limit + 1,
+	}
+	switch in.Status {
+	case "":
+	case "active", "inactive":
+		active := in.Status == "active"
+		q.IsActive = &active
+	default:
+		return nil, ErrInvalidStatus
+	}
+	if in.Cursor != "" {
+		after, err := decodeCursor(in.Cursor, in.Sort, sort)
+		if err != nil {
+			return nil, err
+		}
+		q.After = after
+	}
+
+	users, err := s.users.List(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	page := &ListPage{Users: users}
+	if len(users) > in.Limit {
+		page.Users = users[:in.Limit]
+		page.NextCursor, err = encodeCursor(in.Sort, sort, page.Users[in.Limit-1])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+func encodeCursor(spec string, sort repository.UserSort, last *model.User) (string, error) {
+	c := listCursor{Sort: spec, ID: last.ID}
+	switch sort {
+	case repository.UserSortEmail:
+		c.Value = last.Email
+	case repository.UserSortUsername:
+		c.Value = last.Username
+	default:
+		c.Value = last.CreatedAt.Format(time.RFC3339Nano)
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func decodeCursor(cursor, spec string, sort repository.UserSort) (*repository.UserCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c listCursor
+	if err := json.Unmarshal(b, &c); err != nil || c.Sort != spec || !util.IsID(c.ID) {
+		return nil, ErrInvalidCursor
+	}
+	if sort != repository.UserSortCreatedAt {
+		return &repository.UserCursor{Value: c.Value, ID: c.ID}, nil
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, c.Value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &repository.UserCursor{Value: createdAt, ID: c.ID}, nil
+}
+
+// CreateInput holds the data for an account created by an operator.
+type CreateInput struct {
+	Email    string
+	Username string
+	Password string
+	// Role defaults to model.RoleUser.
+	Role string
+	// Locale and Timezone are optional, see auth.Preferences.
+	Locale   string
+	Timezone string
+}
+
+// Create adds an active account without sending an activation email, since
+// the operator vouches for it. It returns an *auth.ValidationError for bad
+// input and repository.ErrDuplicateEmail or repository.ErrDuplicateUsername
+// when the account already exists.
+func (s *Service) Create(ctx context.Context, in CreateInput) (*model.User, error) {
+	if in.Role == "" {
+		in.Role = model.RoleUser
+	}
+	if in.Role != model.RoleUser && in.Role != model.RoleAdmin {
+		return nil, ErrInvalidRole
+	}
+
+	u, err := s.accounts.NewUser(ctx, auth.RegisterInput{
+		Email:    in.Email,
+		Username: in.Username,
+		Password: in.Password,
+		Locale:   in.Locale,
+		Timezone: in.Timezone,
+	})
+	if err != nil {
+		return nil, err
+	}
+	u.Role = in.Role
+	u.IsActive = true
+	if err := s.users.Create(ctx, u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// Disable deactivates the account. Disabled users can neither log in nor
+// refresh their tokens; access tokens already issued run out on their own.
+func (s *Service) Disable(ctx context.Context, id string) (*model.User, error) {
+	if !util.IsID(id) {
+		return nil, ErrUserNotFound
+	}
+	if err := s.users.Deactivate(ctx, id); err != nil {
+		return nil, mapNotFound(err)
+	}
+	return notFound(s.users.GetByID(ctx, id))
+}
+
+// Enable reactivates a disabled account, or activates one whose owner never
+// followed the activation link.
+func (s *Service) Enable(ctx context.Context, id string) (*model.User, error) {
+	if !util.IsID(id) {
+		return nil, ErrUserNotFound
+	}
+	if err := s.users.Activate(ctx, id); err != nil {
+		return nil, mapNotFound(err)
+	}
+	return notFound(s.users.GetByID(ctx, id))
+}
+
+// ForcePasswordReset invalidates the user's password and sessions and emails
+// them a link to choose a new password.
+func (s *Service) ForcePasswordReset(ctx context.Context, id string) error {
+	if !util.IsID(id) {
+		return ErrUserNotFound
+	}
+	return mapNotFound(s.accounts.ForcePasswordReset(ctx, id))
+}
+
+// Unlock lifts a lockout from failed logins and returns the updated user.
+func (s *Service) Unlock(ctx context.Context, id string) (*model.User, error) {
+	if !util.IsID(id) {
+		return nil, ErrUserNotFound
+	}
+	if err := s.accounts.Unlock(ctx, id); err != nil {
+		return nil, mapNotFound(err)
+	}
+	return notFound(s.users.GetByID(ctx, id))
+}
+
+// Delete removes the user and everything they own.
+func (s *Service) Delete(ctx context.Context, id string) error {
+	if !util.IsID(id) {
+		return ErrUserNotFound
+	}
+	return mapNotFound(s.users.Delete(ctx, id))
+}
+
+func notFound(u *model.User, err error) (*model.User, error) {
+	return u, mapNotFound(err)
+}
+
+func mapNotFound(err error) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrUserNotFound
+	}
+	return err
+}
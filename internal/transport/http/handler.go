@@ -0,0 +1,135 @@
+package http
+
+import (
+	"expvar"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/SarathLUN/go-auth-service/internal/advisory"
+	"github.com/SarathLUN/go-auth-service/internal/clock"
+	"github.com/SarathLUN/go-auth-service/internal/config"
+	"github.com/SarathLUN/go-auth-service/internal/controller"
+	"github.com/SarathLUN/go-auth-service/internal/jwtkeys"
+	"github.com/SarathLUN/go-auth-service/internal/middleware"
+	"github.com/SarathLUN/go-auth-service/internal/ratelimit"
+	"github.com/SarathLUN/go-auth-service/internal/reputation"
+	"github.com/SarathLUN/go-auth-service/internal/service/auth"
+	"github.com/SarathLUN/go-auth-service/internal/service/user"
+	"github.com/SarathLUN/go-auth-service/internal/util"
+)
+
+// Deps holds what the HTTP routes are built from.
+type Deps struct {
+	Keys           *jwtkeys.KeySet
+	Auth           *auth.Service
+	Users          *user.Service
+	AuthController *controller.AuthController
+	UserController *controller.UserController
+	// SigningKeyController is nil unless JWT_KEY_STORE is database.
+	SigningKeyController  *controller.SigningKeyController
+	OAuthController       *controller.OAuthController
+	ActionTokenController *controller.ActionTokenController
+	ShareController       *controller.ShareController
+	SetupController       *controller.SetupController
+	Advisories            []advisory.Advisory
+	// InternalSigningKeys authenticate the /admin routes. Without keys only
+	// the /admin/users routes are served, to signed-in admins.
+	InternalSigningKeys map[string][]byte
+	// Reputation, if set, makes rate limits stricter on known-bad networks.
+	Reputation reputation.Provider
+	// TrustedProxies are the reverse proxies whose X-Forwarded-For header
+	// names the client, for rate limits and login events.
+	TrustedProxies []netip.Prefix
+	Clock          clock.Clock
+}
+
+// NewHandler wires the controllers into the service's HTTP routes and
+// applies the global middleware.
+func NewHandler(cfg *config.Config, deps Deps) http.Handler {
+	mux := http.NewServeMux()
+	requireAuth := middleware.RequireAuth(deps.Auth)
+	rateLimit := middleware.RateLimit(
+		ratelimit.New(cfg.RateLimitIPPerMinute, cfg.RateLimitIPBurst, deps.Clock),
+		ratelimit.New(cfg.RateLimitAccountPerMinute, cfg.RateLimitAccountBurst, deps.Clock),
+		middleware.BadIPLimit{
+			Reputation: deps.Reputation,
+			Threshold:  cfg.IPReputationThreshold,
+			Limiter:    ratelimit.New(cfg.RateLimitBadIPPerMinute, cfg.RateLimitBadIPBurst, deps.Clock),
+		},
+		util.EmailNormalization{Gmail: cfg.EmailNormalizeGmail, StripSubaddress: cfg.EmailStripSubaddress},
+	)
+
+	mux.HandleFunc("GET /setup", deps.SetupController.Status)
+	mux.HandleFunc("POST /setup", deps.SetupController.Complete)
+	mux.Handle("POST /register", rateLimit(http.HandlerFunc(deps.AuthController.Register)))
+	mux.Handle("POST /login", rateLimit(http.HandlerFunc(deps.AuthController.Login)))
+	mux.Handle("POST /login/mfa", rateLimit(http.HandlerFunc(deps.AuthController.LoginMFA)))
+	mux.Handle("POST /login/qr", rateLimit(http.HandlerFunc(deps.AuthController.StartQRLogin)))
+	mux.Handle("GET /login/qr/approve", rateLimit(requireAuth(http.HandlerFunc(deps.AuthController.LookupQRLogin))))
+	mux.Handle("POST /login/qr/approve", requireAuth(http.HandlerFunc(deps.AuthController.ApproveQRLogin)))
+	mux.Handle("POST /login/qr/poll", rateLimit(http.HandlerFunc(deps.AuthController.PollQRLogin)))
+	mux.HandleFunc("POST /token/refresh", deps.AuthController.Refresh)
+	mux.HandleFunc("POST /oauth/token", deps.OAuthController.Token)
+	mux.HandleFunc("POST /oauth/introspect", deps.OAuthController.Introspect)
+	mux.Handle("POST /oauth/device/code", rateLimit(http.HandlerFunc(deps.OAuthController.DeviceAuthorization)))
+	mux.Handle("GET /oauth/device", rateLimit(requireAuth(http.HandlerFunc(deps.OAuthController.LookupDevice))))
+	mux.Handle("POST /oauth/device", rateLimit(requireAuth(http.HandlerFunc(deps.OAuthController.DecideDevice))))
+	mux.HandleFunc("GET /activate", deps.AuthController.Activate)
+	mux.HandleFunc("GET /links", deps.AuthController.FollowEmailLink)
+	mux.Handle("POST /password/reset", rateLimit(http.HandlerFunc(deps.AuthController.ResetPassword)))
+	mux.Handle("POST /logout", requireAuth(http.HandlerFunc(deps.AuthController.Logout)))
+	mux.Handle("POST /mfa/totp/enroll", requireAuth(http.HandlerFunc(deps.AuthController.EnrollTOTP)))
+	mux.Handle("POST /mfa/totp/verify", requireAuth(http.HandlerFunc(deps.AuthController.VerifyTOTP)))
+	mux.Handle("GET /sessions", requireAuth(http.HandlerFunc(deps.AuthController.ListSessions)))
+	mux.Handle("DELETE /sessions/{id}", requireAuth(http.HandlerFunc(deps.AuthController.RevokeSession)))
+	mux.Handle("PUT /preferences", requireAuth(http.HandlerFunc(deps.AuthController.UpdatePreferences)))
+	mux.Handle("GET /mfa/recovery-codes", requireAuth(http.HandlerFunc(deps.AuthController.CountRecoveryCodes)))
+	mux.Handle("POST /mfa/recovery-codes", rateLimit(requireAuth(http.HandlerFunc(deps.AuthController.RegenerateRecoveryCodes))))
+	mux.Handle("POST /v1/password/strength", rateLimit(http.HandlerFunc(controller.PasswordStrength)))
+	mux.HandleFunc("GET /version", controller.Version)
+	mux.HandleFunc("GET /.well-known/jwks.json", controller.JWKS(deps.Keys))
+
+	// The user management routes are open to signed internal calls and to
+	// users with the admin role.
+	requireAdmin := func(next http.Handler) http.Handler {
+		return requireAuth(middleware.RequireAdmin(deps.Users)(next))
+	}
+	userAdmin := requireAdmin
+	if len(deps.InternalSigningKeys) > 0 {
+		requireSignature := middleware.RequireSignature(deps.InternalSigningKeys, cfg.InternalSigningTolerance, deps.Clock)
+		userAdmin = middleware.SignatureOr(requireSignature, requireAdmin)
+
+		mux.Handle("GET /admin/advisories", requireSignature(controller.Advisories(deps.Advisories)))
+		mux.Handle("GET /admin/metrics", requireSignature(expvar.Handler()))
+		mux.Handle("POST /admin/oauth/clients", requireSignature(http.HandlerFunc(deps.OAuthController.RegisterClient)))
+		mux.Handle("DELETE /admin/oauth/clients/{id}", requireSignature(http.HandlerFunc(deps.OAuthController.DeleteClient)))
+		mux.Handle("POST /admin/action-tokens", requireSignature(http.HandlerFunc(deps.ActionTokenController.Issue)))
+		mux.Handle("POST /admin/action-tokens/consume", requireSignature(http.HandlerFunc(deps.ActionTokenController.Consume)))
+		mux.Handle("POST /admin/share-tokens", requireSignature(http.HandlerFunc(deps.ShareController.Issue)))
+		if deps.SigningKeyController != nil {
+			mux.Handle("POST /admin/keys/rotate", requireSignature(http.HandlerFunc(deps.SigningKeyController.Rotate)))
+		}
+	}
+	mux.Handle("GET /admin/users", userAdmin(http.HandlerFunc(deps.UserController.List)))
+	mux.Handle("POST /admin/users", userAdmin(http.HandlerFunc(deps.UserController.Create)))
+	mux.Handle("GET /admin/users/{id}", userAdmin(http.HandlerFunc(deps.UserController.Get)))
+	mux.Handle("DELETE /admin/users/{id}", userAdmin(http.HandlerFunc(deps.UserController.Delete)))
+	mux.Handle("POST /admin/users/{id}/disable", userAdmin(http.HandlerFunc(deps.UserController.Disable)))
+	mux.Handle("POST /admin/users/{id}/enable", userAdmin(http.HandlerFunc(deps.UserController.Enable)))
+	mux.Handle("POST /admin/users/{id}/password-reset", userAdmin(http.HandlerFunc(deps.UserController.ForcePasswordReset)))
+	mux.Handle("POST /admin/users/{id}/unlock", userAdmin(http.HandlerFunc(deps.UserController.Unlock)))
+	mux.Handle("GET /admin/users/by-external-id/{externalID}", userAdmin(http.HandlerFunc(deps.UserController.GetByExternalID)))
+	mux.Handle("PUT /admin/users/{id}/external-id", userAdmin(http.HandlerFunc(deps.UserController.SetExternalID)))
+
+	handler := middleware.RecordClient(mux)
+	handler = middleware.ForwardedFor(deps.TrustedProxies)(handler)
+	handler = middleware.Timeout(cfg.RequestTimeout)(handler)
+	if cfg.CompressionEnabled {
+		handler = middleware.Compress(strings.Split(cfg.CompressionExcludePaths, ","))(handler)
+	}
+	if cfg.BasePath != "" {
+		handler = http.StripPrefix(cfg.BasePath, handler)
+	}
+	return handler
+}
@@ -0,0 +1,36 @@
+package util
+
+import "strings"
+
+// EmailNormalization controls which provider-specific rules NormalizeEmail applies
+// on top of trimming and lower-casing.
+type EmailNormalization struct {
+	// Gmail removes dots and "+tag" suffixes from gmail.com addresses and maps
+	// googlemail.com to gmail.com, since Gmail delivers all variants to one inbox.
+	Gmail bool
+	// StripSubaddress removes "+tag" suffixes for every domain.
+	StripSubaddress bool
+}
+
+// NormalizeEmail returns the canonical form of an email address used for
+// uniqueness checks and login matching. The address as typed by the user is
+// kept separately for display.
+func NormalizeEmail(email string, opts EmailNormalization) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok {
+		return email
+	}
+
+	isGmail := domain == "gmail.com" || domain == "googlemail.com"
+	if opts.Gmail && isGmail {
+		domain = "gmail.com"
+		local = strings.ReplaceAll(local, ".", "")
+	}
+	if opts.StripSubaddress || (opts.Gmail && isGmail) {
+		if i := strings.IndexByte(local, '+'); i > 0 {
+			local = local[:i]
+		}
+	}
+	return local + "@" + domain
+}
@@ -0,0 +1,129 @@
+package util
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password hashing algorithms.
+const (
+	PasswordHashBcrypt   = "bcrypt"
+	PasswordHashArgon2id = "argon2id"
+)
+
+const (
+	argon2SaltBytes = 16
+	argon2KeyBytes  = 32
+)
+
+// PasswordHasher hashes new passwords with Algorithm. CheckPassword verifies
+// hashes of either algorithm, so existing hashes keep working when the
+// algorithm or its parameters change.
+type PasswordHasher struct {
+	Algorithm string
+	// Memory (in KiB), Iterations and Parallelism are the argon2id cost
+	// parameters.
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+// DefaultPasswordHasher uses argon2id with the parameters OWASP recommends.
+var DefaultPasswordHasher = PasswordHasher{Algorithm: PasswordHashArgon2id, Memory: 19 * 1024, Iterations: 2, Parallelism: 1}
+
+// Validate reports unknown algorithms and argon2id parameters that cannot
+// be used.
+func (h PasswordHasher) Validate() error {
+	switch h.Algorithm {
+	case PasswordHashBcrypt:
+		return nil
+	case PasswordHashArgon2id:
+		if h.Iterations < 1 || h.Parallelism < 1 {
+			return errors.New("argon2id needs at least 1 iteration and 1 degree of parallelism")
+		}
+		if h.Memory < 8*uint32(h.Parallelism) {
+			return errors.New("argon2id needs at least 8 KiB of memory per degree of parallelism")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown password hash algorithm %q, expected %s or %s", h.Algorithm, PasswordHashBcrypt, PasswordHashArgon2id)
+	}
+}
+
+// Hash hashes a plaintext password. argon2id hashes use the PHC string
+// format, $argon2id$v=19$m=...,t=...,p=...$salt$key.
+func (h PasswordHasher) Hash(password string) (string, error) {
+	if h.Algorithm == PasswordHashBcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hash), nil
+	}
+
+	salt := make([]byte, argon2SaltBytes)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.Iterations, h.Memory, h.Parallelism, argon2KeyBytes)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, h.Memory, h.Iterations, h.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// NeedsRehash reports whether hash was made with another algorithm or other
+// parameters than h would use, so it should be replaced the next time the
+// password is known.
+func (h PasswordHasher) NeedsRehash(hash string) bool {
+	if h.Algorithm == PasswordHashBcrypt {
+		cost, err := bcrypt.Cost([]byte(hash))
+		return err != nil || cost != bcrypt.DefaultCost
+	}
+	params, _, _, err := parseArgon2id(hash)
+	return err != nil || params != h
+}
+
+// CheckPassword reports whether password matches the bcrypt or argon2id hash.
+func CheckPassword(hash, password string) bool {
+	if !strings.HasPrefix(hash, "$argon2id$") {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	}
+	params, salt, key, err := parseArgon2id(hash)
+	if err != nil {
+		return false
+	}
+	other := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, other) == 1
+}
+
+// parseArgon2id splits an argon2id hash in PHC string format.
+func parseArgon2id(hash string) (params PasswordHasher, salt, key []byte, err error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != PasswordHashArgon2id {
+		return params, nil, nil, errors.New("not an argon2id hash")
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, errors.New("unsupported argon2 version")
+	}
+	params.Algorithm = PasswordHashArgon2id
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, fmt.Errorf("argon2id parameters: %w", err)
+	}
+	if err := params.Validate(); err != nil {
+		return params, nil, nil, err
+	}
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return params, nil, nil, fmt.Errorf("argon2id salt: %w", err)
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(key) == 0 {
+		return params, nil, nil, errors.New("invalid argon2id key")
+	}
+	return params, salt, key, nil
+}
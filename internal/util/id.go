@@ -0,0 +1,15 @@
+package util
+
+import "github.com/google/uuid"
+
+// NewID returns a new time-ordered UUIDv7, the identifier format used for
+// every primary key as well as token IDs (jti) and refresh token families.
+func NewID() string {
+	return uuid.Must(uuid.NewV7()).String()
+}
+
+// IsID reports whether s is a well-formed UUID.
+func IsID(s string) bool {
+	_, err := uuid.Parse(s)
+	return err == nil
+}
@@ -0,0 +1,116 @@
+package util
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"github.com/SarathLUN/go-auth-service/internal/jwtkeys"
+)
+
+// JOSE typ headers of the tokens the service signs. Share tokens have their
+// own type so that they are never mistaken for access tokens.
+const (
+	TokenTypeAccess = "JWT"
+	TokenTypeShare  = "share+jwt"
+)
+
+// AccessTokenClaims are the claims of the access tokens the service issues.
+// ClientID and Scope are only set on tokens issued to OAuth clients.
+type AccessTokenClaims struct {
+	jwt.RegisteredClaims
+	ClientID string `json:"client_id,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+
+	// Raw holds every claim of a parsed token by name, including those
+	// above, for claims whose names are only known at runtime.
+	Raw map[string]any `json:"-"`
+}
+
+// UnmarshalJSON decodes the claims into the fields and into Raw.
+func (c *AccessTokenClaims) UnmarshalJSON(data []byte) error {
+	type fields AccessTokenClaims
+	if err := json.Unmarshal(data, (*fields)(c)); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &c.Raw)
+}
+
+// GenerateAccessToken issues a JWT for subject signed with keys, with the
+// standard sub, iat and exp claims and a random jti, which identifies the
+// token for revocation. custom adds further claims by name; it cannot
+// override the standard ones.
+func GenerateAccessToken(keys *jwtkeys.KeySet, subject string, custom map[string]any, issuedAt time.Time, ttl time.Duration) (string, error) {
+	claims := make(jwt.MapClaims, len(custom)+4)
+	for name, value := range custom {
+		claims[name] = value
+	}
+	claims["jti"] = NewID()
+	claims["sub"] = subject
+	claims["iat"] = jwt.NewNumericDate(issuedAt)
+	claims["exp"] = jwt.NewNumericDate(issuedAt.Add(ttl))
+	return keys.Sign(TokenTypeAccess, claims)
+}
+
+// GenerateClientAccessToken issues a JWT for an OAuth client acting on its
+// own behalf. As in RFC 9068, sub and client_id are both the client ID.
+func GenerateClientAccessToken(keys *jwtkeys.KeySet, clientID, scope string, issuedAt time.Time, ttl time.Duration) (string, error) {
+	claims := AccessTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        NewID(),
+			Subject:   clientID,
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+			ExpiresAt: jwt.NewNumericDate(issuedAt.Add(ttl)),
+		},
+		ClientID: clientID,
+		Scope:    scope,
+	}
+	return keys.Sign(TokenTypeAccess, claims)
+}
+
+// ShareTokenClaims are the claims of share tokens.
+type ShareTokenClaims struct {
+	jwt.RegisteredClaims
+	Resource string `json:"resource"`
+}
+
+// GenerateShareToken issues a JWT of type TokenTypeShare granting read
+// access to a single resource of the service named by audience. subject
+// identifies who shared it.
+func GenerateShareToken(keys *jwtkeys.KeySet, audience, resource, subject string, issuedAt time.Time, ttl time.Duration) (string, error) {
+	claims := ShareTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        NewID(),
+			Subject:   subject,
+			Audience:  jwt.ClaimStrings{audience},
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+			ExpiresAt: jwt.NewNumericDate(issuedAt.Add(ttl)),
+		},
+		Resource: resource,
+	}
+	return keys.Sign(TokenTypeShare, claims)
+}
+
+// ParseAccessToken verifies the signature of an access token issued by
+// GenerateAccessToken or GenerateClientAccessToken and checks that it has
+// not expired at now.
+func ParseAccessToken(keys *jwtkeys.KeySet, token string, now time.Time) (*AccessTokenClaims, error) {
+	var claims AccessTokenClaims
+	if err := keys.Parse(token, TokenTypeAccess, &claims); err != nil {
+		return nil, fmt.Errorf("parse access token: %w", err)
+	}
+	// Share tokens issued before they had their own type.
+	if _, ok := claims.Raw["resource"]; ok {
+		return nil, errors.New("share tokens are not access tokens")
+	}
+	if !claims.VerifyExpiresAt(now, true) {
+		return nil, errors.New("access token has expired")
+	}
+	if claims.Subject == "" || claims.ID == "" {
+		return nil, errors.New("access token is missing sub or jti")
+	}
+	return &claims, nil
+}
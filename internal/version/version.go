@@ -0,0 +1,51 @@
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// Build metadata, set at build time with:
+//
+//	go build -ldflags "-X github.com/SarathLUN/go-auth-service/internal/version.GitSHA=$(git rev-parse HEAD) \
+//	  -X github.com/SarathLUN/go-auth-service/internal/version.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	GitSHA    = ""
+	BuildTime = ""
+)
+
+// Info describes the running build.
+type Info struct {
+	GitSHA    string `json:"git_sha"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build info. Values not injected via ldflags fall back to
+// the VCS stamp embedded by the Go toolchain, if any.
+func Get() Info {
+	info := Info{GitSHA: GitSHA, BuildTime: BuildTime, GoVersion: runtime.Version()}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch {
+			case s.Key == "vcs.revision" && info.GitSHA == "":
+				info.GitSHA = s.Value
+			case s.Key == "vcs.time" && info.BuildTime == "":
+				info.BuildTime = s.Value
+			}
+		}
+	}
+	if info.GitSHA == "" {
+		info.GitSHA = "unknown"
+	}
+	if info.BuildTime == "" {
+		info.BuildTime = "unknown"
+	}
+	return info
+}
+
+// String formats the info for logs and --version output.
+func (i Info) String() string {
+	return fmt.Sprintf("commit %s, built %s, %s", i.GitSHA, i.BuildTime, i.GoVersion)
+}
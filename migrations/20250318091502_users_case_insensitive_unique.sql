@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE UNIQUE INDEX users_email_lower_key ON users (LOWER(email));
+CREATE UNIQUE INDEX users_username_lower_key ON users (LOWER(username));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX users_username_lower_key;
+DROP INDEX users_email_lower_key;
+-- +goose StatementEnd
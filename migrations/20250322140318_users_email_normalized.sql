@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users ADD COLUMN email_normalized VARCHAR(255);
+-- Existing rows get the case-insensitive form; provider-specific rules
+-- (Gmail dots, plus addressing) are applied by `authctl normalize-emails`.
+UPDATE users SET email_normalized = LOWER(TRIM(email));
+ALTER TABLE users ALTER COLUMN email_normalized SET NOT NULL;
+CREATE UNIQUE INDEX users_email_normalized_key ON users (email_normalized);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX users_email_normalized_key;
+ALTER TABLE users DROP COLUMN email_normalized;
+-- +goose StatementEnd
@@ -0,0 +1,129 @@
+-- +goose Up
+-- +goose StatementBegin
+-- gen_uuid_v7 builds a UUIDv7: 48 bits of unix milliseconds followed by
+-- random bits, with the version nibble set to 7. Existing rows are keyed by
+-- their created_at so they keep their order.
+CREATE FUNCTION gen_uuid_v7(ts TIMESTAMP WITH TIME ZONE DEFAULT clock_timestamp()) RETURNS UUID AS $$
+    SELECT encode(
+        set_bit(set_bit(
+            overlay(uuid_send(gen_random_uuid())
+                PLACING substring(int8send(floor(extract(epoch FROM ts) * 1000)::BIGINT) FROM 3)
+                FROM 1 FOR 6),
+            52, 1), 53, 1),
+        'hex')::UUID;
+$$ LANGUAGE SQL VOLATILE;
+
+-- users.id becomes a UUID; the foreign keys follow it.
+ALTER TABLE users ADD COLUMN new_id UUID;
+UPDATE users SET new_id = gen_uuid_v7(COALESCE(created_at, clock_timestamp()));
+
+ALTER TABLE refresh_tokens ADD COLUMN new_user_id UUID;
+UPDATE refresh_tokens t SET new_user_id = u.new_id FROM users u WHERE u.id = t.user_id;
+ALTER TABLE refresh_tokens DROP COLUMN user_id;
+ALTER TABLE refresh_tokens RENAME COLUMN new_user_id TO user_id;
+ALTER TABLE refresh_tokens ALTER COLUMN user_id SET NOT NULL;
+
+ALTER TABLE activation_tokens ADD COLUMN new_user_id UUID;
+UPDATE activation_tokens t SET new_user_id = u.new_id FROM users u WHERE u.id = t.user_id;
+ALTER TABLE activation_tokens DROP COLUMN user_id;
+ALTER TABLE activation_tokens RENAME COLUMN new_user_id TO user_id;
+ALTER TABLE activation_tokens ALTER COLUMN user_id SET NOT NULL;
+
+ALTER TABLE revoked_tokens ADD COLUMN new_user_id UUID;
+UPDATE revoked_tokens t SET new_user_id = u.new_id FROM users u WHERE u.id = t.user_id;
+ALTER TABLE revoked_tokens DROP COLUMN user_id;
+ALTER TABLE revoked_tokens RENAME COLUMN new_user_id TO user_id;
+ALTER TABLE revoked_tokens ALTER COLUMN user_id SET NOT NULL;
+
+ALTER TABLE users DROP COLUMN id;
+ALTER TABLE users RENAME COLUMN new_id TO id;
+ALTER TABLE users ALTER COLUMN id SET DEFAULT gen_uuid_v7();
+ALTER TABLE users ADD PRIMARY KEY (id);
+
+ALTER TABLE refresh_tokens ADD FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+CREATE INDEX refresh_tokens_user_id_idx ON refresh_tokens (user_id);
+ALTER TABLE activation_tokens ADD FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+CREATE INDEX activation_tokens_user_id_idx ON activation_tokens (user_id);
+ALTER TABLE revoked_tokens ADD FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+-- Token tables are not referenced by anything, so their keys convert in place.
+ALTER TABLE refresh_tokens ALTER COLUMN id DROP DEFAULT;
+DROP SEQUENCE refresh_tokens_id_seq;
+ALTER TABLE refresh_tokens
+    ALTER COLUMN id TYPE UUID USING gen_uuid_v7(COALESCE(created_at, clock_timestamp())),
+    ALTER COLUMN id SET DEFAULT gen_uuid_v7();
+
+ALTER TABLE activation_tokens ALTER COLUMN id DROP DEFAULT;
+DROP SEQUENCE activation_tokens_id_seq;
+ALTER TABLE activation_tokens
+    ALTER COLUMN id TYPE UUID USING gen_uuid_v7(COALESCE(created_at, clock_timestamp())),
+    ALTER COLUMN id SET DEFAULT gen_uuid_v7();
+
+-- Identifier of the user in a legacy system, for mapping during migrations.
+ALTER TABLE users ADD COLUMN external_id VARCHAR(255);
+CREATE UNIQUE INDEX users_external_id_key ON users (external_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX users_external_id_key;
+ALTER TABLE users DROP COLUMN external_id;
+
+-- Integer keys are reassigned in UUIDv7 (creation) order.
+ALTER TABLE refresh_tokens ADD COLUMN old_id BIGINT;
+UPDATE refresh_tokens t SET old_id = o.n
+    FROM (SELECT id, row_number() OVER (ORDER BY id) AS n FROM refresh_tokens) o WHERE o.id = t.id;
+ALTER TABLE refresh_tokens DROP COLUMN id;
+ALTER TABLE refresh_tokens RENAME COLUMN old_id TO id;
+ALTER TABLE refresh_tokens ADD PRIMARY KEY (id);
+CREATE SEQUENCE refresh_tokens_id_seq OWNED BY refresh_tokens.id;
+SELECT setval('refresh_tokens_id_seq', COALESCE((SELECT MAX(id) FROM refresh_tokens), 0) + 1, false);
+ALTER TABLE refresh_tokens ALTER COLUMN id SET DEFAULT nextval('refresh_tokens_id_seq');
+
+ALTER TABLE activation_tokens ADD COLUMN old_id BIGINT;
+UPDATE activation_tokens t SET old_id = o.n
+    FROM (SELECT id, row_number() OVER (ORDER BY id) AS n FROM activation_tokens) o WHERE o.id = t.id;
+ALTER TABLE activation_tokens DROP COLUMN id;
+ALTER TABLE activation_tokens RENAME COLUMN old_id TO id;
+ALTER TABLE activation_tokens ADD PRIMARY KEY (id);
+CREATE SEQUENCE activation_tokens_id_seq OWNED BY activation_tokens.id;
+SELECT setval('activation_tokens_id_seq', COALESCE((SELECT MAX(id) FROM activation_tokens), 0) + 1, false);
+ALTER TABLE activation_tokens ALTER COLUMN id SET DEFAULT nextval('activation_tokens_id_seq');
+
+ALTER TABLE users ADD COLUMN old_id INTEGER;
+UPDATE users u SET old_id = o.n
+    FROM (SELECT id, row_number() OVER (ORDER BY id) AS n FROM users) o WHERE o.id = u.id;
+
+ALTER TABLE refresh_tokens ADD COLUMN old_user_id INTEGER;
+UPDATE refresh_tokens t SET old_user_id = u.old_id FROM users u WHERE u.id = t.user_id;
+ALTER TABLE refresh_tokens DROP COLUMN user_id;
+ALTER TABLE refresh_tokens RENAME COLUMN old_user_id TO user_id;
+ALTER TABLE refresh_tokens ALTER COLUMN user_id SET NOT NULL;
+
+ALTER TABLE activation_tokens ADD COLUMN old_user_id INTEGER;
+UPDATE activation_tokens t SET old_user_id = u.old_id FROM users u WHERE u.id = t.user_id;
+ALTER TABLE activation_tokens DROP COLUMN user_id;
+ALTER TABLE activation_tokens RENAME COLUMN old_user_id TO user_id;
+ALTER TABLE activation_tokens ALTER COLUMN user_id SET NOT NULL;
+
+ALTER TABLE revoked_tokens ADD COLUMN old_user_id INTEGER;
+UPDATE revoked_tokens t SET old_user_id = u.old_id FROM users u WHERE u.id = t.user_id;
+ALTER TABLE revoked_tokens DROP COLUMN user_id;
+ALTER TABLE revoked_tokens RENAME COLUMN old_user_id TO user_id;
+ALTER TABLE revoked_tokens ALTER COLUMN user_id SET NOT NULL;
+
+ALTER TABLE users DROP COLUMN id;
+ALTER TABLE users RENAME COLUMN old_id TO id;
+ALTER TABLE users ADD PRIMARY KEY (id);
+CREATE SEQUENCE users_id_seq AS INTEGER OWNED BY users.id;
+SELECT setval('users_id_seq', COALESCE((SELECT MAX(id) FROM users), 0) + 1, false);
+ALTER TABLE users ALTER COLUMN id SET DEFAULT nextval('users_id_seq');
+
+ALTER TABLE refresh_tokens ADD FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+CREATE INDEX refresh_tokens_user_id_idx ON refresh_tokens (user_id);
+ALTER TABLE activation_tokens ADD FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+CREATE INDEX activation_tokens_user_id_idx ON activation_tokens (user_id);
+ALTER TABLE revoked_tokens ADD FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+DROP FUNCTION gen_uuid_v7(TIMESTAMP WITH TIME ZONE);
+-- +goose StatementEnd
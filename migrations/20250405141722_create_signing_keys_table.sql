@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE signing_keys (
+    kid VARCHAR(64) PRIMARY KEY, -- RFC 7638 thumbprint of the public key
+    algorithm VARCHAR(16) NOT NULL,
+    private_key BYTEA NOT NULL, -- AES-GCM sealed PKCS#8 PEM, see JWT_KEY_ENCRYPTION_KEY
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    retired_at TIMESTAMP WITH TIME ZONE -- when a newer key took over signing
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE signing_keys;
+-- +goose StatementEnd
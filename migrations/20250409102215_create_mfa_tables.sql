@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE totp_credentials (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret BYTEA NOT NULL, -- AES-GCM sealed base32 seed, see MFA_ENCRYPTION_KEY
+    confirmed_at TIMESTAMP WITH TIME ZONE, -- NULL until the first code is verified
+    last_used_step BIGINT NOT NULL DEFAULT 0, -- time step of the last accepted code, against replay
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE mfa_challenges (
+    id UUID PRIMARY KEY DEFAULT gen_uuid_v7(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash CHAR(64) UNIQUE NOT NULL, -- hex SHA-256 of the challenge token
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX mfa_challenges_user_id_idx ON mfa_challenges (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE mfa_challenges;
+DROP TABLE totp_credentials;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE oauth_clients (
+    id UUID PRIMARY KEY DEFAULT gen_uuid_v7(), -- the client_id
+    name VARCHAR(255) NOT NULL,
+    secret_hash CHAR(64) NOT NULL, -- hex SHA-256 of the client secret
+    scope TEXT NOT NULL DEFAULT '', -- space-separated scopes the client may request
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE oauth_clients;
+-- +goose StatementEnd
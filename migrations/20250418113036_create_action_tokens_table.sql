@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE action_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_uuid_v7(),
+    purpose VARCHAR(64) NOT NULL, -- what the token may be used for, e.g. activation
+    subject VARCHAR(255) NOT NULL DEFAULT '', -- e.g. the user the action applies to
+    payload JSONB,
+    token_hash CHAR(64) UNIQUE NOT NULL, -- hex SHA-256 of the token
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Activation tokens become action tokens; links already emailed keep working.
+INSERT INTO action_tokens (id, purpose, subject, token_hash, expires_at, used_at, created_at)
+SELECT id, 'activation', user_id::text, token_hash, expires_at, used_at, created_at
+FROM activation_tokens;
+DROP TABLE activation_tokens;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE TABLE activation_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_uuid_v7(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash CHAR(64) UNIQUE NOT NULL, -- hex SHA-256 of the emailed token
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX activation_tokens_user_id_idx ON activation_tokens (user_id);
+
+INSERT INTO activation_tokens (id, user_id, token_hash, expires_at, used_at, created_at)
+SELECT t.id, u.id, t.token_hash, t.expires_at, t.used_at, t.created_at
+FROM action_tokens t JOIN users u ON u.id::text = t.subject
+WHERE t.purpose = 'activation';
+DROP TABLE action_tokens;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE qr_logins (
+    id UUID PRIMARY KEY DEFAULT gen_uuid_v7(),
+    code_hash CHAR(64) UNIQUE NOT NULL, -- hex SHA-256 of the code shown in the QR
+    poll_token_hash CHAR(64) UNIQUE NOT NULL, -- hex SHA-256 of the token the device polls with
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE, -- who approved the login
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    approved_at TIMESTAMP WITH TIME ZONE,
+    completed_at TIMESTAMP WITH TIME ZONE, -- when tokens were handed out
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE qr_logins;
+-- +goose StatementEnd
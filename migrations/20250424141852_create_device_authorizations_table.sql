@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE device_authorizations (
+    id UUID PRIMARY KEY DEFAULT gen_uuid_v7(),
+    client_id UUID NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    device_code_hash CHAR(64) UNIQUE NOT NULL, -- hex SHA-256 of the device code
+    user_code_hash CHAR(64) UNIQUE NOT NULL, -- hex SHA-256 of the normalized user code
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE, -- who approved the request
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    approved_at TIMESTAMP WITH TIME ZONE,
+    denied_at TIMESTAMP WITH TIME ZONE,
+    last_polled_at TIMESTAMP WITH TIME ZONE,
+    completed_at TIMESTAMP WITH TIME ZONE, -- when tokens were handed out
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE device_authorizations;
+-- +goose StatementEnd
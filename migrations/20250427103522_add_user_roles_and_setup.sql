@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users ADD COLUMN role VARCHAR(32) NOT NULL DEFAULT 'user';
+
+-- setup_completion has a single row once the first admin was created with
+-- POST /setup.
+CREATE TABLE setup_completion (
+    singleton BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (singleton),
+    admin_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    completed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Installations that already have users were set up by hand.
+INSERT INTO setup_completion (singleton) SELECT TRUE WHERE EXISTS (SELECT 1 FROM users);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE setup_completion;
+ALTER TABLE users DROP COLUMN role;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Keyset pagination of the admin user list orders by (sort column, id).
+CREATE INDEX users_created_at_id_idx ON users (created_at, id);
+CREATE INDEX users_email_id_idx ON users (email, id);
+CREATE INDEX users_username_id_idx ON users (username, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX users_username_id_idx;
+DROP INDEX users_email_id_idx;
+DROP INDEX users_created_at_id_idx;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- User-Agent of the client each refresh token was issued to, so sessions
+-- can be told apart in GET /sessions.
+ALTER TABLE refresh_tokens ADD COLUMN user_agent VARCHAR(512) NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE refresh_tokens DROP COLUMN user_agent;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Failed logins are kept for auditing. Only those after
+-- failed_logins_reset_at count towards a lockout, so a successful login,
+-- a lockout itself and an admin unlock each start the count afresh.
+ALTER TABLE users ADD COLUMN locked_until TIMESTAMP WITH TIME ZONE;
+ALTER TABLE users ADD COLUMN failed_logins_reset_at TIMESTAMP WITH TIME ZONE;
+
+CREATE TABLE failed_logins (
+    id UUID PRIMARY KEY DEFAULT gen_uuid_v7(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_agent VARCHAR(512) NOT NULL DEFAULT '',
+    attempted_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+CREATE INDEX failed_logins_user_id_attempted_at_idx ON failed_logins (user_id, attempted_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE failed_logins;
+ALTER TABLE users DROP COLUMN failed_logins_reset_at;
+ALTER TABLE users DROP COLUMN locked_until;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- BCP 47 language tag and IANA time zone of each user, empty when unknown.
+-- They are sent as the locale and zoneinfo claims of access tokens.
+ALTER TABLE users ADD COLUMN locale VARCHAR(35) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN timezone VARCHAR(64) NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN timezone;
+ALTER TABLE users DROP COLUMN locale;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- When the emailed link carrying the token was first followed, if links go
+-- through the EMAIL_LINK_PROXY redirect. A link can only be followed once.
+ALTER TABLE action_tokens ADD COLUMN clicked_at TIMESTAMP WITH TIME ZONE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE action_tokens DROP COLUMN clicked_at;
+-- +goose StatementEnd
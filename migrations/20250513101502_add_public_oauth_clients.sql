@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Public clients (token_endpoint_auth_method "none"), such as CLIs, cannot
+-- keep a secret. They have no secret_hash and may only use the device
+-- authorization grant.
+ALTER TABLE oauth_clients ADD COLUMN public BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE oauth_clients ALTER COLUMN secret_hash DROP NOT NULL;
+ALTER TABLE oauth_clients ADD CONSTRAINT oauth_clients_secret_check
+    CHECK (public = (secret_hash IS NULL));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM oauth_clients WHERE public;
+ALTER TABLE oauth_clients DROP CONSTRAINT oauth_clients_secret_check;
+ALTER TABLE oauth_clients ALTER COLUMN secret_hash SET NOT NULL;
+ALTER TABLE oauth_clients DROP COLUMN public;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The device that started each QR login, shown to the user before they
+-- approve it.
+ALTER TABLE qr_logins ADD COLUMN user_agent VARCHAR(512) NOT NULL DEFAULT '';
+ALTER TABLE qr_logins ADD COLUMN client_ip VARCHAR(45) NOT NULL DEFAULT '';
+CREATE INDEX idx_qr_logins_expires_at ON qr_logins (expires_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX idx_qr_logins_expires_at;
+ALTER TABLE qr_logins DROP COLUMN client_ip;
+ALTER TABLE qr_logins DROP COLUMN user_agent;
+-- +goose StatementEnd
@@ -0,0 +1,338 @@
+// Package auth embeds go-auth-service's identity operations in another Go
+// program, for monoliths that would rather call them directly than run the
+// service and talk to it over HTTP.
+//
+// The library works on the service's own Postgres schema, so run the
+// migrations in migrations/ against the database first. Tokens it issues
+// are interchangeable with those of a running server configured with the
+// same signing key:
+//
+//	a, err := auth.New(db, auth.Options{
+//		JWTSecret:       os.Getenv("JWT_SECRET"),
+//		ActivateBaseURL: "https://app.example.com/activate",
+//		Mailer:          mailer,
+//	})
+//
+//	user, err := a.RegisterUser(ctx, auth.RegisterInput{Email: email, Username: name, Password: pw})
+//	result, err := a.Authenticate(ctx, email, pw)
+//	claims, err := a.VerifyToken(ctx, result.Tokens.AccessToken)
+//
+// Errors are the same sentinels the service uses, re-exported here for
+// errors.Is, and *ValidationError for bad input.
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/clock"
+	"github.com/SarathLUN/go-auth-service/internal/config"
+	"github.com/SarathLUN/go-auth-service/internal/jwtkeys"
+	"github.com/SarathLUN/go-auth-service/internal/model"
+	"github.com/SarathLUN/go-auth-service/internal/password"
+	"github.com/SarathLUN/go-auth-service/internal/pwned"
+	"github.com/SarathLUN/go-auth-service/internal/repository"
+	"github.com/SarathLUN/go-auth-service/internal/secretbox"
+	"github.com/SarathLUN/go-auth-service/internal/service/actiontoken"
+	authsvc "github.com/SarathLUN/go-auth-service/internal/service/auth"
+)
+
+type (
+	// User is a registered account.
+	User = model.User
+	// RegisterInput holds the data needed to register a user.
+	RegisterInput = authsvc.RegisterInput
+	// Preferences are a user's BCP 47 locale and IANA time zone.
+	Preferences = authsvc.Preferences
+	// LoginResult holds either Tokens or, for accounts with MFA enabled, an
+	// MFAChallenge to pass to CompleteMFALogin with the user's code.
+	LoginResult = authsvc.LoginResult
+	// Tokens is an access token and the refresh token to renew it with.
+	Tokens = authsvc.TokenPair
+	// Claims identifies the user and token behind a verified access token.
+	Claims = authsvc.AccessClaims
+	// ValidationError reports invalid user input. Its message is safe to show to users.
+	ValidationError = authsvc.ValidationError
+	// PasswordViolation is a password requirement listed in a ValidationError.
+	PasswordViolation = password.Violation
+	// LoginEvent describes the outcome of one login attempt.
+	LoginEvent = authsvc.LoginEvent
+	// LoginObserver is told about login attempts. It is called on the login
+	// path and must return quickly.
+	LoginObserver = authsvc.LoginObserver
+)
+
+// Errors returned by Auth's methods.
+var (
+	ErrInvalidCredentials     = authsvc.ErrInvalidCredentials
+	ErrUserNotActive          = authsvc.ErrUserNotActive
+	ErrAccountLocked          = authsvc.ErrAccountLocked
+	ErrInvalidAccessToken     = authsvc.ErrInvalidAccessToken
+	ErrInvalidRefreshToken    = authsvc.ErrInvalidRefreshToken
+	ErrRefreshTokenReused     = authsvc.ErrRefreshTokenReused
+	ErrInvalidActivationToken = authsvc.ErrInvalidActivationToken
+	ErrInvalidMFACode         = authsvc.ErrInvalidMFACode
+	ErrInvalidMFAChallenge    = authsvc.ErrInvalidMFAChallenge
+	ErrDuplicateEmail         = repository.ErrDuplicateEmail
+	ErrDuplicateUsername      = repository.ErrDuplicateUsername
+)
+
+// Mailer sends the emails that registration and password resets rely on.
+// expires is when the link stops working, in the user's time zone.
+type Mailer interface {
+	SendActivationEmail(ctx context.Context, to, username, link string, expires time.Time) error
+	SendPasswordResetEmail(ctx context.Context, to, username, link string, expires time.Time) error
+}
+
+// Options configures the library. They mirror the server's environment
+// variables, and zero values get the same defaults.
+type Options struct {
+	// SigningAlg is HS256, RS256 or ES256. HS256, the default, signs with
+	// JWTSecret; the others with PrivateKeyPEM.
+	SigningAlg    string
+	JWTSecret     string
+	PrivateKeyPEM string
+
+	AccessTokenTTL     time.Duration
+	RefreshTokenTTL    time.Duration
+	ActivationTokenTTL time.Duration
+
+	// ClaimNamespace and ClaimMapping rename the user claims of access
+	// tokens, as CLAIM_NAMESPACE and CLAIM_MAPPING do for the server.
+	ClaimNamespace string
+	ClaimMapping   string
+
+	// PasswordMinLength defaults to 8 characters. PasswordRequiredClasses
+	// lists character classes passwords must contain: lower, upper, digit
+	// or symbol. Common passwords and passwords containing the email address
+	// are rejected unless allowed. PasswordMinScore is the lowest strength
+	// score, 1-4, accepted; it defaults to 2 and a negative value disables
+	// the check.
+	PasswordMinLength       int
+	PasswordMinScore        int
+	PasswordRequiredClasses []string
+	AllowCommonPasswords    bool
+	AllowEmailInPassword    bool
+
+	// PasswordHashAlgorithm is argon2id, the default, or bcrypt. The argon2id
+	// costs default to 19456 KiB of memory, 2 iterations and 1 lane; New
+	// fails for negative costs or too little memory per lane. Hashes made
+	// another way are upgraded when their user logs in.
+	PasswordHashAlgorithm string
+	Argon2MemoryKiB       int
+	Argon2Iterations      int
+	Argon2Parallelism     int
+	// CheckPwnedPasswords also rejects passwords found in data breaches,
+	// using the Have I Been Pwned range API. Passwords are accepted when
+	// the API cannot be reached.
+	CheckPwnedPasswords bool
+
+	// LockoutThreshold failed logins within LockoutWindow lock an account
+	// for LockoutDuration. A negative threshold disables lockout.
+	LockoutThreshold int
+	LockoutWindow    time.Duration
+	LockoutDuration  time.Duration
+
+	// ActivateBaseURL is where activation emails link to, with the token
+	// appended as a query parameter. The page should pass the token on to
+	// ActivateUser.
+	ActivateBaseURL string
+
+	// MFAEncryptionKey decrypts the TOTP secrets of users who enabled MFA,
+	// as MFA_ENCRYPTION_KEY does for the server.
+	MFAEncryptionKey string
+
+	Mailer Mailer
+
+	// LoginObserver, if set, receives an event for every login attempt, for
+	// metrics, auditing or fraud detection.
+	LoginObserver LoginObserver
+}
+
+// WithClient returns a context describing the client a call is made for.
+// Sessions started with it are labelled with userAgent, and login events
+// report both values.
+func WithClient(ctx context.Context, userAgent string, ip netip.Addr) context.Context {
+	return authsvc.WithClientIP(authsvc.WithUserAgent(ctx, userAgent), ip)
+}
+
+// Auth performs the identity operations against the service's database.
+type Auth struct {
+	svc *authsvc.Service
+}
+
+// New creates an Auth backed by db. It returns an error for options the
+// server would only warn about, such as an unknown PasswordHashAlgorithm,
+// unusable argon2id costs, unknown PasswordRequiredClasses or an invalid
+// ClaimMapping, rather than fall back to weaker defaults.
+func New(db *sql.DB, opts Options) (*Auth, error) {
+	return newAuth(opts, authsvc.Deps{
+		Users:         repository.NewUserRepository(db),
+		RefreshTokens: repository.NewRefreshTokenRepository(db),
+		ActionTokens: actiontoken.NewService(actiontoken.Deps{
+			Tokens: repository.NewActionTokenRepository(db),
+			Clock:  clock.Real{},
+		}),
+		RevokedTokens:   repository.NewRevokedTokenRepository(db),
+		TOTPCredentials: repository.NewTOTPCredentialRepository(db),
+		MFAChallenges:   repository.NewMFAChallengeRepository(db),
+		RecoveryCodes:   repository.NewRecoveryCodeRepository(db),
+		QRLogins:        repository.NewQRLoginRepository(db),
+		FailedLogins:    repository.NewFailedLoginRepository(db),
+		Tx:              repository.NewUnitOfWork(db),
+		Clock:           clock.Real{},
+	})
+}
+
+// newAuth completes deps, which hold the repositories and clock, from opts.
+func newAuth(opts Options, deps authsvc.Deps) (*Auth, error) {
+	if opts.Mailer == nil {
+		return nil, errors.New("auth: Options.Mailer is required")
+	}
+	cfg := opts.config()
+	if err := validate(cfg); err != nil {
+		return nil, err
+	}
+
+	keys, err := jwtkeys.FromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var mfaBox *secretbox.Box
+	if cfg.MFAEncryptionKey != "" {
+		if mfaBox, err = secretbox.New(cfg.MFAEncryptionKey); err != nil {
+			return nil, err
+		}
+	}
+
+	pwnedPasswords, err := pwned.NewFromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	deps.Mailer = opts.Mailer
+	deps.Keys = keys
+	deps.MFABox = mfaBox
+	deps.LoginObserver = opts.LoginObserver
+	deps.PwnedPasswords = pwnedPasswords
+	return &Auth{svc: authsvc.NewService(cfg, deps)}, nil
+}
+
+// validate rejects the settings that the service logs and replaces with
+// defaults, which authctl doctor reports as errors for the server.
+func validate(cfg *config.Config) error {
+	if _, err := password.ParseClasses(cfg.PasswordRequiredClasses); err != nil {
+		return fmt.Errorf("auth: Options.PasswordRequiredClasses: %w", err)
+	}
+	if _, err := authsvc.PasswordHasherFromConfig(cfg); err != nil {
+		return fmt.Errorf("auth: password hashing options: %w", err)
+	}
+	if _, err := authsvc.ParseClaimMapping(cfg.ClaimNamespace, cfg.ClaimMapping); err != nil {
+		return fmt.Errorf("auth: Options.ClaimMapping: %w", err)
+	}
+	return nil
+}
+
+// config starts from the server's defaults and applies the options that
+// are set.
+func (o Options) config() *config.Config {
+	cfg := config.Defaults()
+	// Keys, links and claim names have no useful server default.
+	cfg.JWTKeyStore = jwtkeys.StoreConfig
+	cfg.JWTSecret = o.JWTSecret
+	cfg.JWTPrivateKey = o.PrivateKeyPEM
+	cfg.ActivateBaseURL = o.ActivateBaseURL
+	cfg.MFAEncryptionKey = o.MFAEncryptionKey
+	cfg.ClaimNamespace = o.ClaimNamespace
+	cfg.ClaimMapping = o.ClaimMapping
+	cfg.PasswordRequiredClasses = strings.Join(o.PasswordRequiredClasses, ",")
+	cfg.PasswordDenyCommon = !o.AllowCommonPasswords
+	cfg.PasswordDenyEmail = !o.AllowEmailInPassword
+	cfg.PwnedPasswordsCheck = o.CheckPwnedPasswords
+
+	setIfNonZero(&cfg.JWTSigningAlg, o.SigningAlg)
+	setIfNonZero(&cfg.AccessTokenTTL, o.AccessTokenTTL)
+	setIfNonZero(&cfg.RefreshTokenTTL, o.RefreshTokenTTL)
+	setIfNonZero(&cfg.ActivationTokenTTL, o.ActivationTokenTTL)
+	setIfNonZero(&cfg.PasswordMinLength, o.PasswordMinLength)
+	setIfNonZero(&cfg.PasswordMinScore, o.PasswordMinScore)
+	setIfNonZero(&cfg.PasswordHashAlgorithm, o.PasswordHashAlgorithm)
+	setIfNonZero(&cfg.Argon2MemoryKiB, o.Argon2MemoryKiB)
+	setIfNonZero(&cfg.Argon2Iterations, o.Argon2Iterations)
+	setIfNonZero(&cfg.Argon2Parallelism, o.Argon2Parallelism)
+	setIfNonZero(&cfg.LockoutThreshold, o.LockoutThreshold)
+	setIfNonZero(&cfg.LockoutWindow, o.LockoutWindow)
+	setIfNonZero(&cfg.LockoutDuration, o.LockoutDuration)
+	return cfg
+}
+
+// setIfNonZero sets *dst to v unless v is the zero value.
+func setIfNonZero[T comparable](dst *T, v T) {
+	var zero T
+	if v != zero {
+		*dst = v
+	}
+}
+
+// RegisterUser creates an inactive user and emails them an activation link.
+// It returns a *ValidationError for bad input and ErrDuplicateEmail or
+// ErrDuplicateUsername when the account already exists.
+func (a *Auth) RegisterUser(ctx context.Context, in RegisterInput) (*User, error) {
+	return a.svc.Register(ctx, in)
+}
+
+// ActivateUser consumes the token from an activation link and marks its user
+// as active.
+func (a *Auth) ActivateUser(ctx context.Context, token string) error {
+	return a.svc.Activate(ctx, token)
+}
+
+// Authenticate checks an email and password and starts a session. It returns
+// ErrInvalidCredentials for an unknown email or wrong password,
+// ErrAccountLocked while failed logins keep the account locked and
+// ErrUserNotActive for accounts that have not been activated.
+func (a *Auth) Authenticate(ctx context.Context, email, password string) (*LoginResult, error) {
+	return a.svc.Login(ctx, email, password)
+}
+
+// UnlockUser lifts a lockout from failed logins before it runs out.
+func (a *Auth) UnlockUser(ctx context.Context, userID string) error {
+	return a.svc.Unlock(ctx, userID)
+}
+
+// SetPreferences sets the user's locale and time zone, which access tokens
+// carry as the locale and zoneinfo claims. It returns a *ValidationError for
+// bad input.
+func (a *Auth) SetPreferences(ctx context.Context, userID string, prefs Preferences) (*User, error) {
+	return a.svc.SetPreferences(ctx, userID, prefs)
+}
+
+// CompleteMFALogin finishes an Authenticate that returned an MFA challenge.
+func (a *Auth) CompleteMFALogin(ctx context.Context, challenge, code string) (*Tokens, error) {
+	return a.svc.CompleteMFALogin(ctx, challenge, code)
+}
+
+// IssueTokens starts a session for a user the caller has authenticated by
+// other means, such as an existing login of the monolith. It returns
+// ErrUserNotActive if the user is not activated or does not exist.
+func (a *Auth) IssueTokens(ctx context.Context, userID string) (*Tokens, error) {
+	return a.svc.StartSession(ctx, userID)
+}
+
+// RefreshTokens exchanges a refresh token for new tokens. Presenting a token
+// that was already exchanged revokes the session and returns
+// ErrRefreshTokenReused.
+func (a *Auth) RefreshTokens(ctx context.Context, refreshToken string) (*Tokens, error) {
+	return a.svc.Refresh(ctx, refreshToken)
+}
+
+// VerifyToken validates an access token, including against the revocation
+// list, and returns its claims or ErrInvalidAccessToken.
+func (a *Auth) VerifyToken(ctx context.Context, accessToken string) (*Claims, error) {
+	return a.svc.Authenticate(ctx, accessToken)
+}
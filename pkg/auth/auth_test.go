@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/clock"
+	"github.com/SarathLUN/go-auth-service/internal/repository/repotest"
+	"github.com/SarathLUN/go-auth-service/internal/service/actiontoken"
+	authsvc "github.com/SarathLUN/go-auth-service/internal/service/auth"
+)
+
+// mailer remembers the token of the last link it was asked to send.
+type mailer struct {
+	mu    sync.Mutex
+	token string
+}
+
+func (m *mailer) SendActivationEmail(_ context.Context, _, _, link string, _ time.Time) error {
+	return m.remember(link)
+}
+
+func (m *mailer) SendPasswordResetEmail(_ context.Context, _, _, link string, _ time.Time) error {
+	return m.remember(link)
+}
+
+func (m *mailer) remember(link string) error {
+	u, err := url.Parse(link)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.token = u.Query().Get("token")
+	return nil
+}
+
+func (m *mailer) lastToken() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.token
+}
+
+// testOptions are valid options with cheap hashing to keep the tests fast.
+func testOptions(m Mailer) Options {
+	return Options{
+		JWTSecret:         "test-secret-of-at-least-32-bytes!",
+		ActivateBaseURL:   "https://app.example.com/activate",
+		Argon2MemoryKiB:   64,
+		Argon2Iterations:  1,
+		Argon2Parallelism: 1,
+		Mailer:            m,
+	}
+}
+
+func TestNewValidatesOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Options)
+		wantErr string
+	}{
+		{"valid", func(*Options) {}, ""},
+		{"bcrypt", func(o *Options) { o.PasswordHashAlgorithm = "bcrypt" }, ""},
+		{"no mailer", func(o *Options) { o.Mailer = nil }, "Mailer is required"},
+		{"unknown hash algorithm", func(o *Options) { o.PasswordHashAlgorithm = "md5" }, "unknown password hash algorithm"},
+		{"negative iterations", func(o *Options) { o.Argon2Iterations = -1 }, "iteration"},
+		{"negative memory", func(o *Options) { o.Argon2MemoryKiB = -1 }, "memory"},
+		{"too little memory", func(o *Options) { o.Argon2MemoryKiB = 8; o.Argon2Parallelism = 4 }, "memory"},
+		{"parallelism above 255", func(o *Options) { o.Argon2Parallelism = 256 }, "parallelism"},
+		{"unknown class", func(o *Options) { o.PasswordRequiredClasses = []string{"upper", "emoji"} }, "PasswordRequiredClasses"},
+		{"standard claim", func(o *Options) { o.ClaimMapping = "email=sub" }, "ClaimMapping"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions(&mailer{})
+			tt.modify(&opts)
+			a, err := New(nil, opts)
+			if tt.wantErr == "" {
+				if err != nil || a == nil {
+					t.Fatalf("New() = %v, %v; want an Auth", a, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("New() error = %v, want one mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegisterAuthenticateVerify(t *testing.T) {
+	ctx := context.Background()
+	m := &mailer{}
+	a, err := newAuth(testOptions(m), authsvc.Deps{
+		Users:         &repotest.Users{},
+		RefreshTokens: &repotest.RefreshTokens{},
+		ActionTokens: actiontoken.NewService(actiontoken.Deps{
+			Tokens: &repotest.ActionTokens{},
+			Clock:  clock.Real{},
+		}),
+		RevokedTokens:   &repotest.RevokedTokens{},
+		TOTPCredentials: &repotest.TOTPCredentials{},
+		MFAChallenges:   &repotest.MFAChallenges{},
+		RecoveryCodes:   &repotest.RecoveryCodes{},
+		QRLogins:        &repotest.QRLogins{},
+		FailedLogins:    &repotest.FailedLogins{},
+		Tx:              repotest.Tx{},
+		Clock:           clock.Real{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const email, password = "ada@example.com", "Correct-Horse-9-Battery"
+	user, err := a.RegisterUser(ctx, RegisterInput{Email: email, Username: "ada", Password: password})
+	if err != nil {
+		t.Fatalf("RegisterUser: %v", err)
+	}
+	var verr *ValidationError
+	if _, err := a.RegisterUser(ctx, RegisterInput{Email: "bob@example.com", Username: "bob", Password: "short"}); !errors.As(err, &verr) {
+		t.Fatalf("RegisterUser(weak password) = %v, want a ValidationError", err)
+	}
+
+	if _, err := a.Authenticate(ctx, email, password); !errors.Is(err, ErrUserNotActive) {
+		t.Fatalf("Authenticate before activation = %v, want ErrUserNotActive", err)
+	}
+	if err := a.ActivateUser(ctx, m.lastToken()); err != nil {
+		t.Fatalf("ActivateUser: %v", err)
+	}
+	if _, err := a.Authenticate(ctx, email, "Wrong-Horse-9-Battery"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("Authenticate(wrong password) = %v, want ErrInvalidCredentials", err)
+	}
+	result, err := a.Authenticate(ctx, email, password)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if result.Tokens == nil {
+		t.Fatalf("Authenticate returned an MFA challenge for a user without MFA")
+	}
+
+	claims, err := a.VerifyToken(ctx, result.Tokens.AccessToken)
+	if err != nil {
+		t.Fatalf("VerifyToken: %v", err)
+	}
+	if claims.UserID != user.ID {
+		t.Errorf("VerifyToken UserID = %q, want %q", claims.UserID, user.ID)
+	}
+	if _, err := a.VerifyToken(ctx, "not-a-jwt"); !errors.Is(err, ErrInvalidAccessToken) {
+		t.Errorf("VerifyToken(garbage) = %v, want ErrInvalidAccessToken", err)
+	}
+	if _, err := a.VerifyToken(ctx, result.Tokens.RefreshToken); !errors.Is(err, ErrInvalidAccessToken) {
+		t.Errorf("VerifyToken(refresh token) = %v, want ErrInvalidAccessToken", err)
+	}
+}
@@ -0,0 +1,352 @@
+// Package share verifies the share tokens go-auth-service issues at
+// POST /admin/share-tokens, for services that serve shared resources
+// without a user session.
+//
+// A service wraps the handlers of shareable resources:
+//
+//	v := share.NewJWKSVerifier("https://auth.example.com/.well-known/jwks.json", nil)
+//	requireShare := share.RequireToken(v, "reports", func(r *http.Request) string {
+//		return r.URL.Path
+//	})
+//	http.Handle("/reports/", requireShare(reportHandler))
+//
+// Deployments signing with HS256 publish no keys; verify with their
+// JWT_SECRET using NewHMACVerifier instead.
+//
+// Share tokens are JWTs of type TokenType. Only that type is accepted, so
+// access tokens signed with the same keys cannot be used as share tokens,
+// nor share tokens as access tokens.
+package share
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// TokenType is the JOSE typ header of share tokens.
+const TokenType = "share+jwt"
+
+// TokenParam is the query parameter share links carry their token in.
+const TokenParam = "share_token"
+
+// ErrInvalidToken is returned for malformed, badly signed or expired share
+// tokens, and for tokens issued for another audience or resource.
+var ErrInvalidToken = errors.New("share: invalid or expired share token")
+
+// Claims are the verified contents of a share token.
+type Claims struct {
+	TokenID  string
+	Audience string
+	Resource string
+	// Subject identifies who shared the resource.
+	Subject   string
+	ExpiresAt time.Time
+}
+
+// Verifier validates share tokens for a resource of audience.
+type Verifier interface {
+	Verify(ctx context.Context, token, audience, resource string) (*Claims, error)
+}
+
+type claimsKey struct{}
+
+// RequireToken returns middleware that only lets requests through with a
+// share token for audience and the resource that resource(r) returns. The
+// token is read from the share_token query parameter, or else from an
+// "Authorization: Bearer" header. The claims are available to handlers
+// through ClaimsFromContext.
+func RequireToken(v Verifier, audience string, resource func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// The token is part of the page URL; don't hand it to other sites.
+			w.Header().Set("Referrer-Policy", "no-referrer")
+
+			token := r.URL.Query().Get(TokenParam)
+			if token == "" {
+				var ok bool
+				if token, ok = bearerToken(r); !ok {
+					writeError(w, http.StatusUnauthorized, "missing share token")
+					return
+				}
+			}
+
+			claims, err := v.Verify(r.Context(), token, audience, resource(r))
+			if errors.Is(err, ErrInvalidToken) {
+				writeError(w, http.StatusUnauthorized, "invalid or expired share token")
+				return
+			}
+			if err != nil {
+				log.Printf("Error verifying share token: %v", err)
+				writeError(w, http.StatusInternalServerError, "Internal server error")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimsKey{}, claims)))
+		})
+	}
+}
+
+// ClaimsFromContext returns the claims stored by RequireToken, or nil if the
+// request did not carry a share token.
+func ClaimsFromContext(ctx context.Context) *Claims {
+	claims, _ := ctx.Value(claimsKey{}).(*Claims)
+	return claims
+}
+
+// HMACVerifier verifies share tokens signed with HS256 and a shared secret.
+type HMACVerifier struct {
+	secret []byte
+	// Now defaults to time.Now.
+	Now func() time.Time
+}
+
+// NewHMACVerifier returns a verifier for tokens signed with secret, the
+// service's JWT_SECRET.
+func NewHMACVerifier(secret []byte) *HMACVerifier {
+	return &HMACVerifier{secret: secret}
+}
+
+// Verify implements Verifier.
+func (v *HMACVerifier) Verify(_ context.Context, token, audience, resource string) (*Claims, error) {
+	return verify(token, audience, resource, now(v.Now), func(t *jwt.Token) (any, error) {
+		if t.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+		}
+		return v.secret, nil
+	})
+}
+
+// Bounds on how often JWKSVerifier downloads the key set.
+const (
+	// jwksMaxAge is how long keys are used before the set is downloaded
+	// again, so retired keys stop being accepted.
+	jwksMaxAge = time.Hour
+	// jwksMinRefresh limits downloads triggered by unknown key IDs.
+	jwksMinRefresh = time.Minute
+	// maxJWKSBytes bounds the key set document.
+	maxJWKSBytes = 1 << 20
+)
+
+// JWKSVerifier verifies share tokens signed with RS256 or ES256 against the
+// keys the service publishes at /.well-known/jwks.json. The keys are cached
+// and downloaded again when a token names an unknown key.
+type JWKSVerifier struct {
+	url    string
+	client *http.Client
+	// Now defaults to time.Now.
+	Now func() time.Time
+
+	mu        sync.Mutex
+	keys      map[string]publicKey
+	fetchedAt time.Time
+}
+
+type publicKey struct {
+	alg string
+	key any
+}
+
+// NewJWKSVerifier returns a verifier using the key set at url. A nil client
+// uses http.DefaultClient.
+func NewJWKSVerifier(url string, client *http.Client) *JWKSVerifier {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &JWKSVerifier{url: url, client: client}
+}
+
+// fetchError carries a failure to download the key set out of the JWT
+// parser, so that it is not reported as an invalid token.
+type fetchError struct{ err error }
+
+func (e *fetchError) Error() string { return e.err.Error() }
+
+// Verify implements Verifier. It returns an error other than
+// ErrInvalidToken if the key set cannot be downloaded.
+func (v *JWKSVerifier) Verify(ctx context.Context, token, audience, resource string) (*Claims, error) {
+	claims, err := verify(token, audience, resource, now(v.Now), func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, err := v.key(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		if t.Method.Alg() != key.alg {
+			return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+		}
+		return key.key, nil
+	})
+	var fe *fetchError
+	if errors.As(err, &fe) {
+		return nil, fe.err
+	}
+	return claims, err
+}
+
+// key returns the key named kid, downloading the key set if it is stale or
+// lacks kid.
+func (v *JWKSVerifier) key(ctx context.Context, kid string) (publicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	now := now(v.Now)
+	key, ok := v.keys[kid]
+	stale := now.Sub(v.fetchedAt) > jwksMaxAge
+	if ok && !stale {
+		return key, nil
+	}
+	if !stale && now.Sub(v.fetchedAt) < jwksMinRefresh {
+		return publicKey{}, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	keys, err := v.fetch(ctx)
+	if err != nil {
+		return publicKey{}, &fetchError{fmt.Errorf("share: fetch %s: %w", v.url, err)}
+	}
+	v.keys, v.fetchedAt = keys, now
+	if key, ok = keys[kid]; !ok {
+		return publicKey{}, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+// jwk holds the members of RFC 7517 keys used by RS256 and ES256.
+type jwk struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Alg     string `json:"alg"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+	Curve   string `json:"crv"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+}
+
+// fetch downloads the key set. Keys of other types or algorithms are
+// skipped.
+func (v *JWKSVerifier) fetch(ctx context.Context) (map[string]publicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxJWKSBytes)).Decode(&set); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]publicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if key, ok := k.publicKey(); ok {
+			keys[k.KeyID] = key
+		}
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (publicKey, bool) {
+	switch {
+	case k.KeyType == "RSA" && k.Alg == "RS256":
+		n, err1 := base64.RawURLEncoding.DecodeString(k.N)
+		e, err2 := base64.RawURLEncoding.DecodeString(k.E)
+		if err1 != nil || err2 != nil || len(e) > 4 {
+			return publicKey{}, false
+		}
+		return publicKey{k.Alg, &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}}, true
+	case k.KeyType == "EC" && k.Alg == "ES256" && k.Curve == "P-256":
+		x, err1 := base64.RawURLEncoding.DecodeString(k.X)
+		y, err2 := base64.RawURLEncoding.DecodeString(k.Y)
+		if err1 != nil || err2 != nil {
+			return publicKey{}, false
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return publicKey{}, false
+		}
+		return publicKey{k.Alg, pub}, true
+	default:
+		return publicKey{}, false
+	}
+}
+
+type tokenClaims struct {
+	jwt.RegisteredClaims
+	Resource string `json:"resource"`
+}
+
+// verify parses a share token with the key keyFunc returns and checks its
+// type, expiry, audience and resource.
+func verify(token, audience, resource string, now time.Time, keyFunc jwt.Keyfunc) (*Claims, error) {
+	var claims tokenClaims
+	parser := jwt.NewParser(jwt.WithoutClaimsValidation())
+	_, err := parser.ParseWithClaims(token, &claims, func(t *jwt.Token) (any, error) {
+		if typ, _ := t.Header["typ"].(string); !strings.EqualFold(typ, TokenType) {
+			return nil, fmt.Errorf("unexpected token type %q", typ)
+		}
+		return keyFunc(t)
+	})
+	var fe *fetchError
+	if errors.As(err, &fe) {
+		return nil, fe
+	}
+	if err != nil || !claims.VerifyExpiresAt(now, true) || claims.ID == "" || claims.Subject == "" {
+		return nil, ErrInvalidToken
+	}
+	if claims.Resource == "" || claims.Resource != resource || !claims.VerifyAudience(audience, true) {
+		return nil, ErrInvalidToken
+	}
+	return &Claims{
+		TokenID:   claims.ID,
+		Audience:  audience,
+		Resource:  claims.Resource,
+		Subject:   claims.Subject,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}, nil
+}
+
+func now(f func() time.Time) time.Time {
+	if f != nil {
+		return f()
+	}
+	return time.Now()
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer" header.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// writeError writes the same {"error": msg} body as the service.
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(map[string]string{"error": msg}); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
@@ -0,0 +1,214 @@
+package share
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SarathLUN/go-auth-service/internal/jwtkeys"
+	"github.com/SarathLUN/go-auth-service/internal/util"
+)
+
+var issuedAt = time.Unix(1_700_000_000, 0)
+
+func shareToken(t *testing.T, keys *jwtkeys.KeySet, audience, resource string) string {
+	t.Helper()
+	token, err := util.GenerateShareToken(keys, audience, resource, "user-1", issuedAt, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestHMACVerifier(t *testing.T) {
+	keys := jwtkeys.NewHMAC([]byte("secret"))
+	token := shareToken(t, keys, "reports", "/reports/42")
+	accessToken, err := util.GenerateAccessToken(keys, "user-1", map[string]any{"resource": "/reports/42", "aud": "reports"}, issuedAt, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey := shareToken(t, jwtkeys.NewHMAC([]byte("other")), "reports", "/reports/42")
+
+	tests := []struct {
+		name     string
+		token    string
+		audience string
+		resource string
+		now      time.Time
+		wantErr  bool
+	}{
+		{"valid", token, "reports", "/reports/42", issuedAt.Add(time.Minute), false},
+		{"other audience", token, "invoices", "/reports/42", issuedAt.Add(time.Minute), true},
+		{"other resource", token, "reports", "/reports/43", issuedAt.Add(time.Minute), true},
+		{"expired", token, "reports", "/reports/42", issuedAt.Add(time.Hour), true},
+		{"access token", accessToken, "reports", "/reports/42", issuedAt.Add(time.Minute), true},
+		{"other key", otherKey, "reports", "/reports/42", issuedAt.Add(time.Minute), true},
+		{"malformed", "not-a-jwt", "reports", "/reports/42", issuedAt.Add(time.Minute), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewHMACVerifier([]byte("secret"))
+			v.Now = func() time.Time { return tt.now }
+			claims, err := v.Verify(context.Background(), tt.token, tt.audience, tt.resource)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidToken) {
+					t.Fatalf("Verify() = %v, want ErrInvalidToken", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify() = %v", err)
+			}
+			if claims.Subject != "user-1" || claims.Resource != tt.resource || claims.Audience != tt.audience {
+				t.Errorf("claims = %+v", claims)
+			}
+		})
+	}
+}
+
+func TestJWKSVerifier(t *testing.T) {
+	for _, alg := range []string{jwtkeys.RS256, jwtkeys.ES256} {
+		t.Run(alg, func(t *testing.T) {
+			keys, err := jwtkeys.New(alg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			signer, err := jwtkeys.GenerateKey(alg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := keys.SetKeys(signer, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			fetches := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fetches++
+				json.NewEncoder(w).Encode(keys.JWKS())
+			}))
+			defer srv.Close()
+
+			now := issuedAt.Add(time.Minute)
+			v := NewJWKSVerifier(srv.URL, srv.Client())
+			v.Now = func() time.Time { return now }
+			ctx := context.Background()
+
+			if _, err := v.Verify(ctx, shareToken(t, keys, "reports", "/reports/42"), "reports", "/reports/42"); err != nil {
+				t.Fatalf("Verify() = %v", err)
+			}
+			if _, err := v.Verify(ctx, shareToken(t, keys, "reports", "/reports/42"), "reports", "/reports/1"); !errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("Verify() for another resource = %v, want ErrInvalidToken", err)
+			}
+
+			// A rotated key is picked up by downloading the set again, but
+			// not more than once a minute.
+			rotated, err := jwtkeys.GenerateKey(alg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := keys.SetKeys(rotated, []crypto.Signer{signer}); err != nil {
+				t.Fatal(err)
+			}
+			token := shareToken(t, keys, "reports", "/reports/42")
+			if _, err := v.Verify(ctx, token, "reports", "/reports/42"); !errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("Verify() right after rotation = %v, want ErrInvalidToken", err)
+			}
+			now = now.Add(2 * time.Minute)
+			if _, err := v.Verify(ctx, token, "reports", "/reports/42"); err != nil {
+				t.Fatalf("Verify() after rotation = %v", err)
+			}
+			if fetches != 2 {
+				t.Errorf("key set downloaded %d times, want 2", fetches)
+			}
+		})
+	}
+}
+
+func TestJWKSVerifierFetchError(t *testing.T) {
+	keys, err := jwtkeys.New(jwtkeys.ES256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := jwtkeys.GenerateKey(jwtkeys.ES256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := keys.SetKeys(signer, nil); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	v := NewJWKSVerifier(srv.URL, srv.Client())
+	v.Now = func() time.Time { return issuedAt }
+	_, err = v.Verify(context.Background(), shareToken(t, keys, "reports", "/r"), "reports", "/r")
+	if err == nil || errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("Verify() = %v, want a download error", err)
+	}
+}
+
+type fakeVerifier struct {
+	claims *Claims
+	err    error
+	token  string
+}
+
+func (f *fakeVerifier) Verify(_ context.Context, token, audience, resource string) (*Claims, error) {
+	f.token = token
+	return f.claims, f.err
+}
+
+func TestRequireToken(t *testing.T) {
+	claims := &Claims{TokenID: "t1", Audience: "reports", Resource: "/reports/42", Subject: "user-1"}
+	tests := []struct {
+		name      string
+		target    string
+		header    string
+		verifyErr error
+		wantCode  int
+		wantToken string
+	}{
+		{"query parameter", "/reports/42?share_token=abc", "", nil, http.StatusOK, "abc"},
+		{"bearer header", "/reports/42", "Bearer abc", nil, http.StatusOK, "abc"},
+		{"missing token", "/reports/42", "", nil, http.StatusUnauthorized, ""},
+		{"invalid token", "/reports/42?share_token=abc", "", ErrInvalidToken, http.StatusUnauthorized, "abc"},
+		{"verifier failure", "/reports/42?share_token=abc", "", errors.New("boom"), http.StatusInternalServerError, "abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &fakeVerifier{claims: claims, err: tt.verifyErr}
+			var got *Claims
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = ClaimsFromContext(r.Context())
+			})
+			h := RequireToken(v, "reports", func(r *http.Request) string { return r.URL.Path })(next)
+
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+			if v.token != tt.wantToken {
+				t.Errorf("verified token %q, want %q", v.token, tt.wantToken)
+			}
+			if w.Header().Get("Referrer-Policy") != "no-referrer" {
+				t.Error("Referrer-Policy is not no-referrer")
+			}
+			if (w.Code == http.StatusOK) != (got == claims) {
+				t.Errorf("claims in context = %v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+// Package webhookverify verifies the signatures on webhooks sent by
+// go-auth-service, so receivers don't have to implement the checks
+// themselves.
+//
+// Every delivery carries three headers:
+//
+//	Webhook-Id:        unique ID of the event, stable across retries
+//	Webhook-Timestamp: unix seconds when this attempt was signed
+//	Webhook-Signature: one or more space-separated "v1=<hex>" values
+//
+// Each v1 value is the hex HMAC-SHA256, under a shared secret, of
+//
+//	id + "." + timestamp + "." + body
+//
+// More than one signature is sent while the sender rotates its secret.
+//
+// A receiver wraps its handler:
+//
+//	v := webhookverify.New([]byte(os.Getenv("AUTH_WEBHOOK_SECRET")))
+//	http.Handle("/hooks/auth", v.Middleware(handler))
+//
+// or verifies a body it has already read:
+//
+//	if err := v.Verify(r.Header, body); err != nil { ... }
+//
+// Verification rejects deliveries signed more than Tolerance from now and
+// deliveries it has already accepted, identified by event ID and timestamp,
+// so a captured request cannot be replayed, not even with only one of the
+// signatures sent during a secret rotation. Retries are signed afresh and
+// are not affected; use the event ID to make processing idempotent.
+package webhookverify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Headers carrying the event ID, signing time and signatures.
+const (
+	IDHeader        = "Webhook-Id"
+	TimestampHeader = "Webhook-Timestamp"
+	SignatureHeader = "Webhook-Signature"
+)
+
+// DefaultTolerance is how far the signing time may be from the receiver's
+// clock when Verifier.Tolerance is zero.
+const DefaultTolerance = 5 * time.Minute
+
+// MaxBodyBytes bounds how much of a request body VerifyRequest reads.
+const MaxBodyBytes = 1 << 20
+
+// signatureVersion prefixes each signature so the scheme can evolve.
+const signatureVersion = "v1"
+
+var (
+	// ErrMissingHeader is returned when a signature header is absent.
+	ErrMissingHeader = errors.New("webhookverify: missing webhook header")
+	// ErrInvalidTimestamp is returned when the timestamp is not unix seconds.
+	ErrInvalidTimestamp = errors.New("webhookverify: invalid timestamp")
+	// ErrTimestampOutOfRange is returned when the delivery was signed too
+	// long ago or in the future.
+	ErrTimestampOutOfRange = errors.New("webhookverify: timestamp outside tolerance")
+	// ErrInvalidSignature is returned when no signature matches any secret.
+	ErrInvalidSignature = errors.New("webhookverify: invalid signature")
+	// ErrReplayed is returned when a delivery was already accepted.
+	ErrReplayed = errors.New("webhookverify: replayed delivery")
+	// ErrBodyTooLarge is returned by VerifyRequest for bodies over MaxBodyBytes.
+	ErrBodyTooLarge = errors.New("webhookverify: body too large")
+)
+
+// ReplayStore remembers accepted deliveries. Receivers running several
+// instances should back it with shared storage; the default is in memory.
+type ReplayStore interface {
+	// Add records key, the event ID and timestamp of a delivery, until
+	// expiresAt and reports whether it was not already present.
+	Add(key string, expiresAt time.Time) (bool, error)
+}
+
+// Verifier checks webhook deliveries. Its fields may be changed before first
+// use; the zero values select the defaults.
+type Verifier struct {
+	// Secrets are the accepted shared secrets. Keep the old secret here
+	// while rotating.
+	Secrets [][]byte
+	// Tolerance defaults to DefaultTolerance.
+	Tolerance time.Duration
+	// Replay defaults to an in-memory store owned by the Verifier.
+	Replay ReplayStore
+	// Now defaults to time.Now.
+	Now func() time.Time
+
+	once        sync.Once
+	memoryStore *MemoryReplayStore
+}
+
+// New returns a Verifier accepting signatures made with any of secrets.
+func New(secrets ...[]byte) *Verifier {
+	return &Verifier{Secrets: secrets}
+}
+
+// Sign returns the Webhook-Signature value for a delivery. It is what the
+// sender uses, and is handy for testing receivers.
+func Sign(secret []byte, id string, timestamp time.Time, body []byte) string {
+	return signatureVersion + "=" + hex.EncodeToString(mac(secret, id, strconv.FormatInt(timestamp.Unix(), 10), body))
+}
+
+// Verify checks the headers of a delivery against its raw body.
+func (v *Verifier) Verify(header http.Header, body []byte) error {
+	id := header.Get(IDHeader)
+	ts := header.Get(TimestampHeader)
+	sigs := header.Get(SignatureHeader)
+	if id == "" || ts == "" || sigs == "" {
+		return ErrMissingHeader
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return ErrInvalidTimestamp
+	}
+	now := v.now()
+	signedAt := time.Unix(unix, 0)
+	tolerance := v.tolerance()
+	if signedAt.Before(now.Add(-tolerance)) || signedAt.After(now.Add(tolerance)) {
+		return ErrTimestampOutOfRange
+	}
+
+	matched := false
+	for _, field := range strings.Fields(sigs) {
+		version, value, ok := strings.Cut(field, "=")
+		if !ok || version != signatureVersion {
+			continue
+		}
+		provided, err := hex.DecodeString(value)
+		if err != nil {
+			continue
+		}
+		for _, secret := range v.Secrets {
+			// hmac.Equal compares in constant time.
+			if hmac.Equal(provided, mac(secret, id, ts, body)) {
+				matched = true
+			}
+		}
+	}
+	if !matched {
+		return ErrInvalidSignature
+	}
+
+	// The key covers everything the signatures do except the body, so a
+	// delivery counts once however many signatures it carries.
+	fresh, err := v.replayStore().Add(id+"."+ts, signedAt.Add(tolerance))
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrReplayed
+	}
+	return nil
+}
+
+// VerifyRequest reads and verifies the body of r and returns it. r.Body is
+// replaced so that it can be read again.
+func (v *Verifier) VerifyRequest(r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, MaxBodyBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > MaxBodyBytes {
+		return nil, ErrBodyTooLarge
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err := v.Verify(r.Header, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// Middleware rejects deliveries that fail verification with 401 before
+// they reach next.
+func (v *Verifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := v.VerifyRequest(r); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (v *Verifier) now() time.Time {
+	if v.Now != nil {
+		return v.Now()
+	}
+	return time.Now()
+}
+
+func (v *Verifier) tolerance() time.Duration {
+	if v.Tolerance > 0 {
+		return v.Tolerance
+	}
+	return DefaultTolerance
+}
+
+func (v *Verifier) replayStore() ReplayStore {
+	if v.Replay != nil {
+		return v.Replay
+	}
+	v.once.Do(func() {
+		v.memoryStore = NewMemoryReplayStore(v.now)
+	})
+	return v.memoryStore
+}
+
+func mac(secret []byte, id, timestamp string, body []byte) []byte {
+	m := hmac.New(sha256.New, secret)
+	m.Write([]byte(id))
+	m.Write([]byte("."))
+	m.Write([]byte(timestamp))
+	m.Write([]byte("."))
+	m.Write(body)
+	return m.Sum(nil)
+}
+
+// MemoryReplayStore is a ReplayStore for a single process.
+type MemoryReplayStore struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	entries map[string]time.Time
+}
+
+// NewMemoryReplayStore creates an empty store. now may be nil to use time.Now.
+func NewMemoryReplayStore(now func() time.Time) *MemoryReplayStore {
+	if now == nil {
+		now = time.Now
+	}
+	return &MemoryReplayStore{now: now, entries: make(map[string]time.Time)}
+}
+
+// Add implements ReplayStore. Expired entries are dropped as a side effect.
+func (s *MemoryReplayStore) Add(key string, expiresAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for k, exp := range s.entries {
+		if now.After(exp) {
+			delete(s.entries, k)
+		}
+	}
+	if _, dup := s.entries[key]; dup {
+		return false, nil
+	}
+	s.entries[key] = expiresAt
+	return true, nil
+}
@@ -0,0 +1,75 @@
+package webhookverify
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	oldSecret, newSecret := []byte("old-secret"), []byte("new-secret")
+	now := time.Unix(1_700_000_000, 0)
+	body := []byte(`{"type":"user.created"}`)
+
+	header := func(id string, signedAt time.Time, sigs ...string) http.Header {
+		h := http.Header{}
+		h.Set(IDHeader, id)
+		h.Set(TimestampHeader, strconv.FormatInt(signedAt.Unix(), 10))
+		if len(sigs) > 0 {
+			h.Set(SignatureHeader, strings.Join(sigs, " "))
+		}
+		return h
+	}
+
+	tests := []struct {
+		name   string
+		header http.Header
+		body   []byte
+		want   error
+	}{
+		{"valid", header("evt_1", now, Sign(newSecret, "evt_1", now, body)), body, nil},
+		{"old secret", header("evt_2", now, Sign(oldSecret, "evt_2", now, body)), body, nil},
+		{"unknown secret", header("evt_3", now, Sign([]byte("other"), "evt_3", now, body)), body, ErrInvalidSignature},
+		{"tampered body", header("evt_4", now, Sign(newSecret, "evt_4", now, body)), []byte(`{}`), ErrInvalidSignature},
+		{"too old", header("evt_5", now.Add(-time.Hour), Sign(newSecret, "evt_5", now.Add(-time.Hour), body)), body, ErrTimestampOutOfRange},
+		{"missing signature", header("evt_6", now), body, ErrMissingHeader},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := New(newSecret, oldSecret)
+			v.Now = func() time.Time { return now }
+			if err := v.Verify(tt.header, tt.body); !errors.Is(err, tt.want) {
+				t.Fatalf("Verify() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsReplayDuringRotation(t *testing.T) {
+	oldSecret, newSecret := []byte("old-secret"), []byte("new-secret")
+	now := time.Unix(1_700_000_000, 0)
+	body := []byte(`{"type":"user.created"}`)
+	v := New(newSecret, oldSecret)
+	v.Now = func() time.Time { return now }
+
+	delivery := func(sigs string) http.Header {
+		h := http.Header{}
+		h.Set(IDHeader, "evt_1")
+		h.Set(TimestampHeader, strconv.FormatInt(now.Unix(), 10))
+		h.Set(SignatureHeader, sigs)
+		return h
+	}
+	newSig, oldSig := Sign(newSecret, "evt_1", now, body), Sign(oldSecret, "evt_1", now, body)
+
+	if err := v.Verify(delivery(newSig+" "+oldSig), body); err != nil {
+		t.Fatalf("first delivery: %v", err)
+	}
+	for _, sig := range []string{newSig + " " + oldSig, newSig, oldSig} {
+		if err := v.Verify(delivery(sig), body); !errors.Is(err, ErrReplayed) {
+			t.Errorf("replay with %q = %v, want ErrReplayed", sig, err)
+		}
+	}
+}